@@ -0,0 +1,353 @@
+package notion
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cmskitdev/client"
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// Ancestor types, see Ancestor.Type.
+const (
+	AncestorWorkspace = "workspace"
+	AncestorPage      = "page"
+	AncestorDatabase  = "database"
+	AncestorBlock     = "block"
+)
+
+// maxAncestry bounds how many ancestors are resolved for an object, so that a
+// parent cycle in inconsistent data cannot loop forever.
+const maxAncestry = 64
+
+// Ancestor is a page, database or block above an object, or the workspace.
+// The ancestors of an item are stored root first in its "ancestors" metadata
+// property when NotionSourceConfig.Ancestry is set.
+type Ancestor struct {
+	// ID is the ID of the ancestor, empty for the workspace.
+	ID string `json:"id,omitempty"`
+	// Type is AncestorWorkspace, AncestorPage, AncestorDatabase or
+	// AncestorBlock.
+	Type string `json:"type"`
+	// Title is the plain text title of a page or database, or the text of a
+	// heading, child_page or child_database block.
+	Title string `json:"title,omitempty"`
+}
+
+// ancestryNode is an object seen by a read, with the parent it points to.
+type ancestryNode struct {
+	ancestor   Ancestor
+	parentID   string
+	parentType string
+}
+
+// ancestry resolves the ancestor chains of a read, see
+// NotionSourceConfig.Ancestry.
+//
+// A nil *ancestry means ancestors are not resolved.
+type ancestry struct {
+	mu    sync.Mutex
+	nodes map[string]ancestryNode
+}
+
+// newAncestry creates the ancestry of a read.
+//
+// Arguments:
+// - config: The source configuration.
+//
+// Returns:
+// - The ancestry, or nil if ancestors are not resolved.
+func newAncestry(config NotionSourceConfig) *ancestry {
+	if !config.Ancestry {
+		return nil
+	}
+	return &ancestry{nodes: make(map[string]ancestryNode)}
+}
+
+// learnPage records a page.
+//
+// Arguments:
+// - page: The page.
+func (a *ancestry) learnPage(page *types.Page) {
+	if a == nil {
+		return
+	}
+	a.learn(string(page.ID), AncestorPage, pageTitle(page), page.Parent)
+}
+
+// learnDatabase records a database.
+//
+// Arguments:
+// - database: The database.
+func (a *ancestry) learnDatabase(database *types.Database) {
+	if a == nil {
+		return
+	}
+	a.learn(string(database.ID), AncestorDatabase, database.GetTitle(), database.Parent)
+}
+
+// learnBlock records a block reached by walking a page's block tree.
+//
+// child_page and child_database blocks share the ID of their page or
+// database, whose node takes precedence, since it carries the real parent.
+//
+// Arguments:
+// - block: The block.
+// - pageID: The ID of the page the block belongs to.
+// - parentID: The ID of the block's parent page or block.
+func (a *ancestry) learnBlock(block *types.Block, pageID string, parentID string) {
+	if a == nil {
+		return
+	}
+
+	parentType := AncestorBlock
+	if parentID == pageID {
+		parentType = AncestorPage
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if node, ok := a.nodes[string(block.ID)]; ok && node.ancestor.Type != AncestorBlock {
+		return
+	}
+	a.nodes[string(block.ID)] = ancestryNode{
+		ancestor:   Ancestor{ID: string(block.ID), Type: AncestorBlock, Title: blockTitle(block)},
+		parentID:   parentID,
+		parentType: parentType,
+	}
+}
+
+// learn records an object and its parent.
+//
+// Arguments:
+// - id: The ID of the object.
+// - objType: The ancestor type of the object.
+// - title: The title of the object.
+// - parent: The parent of the object, or nil if unknown.
+func (a *ancestry) learn(id string, objType string, title string, parent *types.Parent) {
+	node := ancestryNode{ancestor: Ancestor{ID: id, Type: objType, Title: title}}
+	if parent != nil {
+		node.parentID, node.parentType = parent.GetParentID(), parentAncestorType(parent)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.nodes[id] = node
+}
+
+// node returns a recorded object.
+//
+// Arguments:
+// - id: The ID of the object.
+//
+// Returns:
+// - The node, and false if the object has not been recorded.
+func (a *ancestry) node(id string) (ancestryNode, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	node, ok := a.nodes[id]
+	return node, ok
+}
+
+// ancestors returns the ancestors of an object the read has seen, fetching the
+// pages, databases and blocks above it that the read has not reached.
+//
+// A chain ends at the workspace, at a parent that cannot be fetched, or after
+// maxAncestry ancestors.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - id: The ID of the object.
+//
+// Returns:
+// - The ancestors root first, or nil if the object has not been seen.
+func (ns *Plugin) ancestors(ctx context.Context, scope readScope, id string) []Ancestor {
+	node, ok := scope.ancestry.node(id)
+	if !ok {
+		return nil
+	}
+
+	var chain []Ancestor
+	seen := map[string]bool{id: true}
+	for len(chain) < maxAncestry {
+		if node.parentType == AncestorWorkspace {
+			chain = append(chain, Ancestor{Type: AncestorWorkspace})
+			break
+		}
+		if node.parentID == "" || seen[node.parentID] {
+			break
+		}
+		seen[node.parentID] = true
+
+		parent, ok := ns.resolveAncestor(ctx, scope, node.parentID, node.parentType)
+		if !ok {
+			chain = append(chain, Ancestor{ID: node.parentID, Type: node.parentType})
+			break
+		}
+		chain = append(chain, parent.ancestor)
+		node = parent
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// resolveAncestor returns a parent of an object, fetching pages, databases and
+// blocks the read has not seen.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - id: The ID of the parent.
+// - parentType: The ancestor type of the parent.
+//
+// Returns:
+// - The parent, and false if it could not be resolved.
+func (ns *Plugin) resolveAncestor(ctx context.Context, scope readScope, id string, parentType string) (ancestryNode, bool) {
+	if node, ok := scope.ancestry.node(id); ok {
+		return node, true
+	}
+
+	switch parentType {
+	case AncestorPage:
+		page, err := ns.getPage(ctx, types.PageID(id))
+		if err != nil {
+			scope.errors.report(ctx, err, OperationGetPage)
+			break
+		}
+		scope.ancestry.learnPage(page)
+	case AncestorDatabase:
+		database, err := ns.getDatabase(ctx, types.DatabaseID(id))
+		if err != nil {
+			scope.errors.report(ctx, err, OperationGetDatabase)
+			break
+		}
+		scope.ancestry.learnDatabase(database)
+	case AncestorBlock:
+		block, err := ns.getBlock(ctx, types.BlockID(id))
+		if err != nil {
+			scope.errors.report(ctx, err, OperationGetBlock)
+			break
+		}
+		scope.ancestry.learn(string(block.ID), AncestorBlock, blockTitle(block), block.Parent)
+	}
+
+	if node, ok := scope.ancestry.node(id); ok {
+		return node, true
+	}
+
+	// Parents that cannot be resolved are remembered without a title or
+	// parent, so they are only fetched once.
+	scope.ancestry.learn(id, parentType, "", nil)
+	return ancestryNode{}, false
+}
+
+// getBlock fetches a single block.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - blockID: The ID of the block.
+//
+// Returns:
+// - The block.
+// - An error if the request failed.
+func (ns *Plugin) getBlock(ctx context.Context, blockID types.BlockID) (*types.Block, error) {
+	call := apiCall{
+		operation:  OperationGetBlock,
+		objectID:   string(blockID),
+		objectType: common.ObjectTypeBlock,
+	}
+	return doRequest(ctx, ns, call, func() (*types.Block, error) {
+		result := ns.client.Blocks().Get(ctx, blockID, client.DefaultGetBlockOptions())
+		if result.IsError() {
+			return nil, result.Error
+		}
+		return result.Data.Block, nil
+	})
+}
+
+// attachAncestors stores the ancestors of an item in its metadata.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - item: The item.
+func (ns *Plugin) attachAncestors(ctx context.Context, scope readScope, item *engine.DataItemContainer[any]) {
+	if scope.ancestry == nil {
+		return
+	}
+	if chain := ns.ancestors(ctx, scope, item.ID); chain != nil {
+		item.Metadata.Properties["ancestors"] = chain
+	}
+}
+
+// parentAncestorType returns the ancestor type of a parent.
+//
+// Arguments:
+// - parent: The parent.
+//
+// Returns:
+// - The ancestor type, or empty for unknown parent types.
+func parentAncestorType(parent *types.Parent) string {
+	switch parent.Type {
+	case types.ParentTypeWorkspace:
+		return AncestorWorkspace
+	case types.ParentTypePage:
+		return AncestorPage
+	case types.ParentTypeDatabase:
+		return AncestorDatabase
+	case types.ParentTypeBlock:
+		return AncestorBlock
+	}
+	return ""
+}
+
+// pageTitle returns the plain text title of a page.
+//
+// Arguments:
+// - page: The page.
+//
+// Returns:
+// - The title, empty if the page has no title property.
+func pageTitle(page *types.Page) string {
+	if page.PropertyContainer == nil {
+		return ""
+	}
+	for _, property := range page.Properties {
+		if property.Type == types.PropertyTypeTitle {
+			return types.ToPlainText(property.Title)
+		}
+	}
+	return ""
+}
+
+// blockTitle returns the text a block contributes to breadcrumbs.
+//
+// Arguments:
+// - block: The block.
+//
+// Returns:
+// - The title of a child page or database, the text of a heading, or empty.
+func blockTitle(block *types.Block) string {
+	switch {
+	case block.ChildPage != nil:
+		return block.ChildPage.Title
+	case block.ChildDatabase != nil:
+		return block.ChildDatabase.Title
+	case block.Heading1 != nil:
+		return types.ToPlainText(block.Heading1.RichText)
+	case block.Heading2 != nil:
+		return types.ToPlainText(block.Heading2.RichText)
+	case block.Heading3 != nil:
+		return types.ToPlainText(block.Heading3.RichText)
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestReadAttachesAncestors(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	top := workspace.AddPage(newWorkspacePage("Top"))
+	heading := workspace.AddBlocks(string(top.ID), types.NewHeading1Block([]types.RichText{*types.NewTextRichText("Heading", nil)}))[0]
+	child := newWorkspacePage("Child")
+	child.Parent = &types.Parent{Type: types.ParentTypeBlock, BlockID: &heading.ID}
+	workspace.AddPage(child)
+	paragraph := workspace.AddBlocks(string(child.ID), newParagraph("text"))[0]
+
+	// The read starts below the heading, which it only reaches as a parent.
+	config := testConfig()
+	config.Ancestry = true
+	config.RootPageIDs = []string{string(child.ID)}
+	ns, server := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+	above := []Ancestor{
+		{Type: AncestorWorkspace},
+		{ID: string(top.ID), Type: AncestorPage, Title: "Top"},
+		{ID: string(heading.ID), Type: AncestorBlock, Title: "Heading"},
+	}
+	want := map[string][]Ancestor{
+		string(child.ID):     above,
+		string(paragraph.ID): append(above[:len(above):len(above)], Ancestor{ID: string(child.ID), Type: AncestorPage, Title: "Child"}),
+	}
+	for _, item := range items {
+		chain, _ := item.Metadata.Properties["ancestors"].([]Ancestor)
+		if got, want := fmt.Sprint(chain), fmt.Sprint(want[item.ID]); got != want {
+			t.Errorf("ancestors of %s %s = %s, want %s", item.Type, item.ID, got, want)
+		}
+	}
+	if n := countRequests(server, "GET /v1/blocks/"+string(heading.ID)); n != 1 {
+		t.Errorf("the heading was fetched %d times, want once", n)
+	}
+	if errs := ns.LastErrorSummary().Errors; len(errs) != 0 {
+		t.Errorf("errors = %v, want none", errs)
+	}
+}
+
+func TestAncestryPrefersPagesOverChildPageBlocks(t *testing.T) {
+	database := newWorkspaceDatabase("Posts")
+	database.ID = types.DatabaseID(missingPageID)
+	page := newRow(database, "Row", true)
+	page.ID = "aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa"
+	block := &types.Block{ID: types.BlockID(page.ID), Type: types.BlockTypeChildPage, ChildPage: &types.ChildPageBlock{Title: "Row"}}
+
+	tests := []struct {
+		name  string
+		learn func(a *ancestry)
+	}{
+		{name: "page first", learn: func(a *ancestry) {
+			a.learnPage(page)
+			a.learnBlock(block, "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", "cccccccc-cccc-4ccc-8ccc-cccccccccccc")
+		}},
+		{name: "block first", learn: func(a *ancestry) {
+			a.learnBlock(block, "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", "cccccccc-cccc-4ccc-8ccc-cccccccccccc")
+			a.learnPage(page)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAncestry(NotionSourceConfig{Ancestry: true})
+			tt.learn(a)
+
+			node, _ := a.node(string(page.ID))
+			if node.ancestor.Type != AncestorPage || node.parentID != string(database.ID) || node.parentType != AncestorDatabase {
+				t.Errorf("node = %+v, want the page below its database", node)
+			}
+		})
+	}
+}
+
+func TestAncestorsStopAtCyclesAndDepth(t *testing.T) {
+	ns, server := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+
+	blockParent := func(id string) *types.Parent {
+		blockID := types.BlockID(id)
+		return &types.Parent{Type: types.ParentTypeBlock, BlockID: &blockID}
+	}
+	blockID := func(i int) string {
+		return fmt.Sprintf("aaaaaaaa-aaaa-4aaa-8aaa-%012d", i)
+	}
+
+	t.Run("cycle", func(t *testing.T) {
+		a := newAncestry(NotionSourceConfig{Ancestry: true})
+		a.learn(blockID(1), AncestorBlock, "", blockParent(blockID(2)))
+		a.learn(blockID(2), AncestorBlock, "", blockParent(blockID(1)))
+
+		chain := ns.ancestors(context.Background(), readScope{ancestry: a}, blockID(1))
+		if len(chain) != 1 || chain[0].ID != blockID(2) {
+			t.Errorf("ancestors = %v, want the other block of the cycle", chain)
+		}
+	})
+
+	t.Run("depth", func(t *testing.T) {
+		a := newAncestry(NotionSourceConfig{Ancestry: true})
+		for i := 0; i < 2*maxAncestry; i++ {
+			a.learn(blockID(i), AncestorBlock, "", blockParent(blockID(i+1)))
+		}
+
+		chain := ns.ancestors(context.Background(), readScope{ancestry: a}, blockID(0))
+		if len(chain) != maxAncestry || chain[len(chain)-1].ID != blockID(1) {
+			t.Errorf("resolved %d ancestors, want the nearest %d", len(chain), maxAncestry)
+		}
+	})
+
+	if requests := server.Requests(); len(requests) != 0 {
+		t.Errorf("requests = %v, want recorded ancestors only", requests)
+	}
+}
@@ -0,0 +1,94 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/cmskitdev/client"
+)
+
+// endpoint is implemented by the request types of the types package.
+type endpoint interface {
+	GetPath() string
+	GetMethod() string
+}
+
+// apiRequest adapts a request of the types package to the client's
+// RequestInterface.
+//
+// The namespaces of the client stream every page of a listing, or lack the
+// endpoint altogether, while reads fetch one page at a time, so listings are
+// executed through operators registered by callAPI instead.
+type apiRequest struct {
+	endpoint endpoint
+}
+
+func (r apiRequest) GetPath() string {
+	return r.endpoint.GetPath()
+}
+
+func (r apiRequest) GetMethod() string {
+	return r.endpoint.GetMethod()
+}
+
+// GetBody returns the request itself, whose JSON tags leave out the IDs that
+// are part of the path.
+func (r apiRequest) GetBody() interface{} {
+	return r.endpoint
+}
+
+func (r apiRequest) GetQuery() url.Values {
+	if query, ok := r.endpoint.(interface{ GetQuery() url.Values }); ok {
+		return query.GetQuery()
+	}
+	return nil
+}
+
+// Validate accepts every request, the Notion API reports invalid ones.
+func (r apiRequest) Validate() error {
+	return nil
+}
+
+// apiOperator returns the operator decoding responses into T, registering it
+// with the client registry on first use.
+//
+// Arguments:
+// - registry: The client registry.
+//
+// Returns:
+// - The operator.
+// - An error if the operator could not be created.
+func apiOperator[T any](registry *client.Registry) (*client.Operator[T], error) {
+	var zero T
+	name := fmt.Sprintf("notion:%T", zero)
+	if !registry.HasOperator(name) {
+		registry.Register(name, func(httpClient *client.HTTPClient, config *client.OperatorConfig) interface{} {
+			return client.NewOperator[T](httpClient, config)
+		})
+	}
+	return client.GetTyped[*client.Operator[T]](registry, name)
+}
+
+// callAPI executes a request of the types package with the client.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - registry: The client registry.
+// - req: The request.
+//
+// Returns:
+// - The decoded response.
+// - An error if the request failed.
+func callAPI[T any](ctx context.Context, registry *client.Registry, req endpoint) (*T, error) {
+	op, err := apiOperator[T](registry)
+	if err != nil {
+		return nil, err
+	}
+
+	result := client.Execute[T, apiRequest, interface{}](op, ctx, apiRequest{endpoint: req})
+	if result.IsError() {
+		return nil, result.Error
+	}
+	return &result.Data, nil
+}
@@ -0,0 +1,92 @@
+package notion
+
+import (
+	"github.com/cmskitdev/engine"
+)
+
+// ArchivedMode controls how archived and trashed objects are read.
+//
+// An object counts as archived when it is archived or in the trash itself, or
+// when one of its ancestor pages, databases or blocks is.
+type ArchivedMode string
+
+const (
+	// ArchivedExclude skips archived objects and everything below them. This
+	// is the default.
+	ArchivedExclude ArchivedMode = "exclude"
+	// ArchivedInclude emits archived objects alongside live ones.
+	ArchivedInclude ArchivedMode = "include"
+	// ArchivedOnly emits archived objects only, which is useful to unpublish
+	// them downstream. Live objects are still walked to find archived
+	// descendants.
+	ArchivedOnly ArchivedMode = "only"
+)
+
+// Reasons stored in the "tombstone_reason" property of archived items.
+const (
+	tombstoneArchived = "archived"
+	tombstoneInTrash  = "in_trash"
+	tombstoneAncestor = "ancestor_archived"
+)
+
+// archiveState returns why an object is considered archived.
+//
+// Arguments:
+// - archived: The object's Archived flag.
+// - inTrash: The object's InTrash flag.
+//
+// Returns:
+// - The tombstone reason, or an empty string if the object is live.
+func (s readScope) archiveState(archived bool, inTrash bool) string {
+	switch {
+	case inTrash:
+		return tombstoneInTrash
+	case archived:
+		return tombstoneArchived
+	case s.inArchive:
+		return tombstoneAncestor
+	}
+	return ""
+}
+
+// admitArchived applies the configured ArchivedMode to an object.
+//
+// Arguments:
+// - state: The object's tombstone reason, see readScope.archiveState.
+//
+// Returns:
+// - Whether the object is emitted.
+// - Whether the object's descendants are visited.
+func (c NotionSourceConfig) admitArchived(state string) (bool, bool) {
+	archived := state != ""
+	switch c.Archived {
+	case ArchivedInclude:
+		return true, true
+	case ArchivedOnly:
+		return archived, true
+	default:
+		return !archived, !archived
+	}
+}
+
+// markTombstone adds tombstone metadata to an item for an archived object, so
+// that destinations can unpublish it.
+//
+// Arguments:
+// - item: The item to mark.
+// - state: The object's tombstone reason, empty for live objects.
+//
+// Returns:
+// - The item.
+func markTombstone(item *engine.DataItemContainer[any], state string) *engine.DataItemContainer[any] {
+	if state == "" {
+		return item
+	}
+
+	if item.Metadata.Properties == nil {
+		item.Metadata.Properties = make(map[string]interface{})
+	}
+	item.Metadata.Properties["tombstone"] = true
+	item.Metadata.Properties["tombstone_reason"] = state
+	return item
+}
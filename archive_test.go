@@ -0,0 +1,119 @@
+package notion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+)
+
+func TestAdmitArchived(t *testing.T) {
+	tests := []struct {
+		mode    ArchivedMode
+		state   string
+		admit   bool
+		descend bool
+	}{
+		{mode: ArchivedExclude, state: "", admit: true, descend: true},
+		{mode: ArchivedExclude, state: tombstoneArchived},
+		{mode: "", state: tombstoneInTrash},
+		{mode: ArchivedInclude, state: "", admit: true, descend: true},
+		{mode: ArchivedInclude, state: tombstoneAncestor, admit: true, descend: true},
+		{mode: ArchivedOnly, state: "", descend: true},
+		{mode: ArchivedOnly, state: tombstoneArchived, admit: true, descend: true},
+	}
+
+	for _, tt := range tests {
+		config := NotionSourceConfig{Archived: tt.mode}
+		if admit, descend := config.admitArchived(tt.state); admit != tt.admit || descend != tt.descend {
+			t.Errorf("%q with %q = %v, %v, want %v, %v", tt.mode, tt.state, admit, descend, tt.admit, tt.descend)
+		}
+	}
+}
+
+func TestReadArchivedModes(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	live := workspace.AddPage(newWorkspacePage("Live"))
+	archived := newWorkspacePage("Archived")
+	archived.Archived = true
+	workspace.AddPage(archived)
+
+	liveBlock := workspace.AddBlocks(string(live.ID), newParagraph("live"))[0]
+	archivedBlock := newParagraph("archived")
+	archivedBlock.Archived = true
+	workspace.AddBlocks(string(live.ID), archivedBlock)
+	belowBlock := workspace.AddBlocks(string(archivedBlock.ID), newParagraph("below an archived block"))[0]
+	belowPage := workspace.AddBlocks(string(archived.ID), newParagraph("below an archived page"))[0]
+
+	reasons := map[string]interface{}{
+		string(live.ID):          nil,
+		string(liveBlock.ID):     nil,
+		string(archived.ID):      tombstoneArchived,
+		string(archivedBlock.ID): tombstoneArchived,
+		string(belowBlock.ID):    tombstoneAncestor,
+		string(belowPage.ID):     tombstoneAncestor,
+	}
+
+	tests := []struct {
+		mode   ArchivedMode
+		pages  []string
+		blocks []string
+	}{
+		{
+			mode:   ArchivedExclude,
+			pages:  []string{string(live.ID)},
+			blocks: []string{string(liveBlock.ID)},
+		},
+		{
+			mode:   ArchivedInclude,
+			pages:  []string{string(live.ID), string(archived.ID)},
+			blocks: []string{string(liveBlock.ID), string(archivedBlock.ID), string(belowBlock.ID), string(belowPage.ID)},
+		},
+		{
+			mode:   ArchivedOnly,
+			pages:  []string{string(archived.ID)},
+			blocks: []string{string(archivedBlock.ID), string(belowBlock.ID), string(belowPage.ID)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			config := testConfig()
+			config.Archived = tt.mode
+			// Search leaves out archived pages, so they are read as roots.
+			config.RootPageIDs = []string{string(live.ID), string(archived.ID)}
+			ns, _ := newTestPlugin(t, workspace, config)
+
+			items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+			assertEmittedOnce(t, "page", itemIDs(items, common.ObjectTypePage), tt.pages)
+			assertEmittedOnce(t, "block", itemIDs(items, common.ObjectTypeBlock), tt.blocks)
+			for _, item := range items {
+				assertTombstone(t, item, reasons[item.ID])
+			}
+		})
+	}
+}
+
+// assertTombstone checks the tombstone metadata of an item.
+//
+// Arguments:
+// - t: The test.
+// - item: The item.
+// - reason: The tombstone reason expected, or nil for a live item.
+func assertTombstone(t *testing.T, item engine.DataItemContainer[any], reason interface{}) {
+	t.Helper()
+
+	properties := item.Metadata.Properties
+	if reason == nil {
+		if _, ok := properties["tombstone"]; ok {
+			t.Errorf("live %s %s is marked as a tombstone", item.Type, item.ID)
+		}
+		return
+	}
+	if properties["tombstone"] != true || properties["tombstone_reason"] != reason {
+		t.Errorf("%s %s tombstone = %v %v, want %v", item.Type, item.ID, properties["tombstone"], properties["tombstone_reason"], reason)
+	}
+}
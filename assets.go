@@ -0,0 +1,518 @@
+package notion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// AssetConfig configures the download of the files referenced by items.
+//
+// Notion-hosted file URLs expire about an hour after they are read, so files
+// are downloaded as items are emitted and the items are rewritten to
+// reference the stored copies.
+type AssetConfig struct {
+	// Store stores the downloaded files. Files are not downloaded when nil.
+	Store AssetStore `json:"-"`
+	// IncludeExternal also downloads files linked from external URLs, which
+	// do not expire.
+	IncludeExternal bool `json:"include_external,omitempty"`
+	// MaxConcurrent is the maximum number of concurrent downloads. Defaults
+	// to 4.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+	// HTTPClient is the client files are downloaded with. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client `json:"-"`
+}
+
+// StoredAsset describes a file kept by an AssetStore. The assets of an item
+// are listed in its "assets" metadata property.
+type StoredAsset struct {
+	// Hash is the hex-encoded SHA-256 of the content.
+	Hash string `json:"hash"`
+	// URL is the URL that now references the file in the item.
+	URL string `json:"url"`
+	// SourceURL is the URL the file was downloaded from, without its query.
+	SourceURL string `json:"source_url"`
+	// ContentType is the content type the file was served with.
+	ContentType string `json:"content_type,omitempty"`
+	// Size is the size of the content in bytes.
+	Size int64 `json:"size"`
+}
+
+// AssetStore stores downloaded files by content.
+//
+// Implementations must be safe for concurrent use.
+type AssetStore interface {
+	// Put stores a file and returns the URL referencing it. Storing the same
+	// content twice returns the same URL.
+	Put(ctx context.Context, name string, contentType string, content io.Reader) (*StoredAsset, error)
+}
+
+// MemoryAssetStore keeps files in memory, which is useful for tests.
+type MemoryAssetStore struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewMemoryAssetStore creates an empty in-memory asset store.
+//
+// Returns:
+// - A new in-memory asset store.
+func NewMemoryAssetStore() *MemoryAssetStore {
+	return &MemoryAssetStore{
+		files: make(map[string][]byte),
+	}
+}
+
+// Put implements AssetStore.Put. The returned URL is "asset:" followed by the
+// hash of the content.
+func (s *MemoryAssetStore) Put(ctx context.Context, name string, contentType string, content io.Reader) (*StoredAsset, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset %s: %w", name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	s.files[hash] = data
+	s.mu.Unlock()
+
+	return &StoredAsset{
+		Hash:        hash,
+		URL:         "asset:" + hash,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}, nil
+}
+
+// Get returns the content stored under a hash.
+//
+// Arguments:
+// - hash: The hash of the content.
+//
+// Returns:
+// - The content, and false if nothing is stored under the hash.
+func (s *MemoryAssetStore) Get(hash string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.files[hash]
+	return data, ok
+}
+
+// FileAssetStore stores files in a directory, named after the hash of their
+// content, e.g. "ab/ab12…ef". Names carry no extension, so that the same
+// content is stored once whatever URL it was found at; the content type is
+// listed in the item's StoredAsset.
+type FileAssetStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileAssetStore creates an asset store writing to a directory.
+//
+// Arguments:
+// - dir: The directory to store files in, created if missing.
+// - baseURL: The URL the directory is served from, prefixed to the paths of
+// stored files. Items reference the paths relative to dir when empty.
+//
+// Returns:
+// - A new file asset store.
+func NewFileAssetStore(dir string, baseURL string) *FileAssetStore {
+	return &FileAssetStore{dir: dir, baseURL: baseURL}
+}
+
+// Put implements AssetStore.Put.
+func (s *FileAssetStore) Put(ctx context.Context, name string, contentType string, content io.Reader) (*StoredAsset, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create asset directory %s: %w", s.dir, err)
+	}
+
+	// The content is hashed while it is written, so the final name is only
+	// known once it has been fully downloaded.
+	tmp, err := os.CreateTemp(s.dir, ".asset-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create asset %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write asset %s: %w", name, err)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	rel := path.Join(hash[:2], hash)
+	target := filepath.Join(s.dir, filepath.FromSlash(rel))
+
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create asset directory: %w", err)
+		}
+		if err := os.Rename(tmp.Name(), target); err != nil {
+			return nil, fmt.Errorf("failed to store asset %s: %w", name, err)
+		}
+	}
+
+	ref := rel
+	if s.baseURL != "" {
+		ref, err = url.JoinPath(s.baseURL, rel)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset base URL %q: %w", s.baseURL, err)
+		}
+	}
+
+	return &StoredAsset{
+		Hash:        hash,
+		URL:         ref,
+		ContentType: contentType,
+		Size:        size,
+	}, nil
+}
+
+// assetRef points at a file URL inside an item's data.
+type assetRef struct {
+	url *string
+	// expiry is the expiry time of a Notion-hosted URL, nil for external
+	// ones.
+	expiry *string
+}
+
+// assetCollector gathers the file references of an item.
+type assetCollector struct {
+	external bool
+	refs     []assetRef
+}
+
+// file collects the references of a file object.
+//
+// Arguments:
+// - file: The file object, may be nil.
+func (c *assetCollector) file(file *types.File) {
+	if file == nil {
+		return
+	}
+	if file.File != nil && file.File.URL != "" {
+		c.refs = append(c.refs, assetRef{url: &file.File.URL, expiry: &file.File.ExpiryTime})
+	}
+	if c.external && file.External != nil && file.External.URL != "" {
+		c.refs = append(c.refs, assetRef{url: &file.External.URL})
+	}
+}
+
+// icon collects the references of an icon.
+//
+// Arguments:
+// - icon: The icon, may be nil.
+func (c *assetCollector) icon(icon *types.Icon) {
+	if icon != nil {
+		c.file(icon.File)
+	}
+}
+
+// cover collects the references of a cover.
+//
+// Arguments:
+// - cover: The cover, may be nil.
+func (c *assetCollector) cover(cover *types.Cover) {
+	if cover == nil {
+		return
+	}
+	c.file(cover.File)
+	if c.external {
+		c.file(cover.External)
+	}
+}
+
+// collect gathers the file references of an item's data: the file, image,
+// video, audio and pdf blocks, files properties, icons, covers and comment
+// attachments.
+//
+// Arguments:
+// - data: The data of the item.
+func (c *assetCollector) collect(data any) {
+	switch data := data.(type) {
+	case *types.Page:
+		c.icon(data.Icon)
+		c.cover(data.Cover)
+		if data.PropertyContainer != nil {
+			for _, property := range data.Properties {
+				for i := range property.Files {
+					c.file(property.Files[i].File)
+					if c.external {
+						c.file(property.Files[i].External)
+					}
+				}
+			}
+		}
+	case *types.Database:
+		c.icon(data.Icon)
+		c.cover(data.Cover)
+	case *types.Block:
+		for _, block := range []*types.FileBlock{data.File, data.Image, data.Video, data.Audio, data.PDF} {
+			if block == nil {
+				continue
+			}
+			c.file(block.File)
+			if c.external {
+				c.file(block.External)
+			}
+		}
+		if data.Callout != nil {
+			c.icon(data.Callout.Icon)
+		}
+	case *types.Comment:
+		for i := range data.Attachments {
+			if file := data.Attachments[i].File; file != nil && file.URL != "" {
+				c.refs = append(c.refs, assetRef{url: &file.URL, expiry: &file.ExpiryTime})
+			}
+		}
+	}
+}
+
+// assetDownloader downloads the files referenced by items and rewrites the
+// items to reference the stored copies.
+//
+// A nil *assetDownloader leaves items untouched.
+type assetDownloader struct {
+	config AssetConfig
+	client *http.Client
+	slots  chan struct{}
+}
+
+// assetFetches holds the downloads of a single read by source URL without
+// its query, since Notion signs the same file with a different query on
+// every request. They are dropped with the read, so that a long-lived plugin
+// does not keep every file it ever downloaded.
+//
+// A nil *assetFetches shares no downloads.
+type assetFetches struct {
+	mu      sync.Mutex
+	fetches map[string]*assetFetch
+}
+
+// assetFetch is a download shared by every reference to the same file.
+type assetFetch struct {
+	done  chan struct{}
+	asset *StoredAsset
+	err   error
+}
+
+// newAssetDownloader creates the downloader of a plugin.
+//
+// Arguments:
+// - config: The asset configuration.
+//
+// Returns:
+// - The downloader, or nil if no store is configured.
+func newAssetDownloader(config AssetConfig) *assetDownloader {
+	if config.Store == nil {
+		return nil
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	size := config.MaxConcurrent
+	if size <= 0 {
+		size = 4
+	}
+
+	return &assetDownloader{
+		config: config,
+		client: client,
+		slots:  make(chan struct{}, size),
+	}
+}
+
+// newFetches creates the downloads of a read.
+//
+// Returns:
+// - The downloads, or nil if no store is configured.
+func (d *assetDownloader) newFetches() *assetFetches {
+	if d == nil {
+		return nil
+	}
+	return &assetFetches{fetches: make(map[string]*assetFetch)}
+}
+
+// localize downloads the files an item references and rewrites the item to
+// reference the stored copies. Files that fail to download are reported and
+// keep their original URL.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - item: The item to rewrite.
+func (d *assetDownloader) localize(ctx context.Context, scope readScope, item *engine.DataItemContainer[any]) {
+	if d == nil {
+		return
+	}
+
+	collector := assetCollector{external: d.config.IncludeExternal}
+	collector.collect(item.Data)
+	if len(collector.refs) == 0 {
+		return
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		stored []*StoredAsset
+	)
+	for _, ref := range collector.refs {
+		wg.Add(1)
+		go func(ref assetRef) {
+			defer wg.Done()
+
+			asset, err := d.fetch(ctx, scope.assets, *ref.url)
+			if err != nil {
+				scope.errors.report(ctx, newReadError(apiCall{
+					operation:  OperationDownloadAsset,
+					objectID:   item.ID,
+					objectType: item.Type,
+				}, err), OperationDownloadAsset)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			*ref.url = asset.URL
+			if ref.expiry != nil {
+				*ref.expiry = ""
+			}
+			stored = append(stored, asset)
+		}(ref)
+	}
+	wg.Wait()
+
+	if len(stored) > 0 {
+		if item.Metadata.Properties == nil {
+			item.Metadata.Properties = make(map[string]interface{})
+		}
+		item.Metadata.Properties["assets"] = stored
+	}
+}
+
+// fetch downloads a file once, sharing the result with every concurrent and
+// later reference to it in the same read.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - fetches: The downloads of the read.
+// - rawURL: The URL of the file.
+//
+// Returns:
+// - The stored file.
+// - An error if the file could not be downloaded or stored.
+func (d *assetDownloader) fetch(ctx context.Context, fetches *assetFetches, rawURL string) (*StoredAsset, error) {
+	source, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset URL: %w", err)
+	}
+	key := *source
+	key.RawQuery = ""
+	key.Fragment = ""
+
+	if fetches == nil {
+		return d.download(ctx, rawURL, key.String())
+	}
+
+	fetches.mu.Lock()
+	fetch, ok := fetches.fetches[key.String()]
+	if !ok {
+		fetch = &assetFetch{done: make(chan struct{})}
+		fetches.fetches[key.String()] = fetch
+	}
+	fetches.mu.Unlock()
+
+	if !ok {
+		fetch.asset, fetch.err = d.download(ctx, rawURL, key.String())
+		if fetch.err != nil {
+			// Failed downloads are retried by the next reference.
+			fetches.mu.Lock()
+			delete(fetches.fetches, key.String())
+			fetches.mu.Unlock()
+		}
+		close(fetch.done)
+	}
+
+	select {
+	case <-fetch.done:
+		return fetch.asset, fetch.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// assetStatusError is returned when a file download fails with an HTTP
+// status.
+type assetStatusError struct {
+	url    string
+	status int
+}
+
+func (e *assetStatusError) Error() string {
+	return fmt.Sprintf("failed to download %s: %d %s", e.url, e.status, http.StatusText(e.status))
+}
+
+// download fetches a file and stores it.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - rawURL: The URL of the file.
+// - sourceURL: The URL of the file without its query.
+//
+// Returns:
+// - The stored file.
+// - An error if the file could not be downloaded or stored.
+func (d *assetDownloader) download(ctx context.Context, rawURL string, sourceURL string) (*StoredAsset, error) {
+	select {
+	case d.slots <- struct{}{}:
+		defer func() { <-d.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", sourceURL, err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", sourceURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &assetStatusError{url: sourceURL, status: resp.StatusCode}
+	}
+
+	name, _ := url.PathUnescape(path.Base(req.URL.Path))
+	asset, err := d.config.Store.Put(ctx, name, resp.Header.Get("Content-Type"), resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	asset.SourceURL = sourceURL
+	return asset, nil
+}
@@ -0,0 +1,180 @@
+package notion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestReadDownloadsEachFileOnce(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	store := NewMemoryAssetStore()
+	config := testConfig()
+	config.Assets = AssetConfig{Store: store}
+	ns, server := newTestPlugin(t, workspace, config)
+
+	content := []byte("png")
+	hosted := server.AddFile("image.png", "image/png", content)
+	source, err := url.Parse(hosted.File.URL)
+	if err != nil {
+		t.Fatalf("invalid file URL: %v", err)
+	}
+
+	// Notion signs every reference to a file with a different query.
+	image := func(signature string) *types.Block {
+		file := *hosted
+		file.File = &types.NotionHostedFileType{URL: hosted.File.URL + "?X-Amz-Signature=" + signature, ExpiryTime: hosted.File.ExpiryTime}
+		return &types.Block{Type: types.BlockTypeImage, Image: &types.FileBlock{Type: types.FileBlockTypeFile, File: &file}}
+	}
+	external := "https://example.com/a.png"
+	page := newWorkspacePage("Page")
+	page.Cover = &types.Cover{Type: "file", File: image("cover").Image.File}
+	workspace.AddPage(page)
+	workspace.AddBlocks(string(page.ID), image("a"), image("b"), &types.Block{
+		Type:  types.BlockTypeImage,
+		Image: &types.FileBlock{Type: types.FileBlockTypeExternal, External: &types.File{Type: "external", External: &types.ExternalFileType{URL: external}}},
+	})
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+	if n := countRequests(server, "GET "+source.Path); n != 1 {
+		t.Errorf("the file was downloaded %d times, want once", n)
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if stored, ok := store.Get(hash); !ok || string(stored) != string(content) {
+		t.Errorf("stored content = %q, want %q", stored, content)
+	}
+
+	var rewritten int
+	for _, item := range items {
+		var files []*types.File
+		switch data := item.Data.(type) {
+		case *types.Page:
+			files = append(files, data.Cover.File)
+		case *types.Block:
+			if data.Image.File == nil {
+				if url := data.Image.External.External.URL; url != external {
+					t.Errorf("external URL = %s, want it untouched", url)
+				}
+				continue
+			}
+			files = append(files, data.Image.File)
+		}
+
+		for _, file := range files {
+			if file.File.URL != "asset:"+hash || file.File.ExpiryTime != "" {
+				t.Errorf("file of %s = %+v, want the stored copy without expiry", item.ID, file.File)
+			}
+			rewritten++
+		}
+		if len(files) == 0 {
+			continue
+		}
+		assets, _ := item.Metadata.Properties["assets"].([]*StoredAsset)
+		if len(assets) != 1 || assets[0].SourceURL != hosted.File.URL || assets[0].ContentType != "image/png" {
+			t.Errorf("assets of %s = %v, want the file without its query", item.ID, assets)
+		}
+	}
+	if rewritten != 3 {
+		t.Errorf("rewrote %d files, want the cover and both images", rewritten)
+	}
+}
+
+func TestAssetCollectorFindsFiles(t *testing.T) {
+	hosted := func() *types.File {
+		return &types.File{Type: "file", File: &types.NotionHostedFileType{URL: "https://files.example.com/a.png"}}
+	}
+	linked := func() *types.File {
+		return &types.File{Type: "external", External: &types.ExternalFileType{URL: "https://example.com/a.png"}}
+	}
+
+	page := newWorkspacePage("Page")
+	page.Icon = &types.Icon{Type: "file", File: hosted()}
+	page.Cover = &types.Cover{Type: "external", External: linked()}
+	page.Properties["Files"] = types.Property{Type: types.PropertyTypeFiles, Files: []types.FileProperty{
+		{Name: "a.png", Type: "file", File: hosted()},
+		{Name: "b.png", Type: "external", External: linked()},
+	}}
+	block := &types.Block{
+		Type: types.BlockTypePDF,
+		PDF:  &types.FileBlock{Type: types.FileBlockTypeFile, File: hosted()},
+	}
+	callout := &types.Block{Type: types.BlockTypeCallout, Callout: &types.CalloutBlock{Icon: &types.Icon{Type: "file", File: hosted()}}}
+	comment := &types.Comment{Attachments: []types.CommentAttachment{{File: &types.CommentFileObject{URL: "https://files.example.com/c.png"}}}}
+
+	tests := []struct {
+		name     string
+		data     any
+		hosted   int
+		external int
+	}{
+		{name: "page", data: page, hosted: 2, external: 2},
+		{name: "block", data: block, hosted: 1},
+		{name: "callout", data: callout, hosted: 1},
+		{name: "comment", data: comment, hosted: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, includeExternal := range []bool{false, true} {
+				collector := assetCollector{external: includeExternal}
+				collector.collect(tt.data)
+
+				want := tt.hosted
+				if includeExternal {
+					want += tt.external
+				}
+				if len(collector.refs) != want {
+					t.Errorf("refs with external %v = %d, want %d", includeExternal, len(collector.refs), want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileAssetStoreKeysOnContent(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileAssetStore(dir, "https://assets.example.com/notion")
+
+	first, err := store.Put(context.Background(), "a.png", "image/png", strings.NewReader("content"))
+	if err != nil {
+		t.Fatalf("failed to store: %v", err)
+	}
+	// The same content under another name and type is the same asset.
+	second, err := store.Put(context.Background(), "b.jpeg", "image/jpeg", strings.NewReader("content"))
+	if err != nil {
+		t.Fatalf("failed to store again: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte("content"))
+	hash := hex.EncodeToString(sum[:])
+	want := "https://assets.example.com/notion/" + hash[:2] + "/" + hash
+	if first.URL != want || second.URL != want || first.Hash != hash || first.Size != int64(len("content")) {
+		t.Errorf("assets = %+v and %+v, want both at %s", first, second, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, hash[:2], hash))
+	if err != nil || string(data) != "content" {
+		t.Errorf("stored file = %q, %v, want the content", data, err)
+	}
+	var files []string
+	_ = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	})
+	if len(files) != 1 {
+		t.Errorf("files = %v, want one without leftovers", files)
+	}
+}
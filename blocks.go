@@ -0,0 +1,144 @@
+package notion
+
+import (
+	"context"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// blockLeaf reports whether a block's children belong to another object
+// rather than to the block tree of the current page.
+//
+// Child pages and child databases have children of their own, but those are
+// read as separate pages and databases instead of being walked inline.
+//
+// Arguments:
+// - block: The block to check.
+//
+// Returns:
+// - True if the block's children must not be walked.
+func blockLeaf(block *types.Block) bool {
+	return block.Type == types.BlockTypeChildPage || block.Type == types.BlockTypeChildDatabase
+}
+
+// walkBlocks recursively emits the block tree below a page or block.
+//
+// Children embedded in a block (toggles, columns, synced blocks, tables, ...)
+// are walked directly, otherwise blocks with HasChildren are listed through the
+// API. Walking stops descending once NotionSourceConfig.MaxDepth is reached.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - pageID: The ID of the page the tree belongs to.
+// - parentID: The ID of the page or block whose children are walked.
+// - depth: The depth of the children being walked, starting at 1.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled while walking, true otherwise.
+func (ns *Plugin) walkBlocks(ctx context.Context, pageID string, parentID string, depth int, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	fetch := func(cursor *string) (*listPage[types.Block], error) {
+		page, err := ns.listBlockChildren(ctx, types.BlockID(parentID), cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &listPage[types.Block]{results: page.Results, nextCursor: page.NextCursor, hasMore: page.HasMore}, nil
+	}
+
+	return paginate(ctx, scope, "children:"+parentID, fetch, func(block *types.Block) bool {
+		return ns.emitBlockTree(ctx, block, pageID, parentID, depth, scope, results)
+	})
+}
+
+// emitBlockTree emits a block, its comments when the scope includes them, and
+// then its descendants. Root-scoped reads also crawl the page or database
+// behind child_page and child_database blocks.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - block: The block to emit.
+// - pageID: The ID of the page the block belongs to.
+// - parentID: The ID of the block's parent page or block.
+// - depth: The depth of the block, starting at 1.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) emitBlockTree(ctx context.Context, block *types.Block, pageID string, parentID string, depth int, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	state := scope.archiveState(block.Archived, block.InTrash)
+	admit, descend := ns.config.admitArchived(state)
+	if !admit && !descend {
+		return true
+	}
+	scope.inArchive = state != ""
+	scope.ancestry.learnBlock(block, pageID, parentID)
+
+	// Incremental reads still walk the whole tree of a changed page, since a
+	// changed block may sit below unchanged ones, but only emit changed blocks.
+	if scope.blocks && admit && scope.marks.changed(checkpointKeyBlocks, block.LastEditedTime) {
+		item := markTombstone(ns.convertBlockToDataItem(block, pageID, parentID, depth), state)
+		if !ns.emit(ctx, scope, item, results) {
+			return false
+		}
+	}
+
+	if !ns.discoverUsers(ctx, scope, results, block.CreatedBy, block.LastEditedBy) {
+		return false
+	}
+
+	if scope.comments && !ns.readComments(ctx, pageID, string(block.ID), scope, results) {
+		return false
+	}
+
+	if scope.roots != nil && !ns.crawlChild(ctx, block, scope, results) {
+		return false
+	}
+
+	if blockLeaf(block) || !ns.config.shouldDescend(depth) {
+		return true
+	}
+
+	if children := block.GetChildren(); len(children) > 0 {
+		for i := range children {
+			if !ns.emitBlockTree(ctx, &children[i], pageID, string(block.ID), depth+1, scope, results) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if block.HasChildren {
+		return ns.walkBlocks(ctx, pageID, string(block.ID), depth+1, scope, results)
+	}
+
+	return true
+}
+
+// listBlockChildren fetches a single page of children for a page or block.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - blockID: The ID of the page or block whose children are listed.
+// - cursor: The cursor to resume from, or nil for the first page.
+//
+// Returns:
+// - The page of child blocks.
+// - An error if the request failed.
+func (ns *Plugin) listBlockChildren(ctx context.Context, blockID types.BlockID, cursor *string) (*types.BlockListResponse, error) {
+	call := apiCall{
+		operation:  OperationListBlockChildren,
+		objectID:   string(blockID),
+		objectType: common.ObjectTypeBlock,
+	}
+	return doRequest(ctx, ns, call, func() (*types.BlockListResponse, error) {
+		return callAPI[types.BlockListResponse](ctx, ns.client.Registry, &types.BlockChildrenRequest{
+			BlockID:     blockID,
+			StartCursor: cursor,
+			PageSize:    &ns.config.PageSize,
+		})
+	})
+}
@@ -0,0 +1,326 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Checkpoint is the state persisted between incremental reads.
+type Checkpoint struct {
+	// Marks holds the high-water mark of LastEditedTime per checkpoint key.
+	Marks map[string]time.Time `json:"marks"`
+	// Objects holds the pages and databases seen by reads that detect
+	// deletions, keyed by ID.
+	Objects map[string]KnownObject `json:"objects,omitempty"`
+	// Hashes holds the content hashes of the items emitted by reads that skip
+	// unchanged items, keyed by type and ID.
+	Hashes map[string]string `json:"hashes,omitempty"`
+	// UpdatedAt is when the checkpoint was last saved.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointStore persists checkpoints between reads.
+//
+// Implementations must be safe for concurrent use.
+type CheckpointStore interface {
+	// Load returns the last saved checkpoint, or an empty checkpoint if none
+	// has been saved yet.
+	Load(ctx context.Context) (*Checkpoint, error)
+	// Save persists the checkpoint, replacing any previous one.
+	Save(ctx context.Context, checkpoint *Checkpoint) error
+}
+
+// MemoryCheckpointStore keeps the checkpoint in memory, which is useful for
+// tests and for long-lived processes that sync on an interval.
+type MemoryCheckpointStore struct {
+	mu         sync.RWMutex
+	checkpoint *Checkpoint
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store.
+//
+// Returns:
+// - A new in-memory checkpoint store.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{}
+}
+
+// Load implements CheckpointStore.Load.
+func (s *MemoryCheckpointStore) Load(ctx context.Context) (*Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.checkpoint == nil {
+		return newCheckpoint(), nil
+	}
+	return s.checkpoint.clone(), nil
+}
+
+// Save implements CheckpointStore.Save.
+func (s *MemoryCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoint = checkpoint.clone()
+	return nil
+}
+
+// FileCheckpointStore persists the checkpoint as a JSON file.
+type FileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointStore creates a checkpoint store backed by the given file.
+//
+// Arguments:
+// - path: The path of the JSON file, created on the first save.
+//
+// Returns:
+// - A new file checkpoint store.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Load implements CheckpointStore.Load.
+func (s *FileCheckpointStore) Load(ctx context.Context) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return newCheckpoint(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint %s: %w", s.path, err)
+	}
+
+	checkpoint := newCheckpoint()
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", s.path, err)
+	}
+	if checkpoint.Marks == nil {
+		checkpoint.Marks = make(map[string]time.Time)
+	}
+	return checkpoint, nil
+}
+
+// Save implements CheckpointStore.Save.
+func (s *FileCheckpointStore) Save(ctx context.Context, checkpoint *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	return writeFileAtomic(s.path, data)
+}
+
+// newCheckpoint creates an empty checkpoint.
+//
+// Returns:
+// - A checkpoint without any marks.
+func newCheckpoint() *Checkpoint {
+	return &Checkpoint{
+		Marks: make(map[string]time.Time),
+	}
+}
+
+// clone returns a deep copy of the checkpoint.
+//
+// Returns:
+// - The copied checkpoint.
+func (c *Checkpoint) clone() *Checkpoint {
+	clone := &Checkpoint{
+		Marks:     make(map[string]time.Time, len(c.Marks)),
+		UpdatedAt: c.UpdatedAt,
+	}
+	for key, mark := range c.Marks {
+		clone.Marks[key] = mark
+	}
+	if c.Objects != nil {
+		clone.Objects = make(map[string]KnownObject, len(c.Objects))
+		for id, object := range c.Objects {
+			clone.Objects[id] = object
+		}
+	}
+	if c.Hashes != nil {
+		clone.Hashes = make(map[string]string, len(c.Hashes))
+		for id, hash := range c.Hashes {
+			clone.Hashes[id] = hash
+		}
+	}
+	return clone
+}
+
+// watermarks tracks the high-water marks for a single incremental read: the
+// marks loaded from the previous checkpoint and the marks observed so far.
+//
+// A nil *watermarks means the read is not incremental and treats every object
+// as changed.
+type watermarks struct {
+	mu    sync.Mutex
+	since map[string]time.Time
+	next  map[string]time.Time
+}
+
+// newWatermarks creates watermarks starting from a loaded checkpoint.
+//
+// Arguments:
+// - checkpoint: The checkpoint loaded from the store.
+//
+// Returns:
+// - The watermarks for the read.
+func newWatermarks(checkpoint *Checkpoint) *watermarks {
+	w := &watermarks{
+		since: make(map[string]time.Time, len(checkpoint.Marks)),
+		next:  make(map[string]time.Time, len(checkpoint.Marks)),
+	}
+	for key, mark := range checkpoint.Marks {
+		w.since[key] = mark
+		w.next[key] = mark
+	}
+	return w
+}
+
+// changed reports whether an object was edited at or after the mark for its
+// key, and records the edit time for the next checkpoint.
+//
+// The comparison is inclusive because Notion truncates edit times to the
+// minute, so edits made in the same minute as the last sync are re-read
+// rather than lost.
+//
+// Arguments:
+// - key: The checkpoint key of the object.
+// - editedAt: The object's LastEditedTime.
+//
+// Returns:
+// - True if the object must be emitted.
+func (w *watermarks) changed(key string, editedAt time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if editedAt.After(w.next[key]) {
+		w.next[key] = editedAt
+	}
+	return !editedAt.Before(w.since[key])
+}
+
+// mark returns the mark loaded for a key.
+//
+// Arguments:
+// - key: The checkpoint key.
+//
+// Returns:
+// - The mark, and false if the key has never been checkpointed.
+func (w *watermarks) mark(key string) (time.Time, bool) {
+	if w == nil {
+		return time.Time{}, false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	mark, ok := w.since[key]
+	return mark, ok
+}
+
+// checkpoint returns the checkpoint to persist after the read.
+//
+// Returns:
+// - The checkpoint holding the highest marks observed.
+func (w *watermarks) checkpoint() *Checkpoint {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	checkpoint := newCheckpoint()
+	for key, mark := range w.next {
+		checkpoint.Marks[key] = mark
+	}
+	checkpoint.UpdatedAt = time.Now()
+	return checkpoint
+}
+
+// databaseCheckpointKey returns the checkpoint key for the rows of a database.
+//
+// Arguments:
+// - databaseID: The ID of the database.
+//
+// Returns:
+// - The checkpoint key.
+func databaseCheckpointKey(databaseID string) string {
+	return checkpointKeyDatabases + ":" + databaseID
+}
+
+// Checkpoint keys for the object types tracked by incremental reads.
+const (
+	checkpointKeyPages     = "page"
+	checkpointKeyDatabases = "collection"
+	checkpointKeyBlocks    = "block"
+)
+
+// loadCheckpoint loads the checkpoint for reads that are incremental, detect
+// deletions or skip unchanged items.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - The checkpoint, or nil if the source needs none.
+// - An error if the checkpoint could not be loaded.
+func (ns *Plugin) loadCheckpoint(ctx context.Context) (*Checkpoint, error) {
+	if !ns.config.Incremental && !ns.config.DetectDeletions && !ns.config.SkipUnchanged {
+		return nil, nil
+	}
+	if ns.config.Checkpoints == nil {
+		return nil, fmt.Errorf("incremental reads, deletion detection and skipping unchanged items require a checkpoint store")
+	}
+
+	checkpoint, err := ns.config.Checkpoints.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// saveCheckpoint saves the checkpoint after a read that ran to completion.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - previous: The checkpoint loaded when the read started.
+// - scope: The stages run for the read.
+// - complete: Whether the read saw every object it tracks.
+// - advance: Whether the marks may advance, which they may not if a listing
+// failed: the objects behind it were never compared against their marks and
+// would be skipped for good.
+//
+// Returns:
+// - An error if the checkpoint could not be saved.
+func (ns *Plugin) saveCheckpoint(ctx context.Context, previous *Checkpoint, scope readScope, complete bool, advance bool) error {
+	checkpoint := previous.clone()
+	if scope.marks != nil && advance {
+		checkpoint.Marks = scope.marks.checkpoint().Marks
+	}
+	if scope.presence != nil {
+		checkpoint.Objects = scope.presence.known(scope, complete)
+	}
+	if scope.hashes != nil {
+		// Incremental reads do not see unchanged objects, so their hashes
+		// are kept.
+		checkpoint.Hashes = scope.hashes.known(complete && scope.marks == nil)
+	}
+	checkpoint.UpdatedAt = time.Now()
+
+	return ns.config.Checkpoints.Save(ctx, checkpoint)
+}
@@ -0,0 +1,199 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+)
+
+// missingPageID is the ID of a page no test workspace holds.
+const missingPageID = "11111111-1111-4111-8111-111111111111"
+
+func TestMemoryCheckpointStoreCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCheckpointStore()
+
+	checkpoint, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if checkpoint.Marks == nil || len(checkpoint.Marks) != 0 {
+		t.Fatalf("marks = %v, want empty", checkpoint.Marks)
+	}
+
+	mark := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	checkpoint.Marks[checkpointKeyPages] = mark
+	checkpoint.Hashes = map[string]string{"page:a": "hash"}
+	if err := store.Save(ctx, checkpoint); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	checkpoint.Marks[checkpointKeyPages] = mark.Add(time.Hour)
+	checkpoint.Hashes["page:a"] = "changed"
+
+	loaded, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if !loaded.Marks[checkpointKeyPages].Equal(mark) || loaded.Hashes["page:a"] != "hash" {
+		t.Errorf("loaded = %+v, want the checkpoint as saved", loaded)
+	}
+}
+
+func TestFileCheckpointStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	checkpoint, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("failed to load a missing checkpoint: %v", err)
+	}
+	if len(checkpoint.Marks) != 0 {
+		t.Fatalf("marks = %v, want empty", checkpoint.Marks)
+	}
+
+	mark := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	checkpoint.Marks[checkpointKeyPages] = mark
+	checkpoint.Objects = map[string]KnownObject{"a": {Type: common.ObjectTypePage}}
+	checkpoint.Hashes = map[string]string{"page:a": "hash"}
+	if err := store.Save(ctx, checkpoint); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	loaded, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if !loaded.Marks[checkpointKeyPages].Equal(mark) {
+		t.Errorf("mark = %s, want %s", loaded.Marks[checkpointKeyPages], mark)
+	}
+	if loaded.Objects["a"].Type != common.ObjectTypePage || loaded.Hashes["page:a"] != "hash" {
+		t.Errorf("loaded = %+v, want the objects and hashes saved", loaded)
+	}
+}
+
+func TestFileCheckpointStoreRejectsCorruptFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileCheckpointStore(path).Load(context.Background()); err == nil {
+		t.Error("expected an error for a corrupt checkpoint")
+	}
+}
+
+func TestWatermarksChangedIsInclusive(t *testing.T) {
+	mark := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	marks := newWatermarks(&Checkpoint{Marks: map[string]time.Time{checkpointKeyPages: mark}})
+
+	if marks.changed(checkpointKeyPages, mark.Add(-time.Minute)) {
+		t.Error("an object edited before the mark was reported changed")
+	}
+	if !marks.changed(checkpointKeyPages, mark) {
+		t.Error("an object edited in the minute of the mark was reported unchanged")
+	}
+	if !marks.changed(checkpointKeyPages, mark.Add(time.Hour)) {
+		t.Error("an object edited after the mark was reported unchanged")
+	}
+	if next := marks.checkpoint().Marks[checkpointKeyPages]; !next.Equal(mark.Add(time.Hour)) {
+		t.Errorf("next mark = %s, want %s", next, mark.Add(time.Hour))
+	}
+
+	var none *watermarks
+	if !none.changed(checkpointKeyPages, mark.Add(-time.Hour)) {
+		t.Error("reads that are not incremental must treat every object as changed")
+	}
+}
+
+func TestIncrementalReadEmitsChangedPages(t *testing.T) {
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	workspace := notiontest.NewWorkspace()
+	older := newWorkspacePage("Older")
+	older.LastEditedTime = base
+	workspace.AddPage(older)
+	newer := newWorkspacePage("Newer")
+	newer.LastEditedTime = base.Add(time.Hour)
+	workspace.AddPage(newer)
+
+	config := testConfig()
+	config.Incremental = true
+	config.Checkpoints = NewMemoryCheckpointStore()
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	read := func() string {
+		items := readAll(t, context.Background(), ns, common.ObjectTypePage)
+		return fmt.Sprint(itemIDs(items, common.ObjectTypePage))
+	}
+
+	if got, want := read(), fmt.Sprint([]string{string(newer.ID), string(older.ID)}); got != want {
+		t.Errorf("first read = %s, want %s", got, want)
+	}
+	// Edits in the minute of the mark are read again.
+	if got, want := read(), fmt.Sprint([]string{string(newer.ID)}); got != want {
+		t.Errorf("second read = %s, want %s", got, want)
+	}
+
+	older.LastEditedTime = base.Add(2 * time.Hour)
+	if got, want := read(), fmt.Sprint([]string{string(older.ID), string(newer.ID)}); got != want {
+		t.Errorf("read after an edit = %s, want %s", got, want)
+	}
+	if got, want := read(), fmt.Sprint([]string{string(older.ID)}); got != want {
+		t.Errorf("read after the edit was checkpointed = %s, want %s", got, want)
+	}
+}
+
+func TestIncrementalReadKeepsMarksAfterListingErrors(t *testing.T) {
+	mark := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	workspace := notiontest.NewWorkspace()
+	page := newWorkspacePage("Page")
+	page.LastEditedTime = mark.Add(time.Hour)
+	workspace.AddPage(page)
+
+	store := NewMemoryCheckpointStore()
+	if err := store.Save(context.Background(), &Checkpoint{Marks: map[string]time.Time{checkpointKeyPages: mark}}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := testConfig()
+	config.Incremental = true
+	config.Checkpoints = store
+	config.RootPageIDs = []string{string(page.ID), missingPageID}
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage)
+	if got := itemIDs(items, common.ObjectTypePage); len(got) != 1 {
+		t.Fatalf("pages = %v, want the page that could be read", got)
+	}
+	if errs := ns.LastErrorSummary().Errors; len(errs) != 1 || errs[0].Operation != OperationGetPage {
+		t.Fatalf("errors = %v, want the missing page", errs)
+	}
+
+	checkpoint, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := checkpoint.Marks[checkpointKeyPages]; !got.Equal(mark) {
+		t.Errorf("mark = %s, want %s kept after the failed read", got, mark)
+	}
+
+	// Once every page can be read, the marks advance.
+	config.RootPageIDs = []string{string(page.ID)}
+	ns, _ = newTestPlugin(t, workspace, config)
+	readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	checkpoint, err = store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := checkpoint.Marks[checkpointKeyPages]; !got.Equal(page.LastEditedTime) {
+		t.Errorf("mark = %s, want %s", got, page.LastEditedTime)
+	}
+}
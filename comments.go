@@ -0,0 +1,70 @@
+package notion
+
+import (
+	"context"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// readComments emits every unresolved comment on a page or block.
+//
+// Comments count as archived when the page or block they belong to is, see
+// ArchivedMode.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - pageID: The ID of the page the commented object belongs to.
+// - parentID: The ID of the page or block whose comments are read.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled while reading, true otherwise.
+func (ns *Plugin) readComments(ctx context.Context, pageID string, parentID string, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	state := scope.archiveState(false, false)
+	if admit, _ := ns.config.admitArchived(state); !admit {
+		return true
+	}
+
+	fetch := func(cursor *string) (*listPage[types.Comment], error) {
+		page, err := ns.listComments(ctx, types.BlockID(parentID), cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &listPage[types.Comment]{results: page.Results, nextCursor: page.NextCursor, hasMore: page.HasMore}, nil
+	}
+
+	return paginate(ctx, scope, "", fetch, func(comment *types.Comment) bool {
+		if !ns.emit(ctx, scope, markTombstone(ns.convertCommentToDataItem(comment, pageID), state), results) {
+			return false
+		}
+		return ns.discoverUsers(ctx, scope, results, comment.CreatedBy)
+	})
+}
+
+// listComments fetches a single page of comments for a page or block.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - blockID: The ID of the page or block whose comments are listed.
+// - cursor: The cursor to resume from, or nil for the first page.
+//
+// Returns:
+// - The page of comments.
+// - An error if the request failed.
+func (ns *Plugin) listComments(ctx context.Context, blockID types.BlockID, cursor *string) (*types.CommentListResponse, error) {
+	call := apiCall{
+		operation:  OperationListComments,
+		objectID:   string(blockID),
+		objectType: common.ObjectTypeComment,
+	}
+	return doRequest(ctx, ns, call, func() (*types.CommentListResponse, error) {
+		return callAPI[types.CommentListResponse](ctx, ns.client.Registry, &types.CommentListRequest{
+			BlockID:     blockID,
+			StartCursor: cursor,
+			PageSize:    &ns.config.PageSize,
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package notion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestReadEmitsCommentsOnNestedBlocks(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	page := workspace.AddPage(newWorkspacePage("Page"))
+	top := workspace.AddBlocks(string(page.ID), newParagraph("top"))[0]
+	nested := workspace.AddBlocks(string(top.ID), newParagraph("nested"))[0]
+
+	comment := func(parent *types.CommentParent, text string) string {
+		return string(workspace.AddComments(types.NewComment(parent, []types.RichText{*types.NewTextRichText(text, nil)}))[0].ID)
+	}
+	onPage := comment(&types.CommentParent{Type: types.CommentParentTypePage, PageID: &page.ID}, "on the page")
+	onTop := comment(&types.CommentParent{Type: types.CommentParentTypeBlock, BlockID: &top.ID}, "on the top block")
+	onNested := comment(&types.CommentParent{Type: types.CommentParentTypeBlock, BlockID: &nested.ID}, "on the nested block")
+
+	ns, _ := newTestPlugin(t, workspace, testConfig())
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock, common.ObjectTypeComment)
+
+	assertEmittedOnce(t, "comments", itemIDs(items, common.ObjectTypeComment), []string{onPage, onTop, onNested})
+	for _, item := range items {
+		if item.Type != common.ObjectTypeComment {
+			continue
+		}
+		if pageID := item.Metadata.Properties["page_id"]; pageID != string(page.ID) {
+			t.Errorf("page_id of comment %s = %v, want %s", item.ID, pageID, page.ID)
+		}
+	}
+	if errs := ns.LastErrorSummary().Errors; len(errs) != 0 {
+		t.Errorf("errors = %v, want none", errs)
+	}
+}
+
+func TestReadSkipsCommentsBelowMaxDepth(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	page := workspace.AddPage(newWorkspacePage("Page"))
+	top := workspace.AddBlocks(string(page.ID), newParagraph("top"))[0]
+	nested := workspace.AddBlocks(string(top.ID), newParagraph("nested"))[0]
+	onTop := workspace.AddComments(types.NewComment(
+		&types.CommentParent{Type: types.CommentParentTypeBlock, BlockID: &top.ID},
+		[]types.RichText{*types.NewTextRichText("on the top block", nil)},
+	))[0]
+	workspace.AddComments(types.NewComment(
+		&types.CommentParent{Type: types.CommentParentTypeBlock, BlockID: &nested.ID},
+		[]types.RichText{*types.NewTextRichText("on the nested block", nil)},
+	))
+
+	config := testConfig()
+	config.MaxDepth = 1
+	ns, server := newTestPlugin(t, workspace, config)
+	items := readAll(t, context.Background(), ns, common.ObjectTypeBlock, common.ObjectTypeComment)
+
+	assertEmittedOnce(t, "comments", itemIDs(items, common.ObjectTypeComment), []string{string(onTop.ID)})
+	if n := countRequests(server, "GET /v1/comments"); n != 2 {
+		t.Errorf("comments were listed %d times, want for the page and the top block", n)
+	}
+}
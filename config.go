@@ -0,0 +1,209 @@
+package notion
+
+import (
+	"time"
+
+	"github.com/cmskitdev/notion/types"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NotionSourceConfig configures what the Notion source reads and how hard it
+// pushes the Notion API while doing so.
+type NotionSourceConfig struct {
+	// SearchQuery optionally narrows the workspace search to matching titles.
+	SearchQuery string `json:"search_query,omitempty"`
+
+	// IncludePages controls whether pages are read.
+	IncludePages bool `json:"include_pages"`
+	// IncludeCollections controls whether databases are read.
+	IncludeCollections bool `json:"include_collections"`
+	// IncludeBlocks controls whether the block tree of each page is read.
+	IncludeBlocks bool `json:"include_blocks"`
+	// IncludeComments controls whether comments are read.
+	IncludeComments bool `json:"include_comments"`
+	// IncludeUsers controls whether workspace users are read.
+	IncludeUsers bool `json:"include_users"`
+	// IncludeDatabaseRows controls whether the rows of every database read are
+	// queried and emitted as pages.
+	IncludeDatabaseRows bool `json:"include_database_rows"`
+
+	// RootPageIDs and RootDatabaseIDs restrict reads to these pages and
+	// databases and everything below them (child pages, child databases and
+	// database rows) instead of searching the whole workspace. IDs may be in
+	// dashed or undashed form.
+	RootPageIDs     []string `json:"root_page_ids,omitempty"`
+	RootDatabaseIDs []string `json:"root_database_ids,omitempty"`
+
+	// Archived controls whether archived and trashed pages, databases, rows
+	// and blocks are skipped, emitted with tombstone metadata, or emitted
+	// exclusively. Defaults to ArchivedExclude.
+	Archived ArchivedMode `json:"archived,omitempty"`
+
+	// RelationHops makes reads follow the relation properties of the pages
+	// they read, up to this many relations away, reading the related pages
+	// that are not read otherwise. The relations of each page are recorded as
+	// RelationEdge values in the "relations" metadata property. Zero disables
+	// following relations.
+	RelationHops int `json:"relation_hops,omitempty"`
+
+	// Ancestry resolves the pages, databases and blocks above every emitted
+	// page, database and block, fetching ancestors the read does not reach,
+	// and records them root first as Ancestor values in the "ancestors"
+	// metadata property.
+	Ancestry bool `json:"ancestry,omitempty"`
+
+	// Validation runs the types package validators on the emitted pages,
+	// databases, blocks and comments, and controls whether invalid items are
+	// emitted flagged, dropped or fail the read. Defaults to ValidationOff.
+	Validation ValidationMode `json:"validation,omitempty"`
+
+	// DatabaseQueries optionally filters and sorts the rows queried for a
+	// database, keyed by database ID in dashed or undashed form.
+	DatabaseQueries map[string]DatabaseQuery `json:"database_queries,omitempty"`
+
+	// MaxDepth limits how deep block trees are walked, where the top-level
+	// blocks of a page are at depth 1. Zero or less means unlimited.
+	MaxDepth int `json:"max_depth"`
+	// PageSize is the number of results requested per API page (max 100).
+	PageSize int `json:"page_size"`
+	// RequestsPerSecond is the sustained request rate allowed against the API
+	// across all reads of the source. Zero or less means unlimited.
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	// MaxConcurrent is the maximum number of concurrent workers.
+	MaxConcurrent int `json:"max_concurrent"`
+	// HydratePages fetches every page found by search in full, using a pool of
+	// MaxConcurrent workers, instead of processing the shallow search result.
+	HydratePages bool `json:"hydrate_pages"`
+
+	// Incremental makes each read emit only the pages, databases and blocks
+	// edited since the checkpoint saved by the previous successful read.
+	Incremental bool `json:"incremental"`
+	// DetectDeletions makes each read compare the pages and databases it sees
+	// with those seen by the previous read, and emit tombstones with
+	// ProcessingStatusDeleted for the ones that were deleted, unshared or
+	// archived since. Requires Checkpoints.
+	DetectDeletions bool `json:"detect_deletions"`
+	// SkipUnchanged makes each read skip the pages, databases, blocks and
+	// comments whose content hash (see the "content_hash" metadata property)
+	// matches the hash saved by the previous read. Requires Checkpoints.
+	SkipUnchanged bool `json:"skip_unchanged,omitempty"`
+	// Watch keeps reads running after the initial crawl, polling /search
+	// every WatchInterval for the pages and databases edited since the
+	// previous poll until the context is cancelled. Changed pages are emitted
+	// with their blocks and comments walked again. Since watching reads only
+	// end when cancelled, their RunSummary is never marked completed.
+	Watch bool `json:"watch,omitempty"`
+	// WatchInterval is how often watching reads poll for changes. Defaults
+	// to one minute.
+	WatchInterval time.Duration `json:"watch_interval,omitempty"`
+	// Checkpoints stores the high-water marks between incremental reads and
+	// the objects seen by reads that detect deletions.
+	Checkpoints CheckpointStore `json:"-"`
+
+	// CrawlState stores the progress of runs so that a read started with the
+	// run ID of an interrupted read (see WithRunID) resumes where it stopped.
+	CrawlState CrawlStateStore `json:"-"`
+	// CrawlStateFlushInterval is how often crawl state is saved while a read
+	// is running. Defaults to 5 seconds.
+	CrawlStateFlushInterval time.Duration `json:"crawl_state_flush_interval,omitempty"`
+
+	// Assets configures downloading the files referenced by items, which is
+	// disabled unless Assets.Store is set.
+	Assets AssetConfig `json:"assets,omitempty"`
+
+	// CloseTimeout is how long Close waits for running reads and writes to
+	// exit. Defaults to 30 seconds.
+	CloseTimeout time.Duration `json:"close_timeout,omitempty"`
+
+	// MeterProvider receives the plugin's OpenTelemetry metrics. Defaults to
+	// the global provider.
+	MeterProvider metric.MeterProvider `json:"-"`
+	// TracerProvider receives a span for every API call and crawl stage.
+	// Defaults to the global provider.
+	TracerProvider trace.TracerProvider `json:"-"`
+
+	// Write configures where a Destination publishes pages and blocks, see
+	// NewNotionDestination.
+	Write WriteConfig `json:"write,omitempty"`
+}
+
+// DatabaseQuery holds the filter and sorts applied when querying the rows of
+// a single database.
+type DatabaseQuery struct {
+	Filter *types.QueryFilter `json:"filter,omitempty"`
+	Sorts  []*types.QuerySort `json:"sorts,omitempty"`
+}
+
+// DefaultNotionSourceConfig returns a configuration that reads pages,
+// databases, blocks and comments using the API's documented rate limit.
+//
+// Returns:
+// - The default source configuration.
+func DefaultNotionSourceConfig() NotionSourceConfig {
+	return NotionSourceConfig{
+		IncludePages:        true,
+		IncludeCollections:  true,
+		IncludeBlocks:       true,
+		IncludeComments:     true,
+		IncludeUsers:        false,
+		IncludeDatabaseRows: true,
+		Archived:            ArchivedExclude,
+		MaxDepth:            0,
+		PageSize:            100,
+		RequestsPerSecond:   3.0,
+		MaxConcurrent:       3,
+	}
+}
+
+// shouldDescend reports whether blocks at the given depth may have their
+// children walked.
+//
+// Arguments:
+// - depth: The depth of the block whose children would be walked.
+//
+// Returns:
+// - True if the configured MaxDepth allows going one level deeper.
+func (c NotionSourceConfig) shouldDescend(depth int) bool {
+	return c.MaxDepth <= 0 || depth < c.MaxDepth
+}
+
+// databaseQuery returns the configured query for a database, matching keys in
+// either dashed or undashed form.
+//
+// Arguments:
+// - databaseID: The canonical ID of the database.
+//
+// Returns:
+// - The configured query, or an empty query if none is configured.
+func (c NotionSourceConfig) databaseQuery(databaseID types.DatabaseID) DatabaseQuery {
+	if query, ok := c.DatabaseQueries[string(databaseID)]; ok {
+		return query
+	}
+
+	for key, query := range c.DatabaseQueries {
+		if id, err := types.ParseDatabaseID(key); err == nil && id == databaseID {
+			return query
+		}
+	}
+
+	return DatabaseQuery{}
+}
+
+// filteredDatabases returns the databases whose rows are filtered by
+// DatabaseQueries.
+//
+// Returns:
+// - The canonical IDs of the filtered databases.
+func (c NotionSourceConfig) filteredDatabases() map[string]bool {
+	filtered := make(map[string]bool)
+	for key, query := range c.DatabaseQueries {
+		if query.Filter == nil {
+			continue
+		}
+		if id, err := types.ParseDatabaseID(key); err == nil {
+			filtered[string(id)] = true
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,723 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// runIDKey is the context key holding the run ID of a read.
+type runIDKey struct{}
+
+// WithRunID returns a context that makes Read use the given run ID.
+//
+// Reads that share a run ID resume from the crawl state persisted by the
+// previous read with that ID, see NotionSourceConfig.CrawlState. Reads started
+// without one get a generated run ID, which identifies their RunSummary.
+//
+// Arguments:
+// - ctx: The parent context.
+// - runID: The run ID to use.
+//
+// Returns:
+// - The derived context.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// RunIDFromContext returns the run ID set with WithRunID, or the one generated
+// by Read for the contexts it passes on.
+//
+// Arguments:
+// - ctx: The context to read from.
+//
+// Returns:
+// - The run ID, or an empty string if none is set.
+func RunIDFromContext(ctx context.Context) string {
+	runID, _ := ctx.Value(runIDKey{}).(string)
+	return runID
+}
+
+// CrawlState is the progress of a single run, persisted so that the run can
+// be resumed after it was cancelled or the process crashed.
+//
+// Load returns the whole progress of a run, while the states passed to Save
+// only hold what changed since the previous save, so that saving does not
+// grow with the number of objects the run has read.
+type CrawlState struct {
+	// RunID is the ID of the run the state belongs to.
+	RunID string `json:"run_id"`
+	// Cursors holds the start_cursor of the next page to fetch per listing
+	// that is not finished.
+	Cursors map[string]string `json:"cursors"`
+	// Completed holds the listings that were read to the end.
+	Completed map[string]bool `json:"completed,omitempty"`
+	// Emitted holds the type and ID of the objects already emitted, see
+	// itemKey.
+	Emitted map[string]bool `json:"emitted,omitempty"`
+	// Seen holds the pages and databases seen by reads that detect deletions.
+	Seen map[string]KnownObject `json:"seen,omitempty"`
+	// UpdatedAt is when the state was last saved.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CrawlStateStore persists crawl state per run ID.
+//
+// Implementations must be safe for concurrent use.
+type CrawlStateStore interface {
+	// Load returns the state saved for a run, or an empty state if none has
+	// been saved yet.
+	Load(ctx context.Context, runID string) (*CrawlState, error)
+	// Save persists the progress of a run since the previous save: the
+	// cursors replace the saved ones, while the completed listings and the
+	// emitted and seen objects are added to the saved ones.
+	Save(ctx context.Context, state *CrawlState) error
+	// Delete removes the state of a run once it has completed.
+	Delete(ctx context.Context, runID string) error
+}
+
+// MemoryCrawlStateStore keeps crawl state in memory, which survives cancelled
+// reads but not process restarts.
+type MemoryCrawlStateStore struct {
+	mu     sync.RWMutex
+	states map[string]*CrawlState
+}
+
+// NewMemoryCrawlStateStore creates an empty in-memory crawl state store.
+//
+// Returns:
+// - A new in-memory crawl state store.
+func NewMemoryCrawlStateStore() *MemoryCrawlStateStore {
+	return &MemoryCrawlStateStore{
+		states: make(map[string]*CrawlState),
+	}
+}
+
+// Load implements CrawlStateStore.Load.
+func (s *MemoryCrawlStateStore) Load(ctx context.Context, runID string) (*CrawlState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if state, ok := s.states[runID]; ok {
+		return state.clone(), nil
+	}
+	return newCrawlState(runID), nil
+}
+
+// Save implements CrawlStateStore.Save.
+func (s *MemoryCrawlStateStore) Save(ctx context.Context, state *CrawlState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, ok := s.states[state.RunID]
+	if !ok {
+		saved = newCrawlState(state.RunID)
+		s.states[state.RunID] = saved
+	}
+	saved.Cursors = make(map[string]string, len(state.Cursors))
+	for key, cursor := range state.Cursors {
+		saved.Cursors[key] = cursor
+	}
+	saved.add(state)
+	saved.UpdatedAt = state.UpdatedAt
+	return nil
+}
+
+// Delete implements CrawlStateStore.Delete.
+func (s *MemoryCrawlStateStore) Delete(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, runID)
+	return nil
+}
+
+// FileCrawlStateStore persists crawl state as two files per run ID: a JSON
+// file holding the cursors, which is replaced on every save, and a log the
+// completed listings and the emitted and seen objects are appended to.
+type FileCrawlStateStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileCrawlStateStore creates a crawl state store writing to a directory.
+//
+// Arguments:
+// - dir: The directory holding the state files, created on the first save.
+//
+// Returns:
+// - A new file crawl state store.
+func NewFileCrawlStateStore(dir string) *FileCrawlStateStore {
+	return &FileCrawlStateStore{dir: dir}
+}
+
+// Load implements CrawlStateStore.Load.
+func (s *FileCrawlStateStore) Load(ctx context.Context, runID string) (*CrawlState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := newCrawlState(runID)
+
+	path := s.path(runID)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read crawl state %s: %w", path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("failed to decode crawl state %s: %w", path, err)
+		}
+	}
+
+	path = s.logPath(runID)
+	data, err = os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read crawl state %s: %w", path, err)
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	// The last line is empty unless the process crashed while appending it,
+	// in which case it is incomplete and dropped.
+	for _, line := range lines[:len(lines)-1] {
+		var entry crawlStateEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode crawl state %s: %w", path, err)
+		}
+		entry.addTo(state)
+	}
+
+	return state.clone(), nil
+}
+
+// Save implements CrawlStateStore.Save.
+func (s *FileCrawlStateStore) Save(ctx context.Context, state *CrawlState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create crawl state directory %s: %w", s.dir, err)
+	}
+
+	// The log is appended to first, so that a crash in between leaves a
+	// completed listing with a stale cursor, which is skipped, rather than a
+	// finished listing without either, which is read again.
+	entry := newCrawlStateEntry(state)
+	if !entry.empty() {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode crawl state: %w", err)
+		}
+		if err := appendLine(s.logPath(state.RunID), line); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(&CrawlState{
+		RunID:     state.RunID,
+		Cursors:   state.Cursors,
+		UpdatedAt: state.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode crawl state: %w", err)
+	}
+	return writeFileAtomic(s.path(state.RunID), data)
+}
+
+// Delete implements CrawlStateStore.Delete.
+func (s *FileCrawlStateStore) Delete(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, path := range []string{s.path(runID), s.logPath(runID)} {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete crawl state for run %s: %w", runID, err)
+		}
+	}
+	return nil
+}
+
+// path returns the state file of a run.
+//
+// Arguments:
+// - runID: The run ID.
+//
+// Returns:
+// - The path of the state file.
+func (s *FileCrawlStateStore) path(runID string) string {
+	return filepath.Join(s.dir, filepath.Base(runID)+".json")
+}
+
+// logPath returns the log of a run.
+//
+// Arguments:
+// - runID: The run ID.
+//
+// Returns:
+// - The path of the log.
+func (s *FileCrawlStateStore) logPath(runID string) string {
+	return filepath.Join(s.dir, filepath.Base(runID)+".log")
+}
+
+// crawlStateEntry is a line of the log of a FileCrawlStateStore, holding the
+// progress added by one save.
+type crawlStateEntry struct {
+	Completed []string               `json:"completed,omitempty"`
+	Emitted   []string               `json:"emitted,omitempty"`
+	Seen      map[string]KnownObject `json:"seen,omitempty"`
+}
+
+// newCrawlStateEntry creates the log entry of a save.
+//
+// Arguments:
+// - state: The state passed to Save.
+//
+// Returns:
+// - The entry.
+func newCrawlStateEntry(state *CrawlState) crawlStateEntry {
+	entry := crawlStateEntry{Seen: state.Seen}
+	for key := range state.Completed {
+		entry.Completed = append(entry.Completed, key)
+	}
+	for key := range state.Emitted {
+		entry.Emitted = append(entry.Emitted, key)
+	}
+	return entry
+}
+
+// empty reports whether the entry adds nothing.
+//
+// Returns:
+// - True if there is nothing to append.
+func (e crawlStateEntry) empty() bool {
+	return len(e.Completed) == 0 && len(e.Emitted) == 0 && len(e.Seen) == 0
+}
+
+// addTo adds the progress of the entry to a state.
+//
+// Arguments:
+// - state: The state being loaded.
+func (e crawlStateEntry) addTo(state *CrawlState) {
+	for _, key := range e.Completed {
+		state.Completed[key] = true
+		delete(state.Cursors, key)
+	}
+	for _, key := range e.Emitted {
+		state.Emitted[key] = true
+	}
+	for id, object := range e.Seen {
+		state.Seen[id] = object
+	}
+}
+
+// newCrawlState creates an empty crawl state.
+//
+// Arguments:
+// - runID: The run ID the state belongs to.
+//
+// Returns:
+// - A crawl state without any progress.
+func newCrawlState(runID string) *CrawlState {
+	return &CrawlState{
+		RunID:     runID,
+		Cursors:   make(map[string]string),
+		Completed: make(map[string]bool),
+		Emitted:   make(map[string]bool),
+		Seen:      make(map[string]KnownObject),
+	}
+}
+
+// clone returns a deep copy of the crawl state.
+//
+// Returns:
+// - The copied crawl state.
+func (s *CrawlState) clone() *CrawlState {
+	clone := newCrawlState(s.RunID)
+	clone.UpdatedAt = s.UpdatedAt
+	for key, cursor := range s.Cursors {
+		clone.Cursors[key] = cursor
+	}
+	for key, done := range s.Completed {
+		clone.Completed[key] = done
+	}
+	for id, emitted := range s.Emitted {
+		clone.Emitted[id] = emitted
+	}
+	for id, object := range s.Seen {
+		clone.Seen[id] = object
+	}
+	return clone
+}
+
+// add adds the completed listings and the emitted and seen objects of another
+// state.
+//
+// Arguments:
+// - other: The state to add.
+func (s *CrawlState) add(other *CrawlState) {
+	for key := range other.Completed {
+		s.Completed[key] = true
+	}
+	for key := range other.Emitted {
+		s.Emitted[key] = true
+	}
+	for id, object := range other.Seen {
+		s.Seen[id] = object
+	}
+}
+
+// crawlProgress tracks and periodically persists the crawl state of a read.
+//
+// A nil *crawlProgress means the read is not resumable; every method is then
+// a no-op.
+type crawlProgress struct {
+	store    CrawlStateStore
+	interval time.Duration
+
+	// flushMu serializes flushes, so that saves reach the store in order.
+	flushMu sync.Mutex
+
+	mu    sync.Mutex
+	state *CrawlState
+	// pending holds the listings completed and the objects emitted and seen
+	// since the last flush.
+	pending   *CrawlState
+	lastFlush time.Time
+}
+
+// cursor returns the cursor a listing resumes from.
+//
+// Arguments:
+// - key: The key of the listing.
+//
+// Returns:
+// - The saved cursor, or nil to start from the first page.
+func (p *crawlProgress) cursor(key string) *string {
+	if p == nil || key == "" {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cursor, ok := p.state.Cursors[key]; ok {
+		return &cursor
+	}
+	return nil
+}
+
+// done reports whether a listing was already read to the end.
+//
+// Arguments:
+// - key: The key of the listing.
+//
+// Returns:
+// - True if the listing must be skipped.
+func (p *crawlProgress) done(key string) bool {
+	if p == nil || key == "" {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.state.Completed[key]
+}
+
+// advance records the cursor of the next page of a listing once the current
+// page has been fully processed.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - key: The key of the listing.
+// - cursor: The cursor of the next page.
+func (p *crawlProgress) advance(ctx context.Context, key string, cursor string) {
+	if p == nil || key == "" {
+		return
+	}
+
+	p.mu.Lock()
+	p.state.Cursors[key] = cursor
+	p.mu.Unlock()
+
+	p.maybeFlush(ctx)
+}
+
+// finish records that a listing was read to the end.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - key: The key of the listing.
+func (p *crawlProgress) finish(ctx context.Context, key string) {
+	if p == nil || key == "" {
+		return
+	}
+
+	p.mu.Lock()
+	delete(p.state.Cursors, key)
+	p.state.Completed[key] = true
+	p.pending.Completed[key] = true
+	p.mu.Unlock()
+
+	p.maybeFlush(ctx)
+}
+
+// emitted reports whether an object was already emitted by this run.
+//
+// Arguments:
+// - key: The type and ID of the object, see itemKey.
+//
+// Returns:
+// - True if the object must not be emitted again.
+func (p *crawlProgress) emitted(key string) bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.state.Emitted[key]
+}
+
+// markEmitted records that an object was emitted by this run.
+//
+// Arguments:
+// - key: The type and ID of the object, see itemKey.
+func (p *crawlProgress) markEmitted(key string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.Emitted[key] = true
+	p.pending.Emitted[key] = true
+}
+
+// markSeen records that a page or database was seen by this run.
+//
+// Arguments:
+// - id: The ID of the object.
+// - object: What is known about the object.
+func (p *crawlProgress) markSeen(id string, object KnownObject) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.Seen[id] = object
+	p.pending.Seen[id] = object
+}
+
+// seenObjects returns the objects seen by previous attempts of this run.
+//
+// Returns:
+// - A copy of the seen objects by ID.
+func (p *crawlProgress) seenObjects() map[string]KnownObject {
+	seen := make(map[string]KnownObject)
+	if p == nil {
+		return seen
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, object := range p.state.Seen {
+		seen[id] = object
+	}
+	return seen
+}
+
+// cursors returns the cursors of the listings that are not finished.
+//
+// Returns:
+// - A copy of the cursors by listing key, or nil if the read is not
+// resumable.
+func (p *crawlProgress) cursors() map[string]string {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cursors := make(map[string]string, len(p.state.Cursors))
+	for key, cursor := range p.state.Cursors {
+		cursors[key] = cursor
+	}
+	return cursors
+}
+
+// maybeFlush saves the state if the flush interval has elapsed.
+//
+// Arguments:
+// - ctx: The context for the request.
+func (p *crawlProgress) maybeFlush(ctx context.Context) {
+	p.mu.Lock()
+	due := time.Since(p.lastFlush) >= p.interval
+	p.mu.Unlock()
+
+	if due {
+		if err := p.flush(ctx); err != nil {
+			multilog.Error("notion.crawlProgress", "failed to save crawl state", map[string]interface{}{
+				"run_id": p.state.RunID,
+				"error":  err.Error(),
+			})
+		}
+	}
+}
+
+// flush saves the cursors and the progress made since the last flush.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - An error if the state could not be saved.
+func (p *crawlProgress) flush(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	p.state.UpdatedAt = time.Now()
+	update := p.pending
+	update.UpdatedAt = p.state.UpdatedAt
+	for key, cursor := range p.state.Cursors {
+		update.Cursors[key] = cursor
+	}
+	p.pending = newCrawlState(p.state.RunID)
+	p.lastFlush = update.UpdatedAt
+	p.mu.Unlock()
+
+	// The state is flushed when a read ends, which is usually because its
+	// context was cancelled, so saving must not depend on that context.
+	if err := p.store.Save(context.WithoutCancel(ctx), update); err != nil {
+		// The progress is saved with the next flush instead.
+		p.mu.Lock()
+		p.pending.add(update)
+		p.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// complete removes the persisted state once the run has finished.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - An error if the state could not be removed.
+func (p *crawlProgress) complete(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	return p.store.Delete(ctx, p.state.RunID)
+}
+
+// loadCrawlProgress loads the crawl state of the run set on the context.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - The crawl progress, or nil if the read is not resumable.
+// - An error if the state could not be loaded.
+func (ns *Plugin) loadCrawlProgress(ctx context.Context) (*crawlProgress, error) {
+	runID := RunIDFromContext(ctx)
+	if ns.config.CrawlState == nil || runID == "" {
+		return nil, nil
+	}
+
+	state, err := ns.config.CrawlState.Load(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load crawl state for run %s: %w", runID, err)
+	}
+
+	interval := ns.config.CrawlStateFlushInterval
+	if interval <= 0 {
+		interval = defaultCrawlStateFlushInterval
+	}
+
+	return &crawlProgress{
+		store:     ns.config.CrawlState,
+		interval:  interval,
+		state:     state,
+		pending:   newCrawlState(runID),
+		lastFlush: time.Now(),
+	}, nil
+}
+
+// defaultCrawlStateFlushInterval is how often crawl state is saved while a
+// read is running when NotionSourceConfig.CrawlStateFlushInterval is unset.
+const defaultCrawlStateFlushInterval = 5 * time.Second
+
+// appendLine appends a line to a file, creating it if needed.
+//
+// Arguments:
+// - path: The path of the file.
+// - line: The line, without the trailing newline.
+//
+// Returns:
+// - An error if the line could not be appended.
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	// The line is written at once so that a crash leaves at most the last
+	// line incomplete.
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes a file through a temporary file that is renamed into
+// place, so that a crash mid-write never leaves a truncated file behind.
+//
+// Arguments:
+// - path: The path of the file.
+// - data: The contents to write.
+//
+// Returns:
+// - An error if the file could not be written.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestFileCrawlStateStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileCrawlStateStore(filepath.Join(t.TempDir(), "state"))
+
+	state, err := store.Load(ctx, "run")
+	if err != nil {
+		t.Fatalf("failed to load a missing state: %v", err)
+	}
+	if state.RunID != "run" || len(state.Cursors) != 0 || len(state.Emitted) != 0 {
+		t.Fatalf("state = %+v, want an empty state for the run", state)
+	}
+
+	state.Cursors["search:page"] = "cursor"
+	state.Completed["children:a"] = true
+	state.Emitted["page:a"] = true
+	if err := store.Save(ctx, state); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	// Later saves only hold the progress made since.
+	update := newCrawlState("run")
+	update.Cursors["search:database"] = "cursor"
+	update.Completed["search:page"] = true
+	update.Emitted["page:b"] = true
+	update.Seen["b"] = KnownObject{Type: common.ObjectTypePage}
+	if err := store.Save(ctx, update); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	loaded, err := store.Load(ctx, "run")
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if len(loaded.Cursors) != 1 || loaded.Cursors["search:database"] != "cursor" {
+		t.Errorf("cursors = %v, want the cursors of the last save", loaded.Cursors)
+	}
+	if !loaded.Completed["children:a"] || !loaded.Completed["search:page"] {
+		t.Errorf("completed = %v, want the listings of both saves", loaded.Completed)
+	}
+	if !loaded.Emitted["page:a"] || !loaded.Emitted["page:b"] || loaded.Seen["b"].Type != common.ObjectTypePage {
+		t.Errorf("loaded = %+v, want the objects of both saves", loaded)
+	}
+
+	if err := store.Delete(ctx, "run"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if err := store.Delete(ctx, "run"); err != nil {
+		t.Errorf("deleting a missing state failed: %v", err)
+	}
+	if loaded, err = store.Load(ctx, "run"); err != nil || len(loaded.Emitted) != 0 {
+		t.Errorf("state = %+v, %v after delete, want an empty state", loaded, err)
+	}
+}
+
+func TestFileCrawlStateStoreDropsIncompleteLines(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileCrawlStateStore(t.TempDir())
+
+	state := newCrawlState("run")
+	state.Emitted["page:a"] = true
+	if err := store.Save(ctx, state); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	// A crash while appending leaves the last line incomplete.
+	f, err := os.OpenFile(store.logPath("run"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"emitted":["page:`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	loaded, err := store.Load(ctx, "run")
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if len(loaded.Emitted) != 1 || !loaded.Emitted["page:a"] {
+		t.Errorf("emitted = %v, want the objects of the complete lines", loaded.Emitted)
+	}
+}
+
+// recordingCrawlStateStore is a crawl state store that records the states
+// saved.
+type recordingCrawlStateStore struct {
+	*MemoryCrawlStateStore
+	saved []*CrawlState
+}
+
+func (s *recordingCrawlStateStore) Save(ctx context.Context, state *CrawlState) error {
+	s.saved = append(s.saved, state.clone())
+	return s.MemoryCrawlStateStore.Save(ctx, state)
+}
+
+func TestCrawlProgressFlushesOnlyNewProgress(t *testing.T) {
+	ctx := context.Background()
+	store := &recordingCrawlStateStore{MemoryCrawlStateStore: NewMemoryCrawlStateStore()}
+	progress := &crawlProgress{store: store, interval: time.Hour, state: newCrawlState("run"), pending: newCrawlState("run"), lastFlush: time.Now()}
+
+	progress.markEmitted("page:a")
+	progress.advance(ctx, "search:page", "cursor")
+	if err := progress.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	progress.markEmitted("page:b")
+	if err := progress.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.saved) != 2 {
+		t.Fatalf("saved %d times, want 2", len(store.saved))
+	}
+	if last := store.saved[1]; len(last.Emitted) != 1 || !last.Emitted["page:b"] || last.Cursors["search:page"] != "cursor" {
+		t.Errorf("second save = %+v, want the cursors and only the page emitted since the first", last)
+	}
+
+	state, err := store.Load(ctx, "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Emitted) != 2 {
+		t.Errorf("emitted = %v, want both pages", state.Emitted)
+	}
+}
+
+func TestCrawlProgressKeepsProgressAfterFailedFlushes(t *testing.T) {
+	ctx := context.Background()
+	failing := failingCrawlStateStore{NewMemoryCrawlStateStore()}
+	progress := &crawlProgress{store: failing, interval: time.Hour, state: newCrawlState("run"), pending: newCrawlState("run")}
+
+	progress.markEmitted("page:a")
+	if err := progress.flush(ctx); err == nil {
+		t.Fatal("the flush succeeded")
+	}
+
+	store := NewMemoryCrawlStateStore()
+	progress.store = store
+	if err := progress.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state, err := store.Load(ctx, "run"); err != nil || !state.Emitted["page:a"] {
+		t.Errorf("state = %+v, %v, want the page emitted before the failed flush", state, err)
+	}
+}
+
+func TestCrawlProgressKeysEmittedByType(t *testing.T) {
+	progress := &crawlProgress{store: NewMemoryCrawlStateStore(), state: newCrawlState("run"), pending: newCrawlState("run")}
+
+	page := &engine.DataItemContainer[any]{ID: "a", Type: common.ObjectTypePage}
+	block := &engine.DataItemContainer[any]{ID: "a", Type: common.ObjectTypeBlock}
+
+	progress.markEmitted(itemKey(page))
+	if !progress.emitted(itemKey(page)) {
+		t.Error("the page was not recorded as emitted")
+	}
+	if progress.emitted(itemKey(block)) {
+		t.Error("a child_page block sharing the ID of an emitted page was reported emitted")
+	}
+
+	var none *crawlProgress
+	none.markEmitted(itemKey(page))
+	if none.emitted(itemKey(page)) || none.cursor("search:page") != nil || none.done("search:page") {
+		t.Error("reads that are not resumable must not track progress")
+	}
+}
+
+func TestResumedReadEmitsTheRest(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	var want []string
+	for i := 0; i < 5; i++ {
+		page := workspace.AddPage(newWorkspacePage(fmt.Sprintf("Page %d", i)))
+		want = append(want, string(page.ID))
+	}
+
+	store := NewMemoryCrawlStateStore()
+	config := testConfig()
+	config.PageSize = 2
+	// Without workers the results are unbuffered, so the read stops right
+	// where the consumer did.
+	config.MaxConcurrent = 0
+	config.CrawlState = store
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	ctx, cancel := context.WithCancel(WithRunID(context.Background(), "run"))
+	defer cancel()
+	results, err := ns.Read(ctx, &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}})
+	if err != nil {
+		t.Fatalf("failed to start read: %v", err)
+	}
+	var first []string
+	for item := range results {
+		first = append(first, item.ID)
+		if len(first) == 2 {
+			cancel()
+		}
+	}
+
+	summary := ns.RunSummary("run")
+	if summary == nil || summary.Completed {
+		t.Fatalf("summary = %+v, want an interrupted run", summary)
+	}
+	state, err := store.Load(context.Background(), "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range first {
+		if !state.Emitted[string(common.ObjectTypePage)+":"+id] {
+			t.Errorf("page %s emitted by the interrupted read is not in the crawl state", id)
+		}
+	}
+
+	second := itemIDs(readAll(t, WithRunID(context.Background(), "run"), ns, common.ObjectTypePage), common.ObjectTypePage)
+
+	if got := fmt.Sprint(append(first, second...)); got != fmt.Sprint(want) {
+		t.Errorf("pages = %s, want each page once: %v", got, want)
+	}
+	if summary := ns.RunSummary("run"); !summary.Completed {
+		t.Errorf("summary = %+v, want the resumed run completed", summary)
+	}
+	if state, err := store.Load(context.Background(), "run"); err != nil || len(state.Emitted) != 0 {
+		t.Errorf("state = %+v, %v, want the state of the completed run removed", state, err)
+	}
+}
+
+func TestResumableReadEmitsChildPageBlocks(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	parent := workspace.AddPage(newWorkspacePage("Parent"))
+	child := newWorkspacePage("Child")
+	child.Parent = &types.Parent{Type: types.ParentTypePage, PageID: &parent.ID}
+	workspace.AddPage(child)
+	workspace.AddBlocks(string(parent.ID), &types.Block{
+		ID:        types.BlockID(child.ID),
+		Type:      types.BlockTypeChildPage,
+		ChildPage: &types.ChildPageBlock{Title: "Child"},
+	})
+
+	config := testConfig()
+	config.CrawlState = NewMemoryCrawlStateStore()
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, WithRunID(context.Background(), "run"), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+	if got := itemIDs(items, common.ObjectTypePage); len(got) != 2 {
+		t.Errorf("pages = %v, want the parent and the child", got)
+	}
+	if got := itemIDs(items, common.ObjectTypeBlock); len(got) != 1 || got[0] != string(child.ID) {
+		t.Errorf("blocks = %v, want the child_page block %s", got, child.ID)
+	}
+}
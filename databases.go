@@ -0,0 +1,119 @@
+package notion
+
+import (
+	"context"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// queryDatabase emits every row of a database as a page, applying the filter
+// and sorts configured for the database in NotionSourceConfig.DatabaseQueries.
+//
+// Incremental reads additionally filter rows to those edited since the
+// database's own checkpoint. Root-scoped reads visit unchanged rows too, without
+// emitting them, since pages below a row may have changed.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - databaseID: The ID of the database to query.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled while querying, true otherwise.
+func (ns *Plugin) queryDatabase(ctx context.Context, databaseID types.DatabaseID, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	ctx, span := ns.otel.startStage(ctx, "query_database_rows", attrDatabaseID.String(string(databaseID)))
+	defer endSpan(ctx, span)
+
+	query := ns.config.databaseQuery(databaseID)
+	key := databaseCheckpointKey(string(databaseID))
+	if mark, ok := scope.marks.mark(key); ok && scope.roots == nil {
+		query.Filter = editedSince(query.Filter, mark)
+	}
+
+	fetch := func(cursor *string) (*listPage[types.Page], error) {
+		page, err := ns.queryDatabaseRows(ctx, databaseID, query, cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &listPage[types.Page]{results: page.Results, nextCursor: page.NextCursor, hasMore: page.HasMore}, nil
+	}
+
+	return paginate(ctx, scope, "query:"+string(databaseID), fetch, func(row *types.Page) bool {
+		changed := scope.marks.changed(key, row.LastEditedTime)
+		if !changed && scope.roots == nil {
+			return true
+		}
+		return ns.processPage(ctx, row, changed, scope, results)
+	})
+}
+
+// editedSince narrows a row filter to rows edited at or after a time.
+//
+// Arguments:
+// - filter: The configured filter, or nil.
+// - since: The time rows must have been edited at or after.
+//
+// Returns:
+// - The combined filter.
+func editedSince(filter *types.QueryFilter, since time.Time) *types.QueryFilter {
+	after := since.Format(time.RFC3339)
+	edited := &types.QueryFilter{
+		Timestamp: &types.TimestampFilter{
+			LastEditedTime: &types.DateFilter{OnOrAfter: &after},
+		},
+	}
+
+	if filter == nil {
+		return edited
+	}
+	return &types.QueryFilter{And: []*types.QueryFilter{filter, edited}}
+}
+
+// pageDatabaseID returns the ID of the database a page is a row of.
+//
+// Arguments:
+// - page: The page to check.
+//
+// Returns:
+// - The database ID, or an empty string if the page is not a database row.
+func pageDatabaseID(page *types.Page) string {
+	if !page.IsInDatabase() || page.Parent.DatabaseID == nil {
+		return ""
+	}
+	return string(*page.Parent.DatabaseID)
+}
+
+// queryDatabaseRows fetches a single page of rows from a database.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - databaseID: The ID of the database to query.
+// - query: The filter and sorts to apply.
+// - cursor: The cursor to resume from, or nil for the first page.
+//
+// Returns:
+// - The page of rows.
+// - An error if the request failed.
+func (ns *Plugin) queryDatabaseRows(ctx context.Context, databaseID types.DatabaseID, query DatabaseQuery, cursor *string) (*types.QueryResponse, error) {
+	call := apiCall{
+		operation:  OperationQueryDatabase,
+		objectID:   string(databaseID),
+		objectType: common.ObjectTypeCollection,
+		databaseID: string(databaseID),
+	}
+	return doRequest(ctx, ns, call, func() (*types.QueryResponse, error) {
+		return callAPI[types.QueryResponse](ctx, ns.client.Registry, &types.DatabaseQueryRequest{
+			DatabaseID: databaseID,
+			Query: types.Query{
+				Filter:      query.Filter,
+				Sorts:       query.Sorts,
+				StartCursor: cursor,
+				PageSize:    &ns.config.PageSize,
+			},
+		})
+	})
+}
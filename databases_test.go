@@ -0,0 +1,72 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestDatabaseQuerySendsFilterAndSorts(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	database := workspace.AddDatabase(newWorkspaceDatabase("Posts"))
+	alpha := workspace.AddPage(newRow(database, "Alpha", true))
+	workspace.AddPage(newRow(database, "Beta", false))
+	gamma := workspace.AddPage(newRow(database, "Gamma", true))
+
+	checked := true
+	config := testConfig()
+	config.DatabaseQueries = map[string]DatabaseQuery{
+		// Keys match in undashed form as well.
+		strings.ReplaceAll(string(database.ID), "-", ""): {
+			Filter: &types.QueryFilter{
+				Property: stringPtr("Published"),
+				Checkbox: &types.CheckboxFilter{Equals: &checked},
+			},
+			Sorts: []*types.QuerySort{
+				{PropertySort: &types.PropertySort{Property: stringPtr("Name"), Direction: "descending"}},
+			},
+		},
+	}
+	ns, server := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypeCollection)
+
+	// The server applies the filter and sorts of the request body.
+	if got, want := fmt.Sprint(itemIDs(items, common.ObjectTypePage)), fmt.Sprint([]string{string(gamma.ID), string(alpha.ID)}); got != want {
+		t.Errorf("rows = %s, want %s", got, want)
+	}
+	if n := countRequests(server, "POST /v1/databases/"+string(database.ID)+"/query"); n != 1 {
+		t.Errorf("the database was queried %d times, want once", n)
+	}
+}
+
+func TestDatabaseQueryReportsRejectedFilters(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	database := workspace.AddDatabase(newWorkspaceDatabase("Posts"))
+	workspace.AddPage(newRow(database, "Alpha", true))
+
+	config := testConfig()
+	config.DatabaseQueries = map[string]DatabaseQuery{
+		string(database.ID): {Filter: &types.QueryFilter{}},
+	}
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypeCollection)
+
+	if rows := itemIDs(items, common.ObjectTypePage); len(rows) != 0 {
+		t.Errorf("rows = %v, want none", rows)
+	}
+	errs := ns.LastErrorSummary().Errors
+	if len(errs) != 1 || errs[0].Operation != OperationQueryDatabase || errs[0].StatusCode != http.StatusBadRequest {
+		t.Fatalf("errors = %v, want the rejected query", errs)
+	}
+	if errs[0].ObjectID != string(database.ID) || errs[0].Code != notiontest.CodeValidationError {
+		t.Errorf("error = %+v, want a validation error for database %s", errs[0], database.ID)
+	}
+}
@@ -0,0 +1,301 @@
+package notion
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// ProcessingStatusDeleted is the processing status of tombstones emitted for
+// objects that were deleted, unshared or archived since the previous read.
+const ProcessingStatusDeleted engine.ProcessingStatus = "deleted"
+
+// Tombstone reasons of objects that are gone since the previous read.
+const (
+	tombstoneDeleted = "deleted"
+)
+
+// KnownObject is what a checkpoint remembers about a page or database seen by
+// a read, so that the next read can emit a tombstone once it is gone.
+type KnownObject struct {
+	// Type is the object type, a page (including database rows) or a
+	// collection.
+	Type common.ObjectType `json:"type"`
+	// ParentID is the ID of the object's parent when it was last seen.
+	ParentID string `json:"parent_id,omitempty"`
+	// DatabaseID is the ID of the database the page is a row of, if any.
+	DatabaseID string `json:"database_id,omitempty"`
+	// Archived is set for objects found archived or trashed and therefore
+	// skipped by the read, these are not carried over into the checkpoint.
+	Archived bool `json:"archived,omitempty"`
+}
+
+// Tombstone is the data of an item emitted for an object that is gone.
+type Tombstone struct {
+	// ID is the ID of the object.
+	ID string `json:"id"`
+	// Type is the type of the object.
+	Type common.ObjectType `json:"type"`
+	// ParentID is the ID of the object's last known parent.
+	ParentID string `json:"parent_id,omitempty"`
+	// DatabaseID is the ID of the database the page was a row of, if any.
+	DatabaseID string `json:"database_id,omitempty"`
+	// Reason is why the object is gone, "deleted" or "archived".
+	Reason string `json:"reason"`
+	// DetectedAt is when the read noticed the object was gone.
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// presence tracks the pages and databases seen by a read that detects
+// deletions, see NotionSourceConfig.DetectDeletions.
+//
+// A nil *presence means deletions are not detected.
+type presence struct {
+	mu       sync.Mutex
+	previous map[string]KnownObject
+	seen     map[string]KnownObject
+	progress *crawlProgress
+}
+
+// newPresence creates the presence tracker for a read.
+//
+// Objects seen by a previous attempt of a resumed run are restored from the
+// crawl state, since the listings that found them are not read again.
+//
+// Arguments:
+// - checkpoint: The checkpoint saved by the previous read.
+// - progress: The crawl progress of the read, or nil.
+//
+// Returns:
+// - A new presence tracker.
+func newPresence(checkpoint *Checkpoint, progress *crawlProgress) *presence {
+	p := &presence{
+		previous: checkpoint.Objects,
+		seen:     progress.seenObjects(),
+		progress: progress,
+	}
+	if p.previous == nil {
+		p.previous = make(map[string]KnownObject)
+	}
+	return p
+}
+
+// observe records that a read saw an object.
+//
+// Arguments:
+// - id: The ID of the object.
+// - object: What to remember about the object.
+func (p *presence) observe(id string, object KnownObject) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.seen[id] = object
+	p.mu.Unlock()
+
+	p.progress.markSeen(id, object)
+}
+
+// gone returns tombstones for the objects the previous read saw that this read
+// did not see or found archived.
+//
+// Arguments:
+// - scope: The stages run for the read, objects of other stages are ignored.
+//
+// Returns:
+// - The tombstones.
+func (p *presence) gone(scope readScope) []*Tombstone {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var tombstones []*Tombstone
+	for id, known := range p.previous {
+		if !scope.tracks(known) {
+			continue
+		}
+
+		reason := tombstoneDeleted
+		if current, ok := p.seen[id]; ok {
+			if !current.Archived {
+				continue
+			}
+			reason = tombstoneArchived
+		}
+
+		tombstones = append(tombstones, &Tombstone{
+			ID:         id,
+			Type:       known.Type,
+			ParentID:   known.ParentID,
+			DatabaseID: known.DatabaseID,
+			Reason:     reason,
+			DetectedAt: now,
+		})
+	}
+	return tombstones
+}
+
+// known returns the objects to remember for the next read.
+//
+// Arguments:
+// - scope: The stages run for the read.
+// - complete: Whether the read saw every object it tracks. Otherwise the
+// objects of the previous read are kept so they can still be diffed later.
+//
+// Returns:
+// - The known live objects by ID.
+func (p *presence) known(scope readScope, complete bool) map[string]KnownObject {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	known := make(map[string]KnownObject, len(p.seen))
+	for id, object := range p.previous {
+		if !complete || !scope.tracks(object) {
+			known[id] = object
+		}
+	}
+	for id, object := range p.seen {
+		if object.Archived {
+			delete(known, id)
+			continue
+		}
+		known[id] = object
+	}
+	return known
+}
+
+// tracks reports whether a read with this scope sees every object of the kind
+// given, so that a missing one can be considered gone.
+//
+// Arguments:
+// - object: The object to check.
+//
+// Returns:
+// - True if the object is tracked.
+func (s readScope) tracks(object KnownObject) bool {
+	switch object.Type {
+	case common.ObjectTypeCollection:
+		return s.databases
+	case common.ObjectTypePage:
+		if object.DatabaseID != "" {
+			// The rows a database query filters out are only seen by the
+			// page search, which observes every row it lists, and by the
+			// sweep of incremental reads. Without pages they are not
+			// tracked, so that they are not mistaken for deleted ones.
+			return s.pages || (s.rows && !s.filtered[object.DatabaseID])
+		}
+		return s.pages
+	}
+	return false
+}
+
+// observePage records a page seen by the read.
+//
+// Arguments:
+// - page: The page.
+// - archived: Whether the page is archived and skipped by the read.
+func (s readScope) observePage(page *types.Page, archived bool) {
+	object := KnownObject{
+		Type:       common.ObjectTypePage,
+		ParentID:   page.GetParentID(),
+		DatabaseID: pageDatabaseID(page),
+		Archived:   archived,
+	}
+	if s.tracks(object) {
+		s.presence.observe(string(page.ID), object)
+	}
+}
+
+// observeDatabase records a database seen by the read.
+//
+// Arguments:
+// - database: The database.
+// - archived: Whether the database is archived and skipped by the read.
+func (s readScope) observeDatabase(database *types.Database, archived bool) {
+	object := KnownObject{
+		Type:     common.ObjectTypeCollection,
+		Archived: archived,
+	}
+	if database.Parent != nil {
+		object.ParentID = database.Parent.GetParentID()
+	}
+	if s.tracks(object) {
+		s.presence.observe(string(database.ID), object)
+	}
+}
+
+// sweep lists every page and database through /search without processing
+// them, so that incremental reads, which stop at the first unchanged object,
+// still see everything that exists.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+func (ns *Plugin) sweep(ctx context.Context, scope readScope) {
+	ctx, span := ns.otel.startStage(ctx, "sweep")
+	defer endSpan(ctx, span)
+
+	for _, object := range []string{"page", "database"} {
+		searchReq := types.SearchRequest{
+			Filter: &types.SearchFilter{
+				Value:    object,
+				Property: "object",
+			},
+			PageSize: &ns.config.PageSize,
+		}
+		if object == "page" {
+			searchReq.Query = ns.config.SearchQuery
+		}
+
+		ns.search(ctx, scope, "sweep:"+object, searchReq, func(result *types.SearchResult) bool {
+			if page := result.Page; page != nil {
+				admit, _ := ns.config.admitArchived(scope.archiveState(page.Archived, page.InTrash))
+				scope.observePage(page, (page.Archived || page.InTrash) && !admit)
+			}
+			if database := result.Database; database != nil {
+				admit, _ := ns.config.admitArchived(scope.archiveState(database.Archived, database.InTrash))
+				scope.observeDatabase(database, (database.Archived || database.InTrash) && !admit)
+			}
+			return true
+		})
+	}
+}
+
+// emitDeletions emits tombstones for the objects that are gone since the
+// previous read.
+//
+// Nothing is emitted if the read had errors, since objects it failed to read
+// would be mistaken for deleted ones.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages run for the read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - Whether the read saw every object it tracks.
+func (ns *Plugin) emitDeletions(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	ctx, span := ns.otel.startStage(ctx, "detect_deletions")
+	defer endSpan(ctx, span)
+
+	if errs := scope.errors.count(); errs > 0 {
+		multilog.Warn("notion.Read", "skipping deletion detection after errors", map[string]interface{}{
+			"run_id": RunIDFromContext(ctx),
+			"errors": errs,
+		})
+		return false
+	}
+
+	for _, tombstone := range scope.presence.gone(scope) {
+		if !ns.emit(ctx, scope, ns.convertTombstoneToDataItem(tombstone), results) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,233 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestReadEmitsTombstonesForDeletedPages(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	config := testConfig()
+	config.DetectDeletions = true
+	config.Checkpoints = store
+
+	before := notiontest.NewWorkspace()
+	kept := before.AddPage(newWorkspacePage("Kept"))
+	deleted := before.AddPage(newWorkspacePage("Deleted"))
+	ns, _ := newTestPlugin(t, before, config)
+	if got := tombstones(readAll(t, context.Background(), ns, common.ObjectTypePage)); len(got) != 0 {
+		t.Fatalf("first read tombstones = %v, want none", got)
+	}
+
+	after := notiontest.NewWorkspace()
+	after.AddPage(kept)
+	ns, _ = newTestPlugin(t, after, config)
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	got := tombstones(items)
+	if len(got) != 1 || got[0].ID != string(deleted.ID) {
+		t.Fatalf("tombstones = %v, want the deleted page", got)
+	}
+	tombstone := got[0]
+	if tombstone.Type != common.ObjectTypePage || tombstone.Reason != tombstoneDeleted || tombstone.ParentID != "" {
+		t.Errorf("tombstone = %+v, want a deleted workspace page", tombstone)
+	}
+	for _, item := range items {
+		if item.ID == tombstone.ID && item.Metadata.ProcessingState.Status != ProcessingStatusDeleted {
+			t.Errorf("status = %q, want %q", item.Metadata.ProcessingState.Status, ProcessingStatusDeleted)
+		}
+	}
+
+	if got := tombstones(readAll(t, context.Background(), ns, common.ObjectTypePage)); len(got) != 0 {
+		t.Errorf("tombstones = %v, want the deleted page forgotten", got)
+	}
+}
+
+func TestReadEmitsTombstonesForArchivedPages(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	live := workspace.AddPage(newWorkspacePage("Live"))
+	archived := workspace.AddPage(newWorkspacePage("Archived"))
+
+	config := testConfig()
+	config.DetectDeletions = true
+	config.Checkpoints = NewMemoryCheckpointStore()
+	// Search leaves out archived pages, so they are read as roots.
+	config.RootPageIDs = []string{string(live.ID), string(archived.ID)}
+	ns, _ := newTestPlugin(t, workspace, config)
+	readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	archived.Archived = true
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	got := tombstones(items)
+	if len(got) != 1 || got[0].ID != string(archived.ID) || got[0].Reason != tombstoneArchived {
+		t.Fatalf("tombstones = %v, want the archived page", got)
+	}
+	if pages := itemIDs(items, common.ObjectTypePage); len(pages) != 2 {
+		t.Errorf("pages = %v, want the live page and the tombstone", pages)
+	}
+}
+
+func TestReadSkipsDeletionsAfterErrors(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	config := testConfig()
+	config.DetectDeletions = true
+	config.Checkpoints = store
+
+	before := notiontest.NewWorkspace()
+	kept := before.AddPage(newWorkspacePage("Kept"))
+	deleted := before.AddPage(newWorkspacePage("Deleted"))
+	ns, _ := newTestPlugin(t, before, config)
+	readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	after := notiontest.NewWorkspace()
+	after.AddPage(kept)
+	ns, server := newTestPlugin(t, after, config)
+	server.Fail(1, http.StatusBadRequest, notiontest.CodeValidationError, "injected")
+	if got := tombstones(readAll(t, context.Background(), ns, common.ObjectTypePage)); len(got) != 0 {
+		t.Fatalf("tombstones = %v, want none after a failed search", got)
+	}
+
+	checkpoint, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := checkpoint.Objects[string(deleted.ID)]; !ok {
+		t.Fatal("the failed read forgot the deleted page")
+	}
+
+	if got := tombstones(readAll(t, context.Background(), ns, common.ObjectTypePage)); len(got) != 1 || got[0].ID != string(deleted.ID) {
+		t.Errorf("tombstones = %v, want the deleted page once the read succeeds", got)
+	}
+}
+
+func TestFilteredRowsAreNotTombstoned(t *testing.T) {
+	tests := []struct {
+		name        string
+		incremental bool
+		types       []common.ObjectType
+	}{
+		{name: "full", types: []common.ObjectType{common.ObjectTypeCollection}},
+		{name: "incremental", incremental: true, types: []common.ObjectType{common.ObjectTypeCollection}},
+		{name: "full with pages", types: []common.ObjectType{common.ObjectTypePage, common.ObjectTypeCollection}},
+		{name: "incremental with pages", incremental: true, types: []common.ObjectType{common.ObjectTypePage, common.ObjectTypeCollection}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := notiontest.NewWorkspace()
+			database := workspace.AddDatabase(newWorkspaceDatabase("Posts"))
+			published := workspace.AddPage(newRow(database, "Published", true))
+			draft := workspace.AddPage(newRow(database, "Draft", false))
+
+			checked := true
+			config := testConfig()
+			config.DetectDeletions = true
+			config.Incremental = tt.incremental
+			config.Checkpoints = NewMemoryCheckpointStore()
+			config.DatabaseQueries = map[string]DatabaseQuery{
+				string(database.ID): {Filter: &types.QueryFilter{
+					Property: stringPtr("Published"),
+					Checkbox: &types.CheckboxFilter{Equals: &checked},
+				}},
+			}
+			ns, _ := newTestPlugin(t, workspace, config)
+
+			items := readAll(t, context.Background(), ns, tt.types...)
+			if rows := itemIDs(items, common.ObjectTypePage); len(rows) != 1 || rows[0] != string(published.ID) {
+				t.Fatalf("rows = %v, want the published row", rows)
+			}
+
+			// Unpublishing a row filters it out, which is not a deletion.
+			unpublished := false
+			row := published.Properties["Published"]
+			row.Checkbox = &unpublished
+			published.Properties["Published"] = row
+
+			items = readAll(t, context.Background(), ns, tt.types...)
+			if got := tombstones(items); len(got) != 0 {
+				t.Errorf("tombstones = %v, want none for filtered rows", got)
+			}
+			for _, id := range itemIDs(items, common.ObjectTypePage) {
+				if id == string(draft.ID) {
+					t.Error("the filtered draft was read")
+				}
+			}
+		})
+	}
+}
+
+// tombstones returns the tombstones among items.
+//
+// Arguments:
+// - items: The items.
+//
+// Returns:
+// - The tombstones in order.
+func tombstones(items []engine.DataItemContainer[any]) []*Tombstone {
+	var found []*Tombstone
+	for _, item := range items {
+		if tombstone, ok := item.Data.(*Tombstone); ok {
+			found = append(found, tombstone)
+		}
+	}
+	return found
+}
+
+// newWorkspaceDatabase returns a top-level database with a title and a
+// Published checkbox.
+//
+// Arguments:
+// - title: The title.
+//
+// Returns:
+// - The database, to be added to a workspace.
+func newWorkspaceDatabase(title string) *types.Database {
+	workspace := true
+	database := types.NewDatabase([]types.RichText{*types.NewTextRichText(title, nil)}, map[string]types.DatabaseProperty{
+		"Name":      {Type: types.PropertyTypeTitle, Title: &types.TitleConfig{}},
+		"Published": {Type: types.PropertyTypeCheckbox, Checkbox: &types.CheckboxConfig{}},
+	})
+	database.Parent = &types.Parent{Type: types.ParentTypeWorkspace, Workspace: &workspace}
+	return database
+}
+
+// newRow returns a row of a database created with newWorkspaceDatabase.
+//
+// Arguments:
+// - database: The database.
+// - name: The title of the row.
+// - published: The value of the Published checkbox.
+//
+// Returns:
+// - The row, to be added to a workspace.
+func newRow(database *types.Database, name string, published bool) *types.Page {
+	return &types.Page{
+		Parent: &types.Parent{Type: types.ParentTypeDatabase, DatabaseID: &database.ID},
+		PropertyAccessor: types.PropertyAccessor[types.Property]{
+			PropertyContainer: &types.PropertyContainer[types.Property]{
+				Properties: map[string]types.Property{
+					"Name":      *types.NewTitleProperty(name),
+					"Published": *types.NewCheckboxProperty(published),
+				},
+			},
+		},
+	}
+}
+
+// stringPtr returns a pointer to a string.
+//
+// Arguments:
+// - s: The string.
+//
+// Returns:
+// - The pointer.
+func stringPtr(s string) *string {
+	return &s
+}
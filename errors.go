@@ -0,0 +1,373 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cmskitdev/client"
+	"github.com/cmskitdev/common"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// Operations reported in ReadError.Operation.
+const (
+	OperationSearch            = "search"
+	OperationGetPage           = "get_page"
+	OperationGetDatabase       = "get_database"
+	OperationGetBlock          = "get_block"
+	OperationListBlockChildren = "list_block_children"
+	OperationListComments      = "list_comments"
+	OperationListUsers         = "list_users"
+	OperationQueryDatabase     = "query_database"
+	OperationSaveCheckpoint    = "save_checkpoint"
+	OperationSaveCrawlState    = "save_crawl_state"
+	OperationCreatePage        = "create_page"
+	OperationUpdatePage        = "update_page"
+	OperationAppendBlocks      = "append_blocks"
+	OperationWrite             = "write"
+	OperationDownloadAsset     = "download_asset"
+	OperationValidate          = "validate"
+	OperationWebhook           = "webhook"
+)
+
+// ReadError describes a failure that occurred while reading or writing, with enough
+// context to alert on it or to retry the affected object.
+type ReadError struct {
+	// ObjectID is the ID of the object the operation was performed on, empty
+	// for workspace-wide operations such as search.
+	ObjectID string `json:"object_id,omitempty"`
+	// ObjectType is the type of the object the operation was performed on.
+	ObjectType common.ObjectType `json:"object_type,omitempty"`
+	// Operation is the operation that failed, one of the Operation constants.
+	Operation string `json:"operation"`
+	// StatusCode is the HTTP status of the response, zero if there was none.
+	StatusCode int `json:"status_code,omitempty"`
+	// Code is the Notion error code, such as "object_not_found".
+	Code string `json:"code,omitempty"`
+	// Retryable reports whether retrying the operation later may succeed.
+	Retryable bool `json:"retryable"`
+	// Message is the message of the underlying error.
+	Message string `json:"message"`
+	// OccurredAt is when the error occurred.
+	OccurredAt time.Time `json:"occurred_at"`
+
+	err error
+}
+
+// Error implements error.
+func (e *ReadError) Error() string {
+	if e.ObjectID == "" {
+		return fmt.Sprintf("notion %s failed: %s", e.Operation, e.Message)
+	}
+	return fmt.Sprintf("notion %s failed for %v %s: %s", e.Operation, e.ObjectType, e.ObjectID, e.Message)
+}
+
+// Unwrap returns the underlying error.
+func (e *ReadError) Unwrap() error {
+	return e.err
+}
+
+// ErrorSummary lists the errors of a single read.
+type ErrorSummary struct {
+	// RunID is the run ID of the read, see WithRunID.
+	RunID string `json:"run_id,omitempty"`
+	// Errors holds the errors in the order they occurred.
+	Errors []*ReadError `json:"errors"`
+	// CompletedAt is when the read ended.
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Retryable returns the errors worth retrying.
+//
+// Returns:
+// - The retryable errors in the order they occurred.
+func (s *ErrorSummary) Retryable() []*ReadError {
+	var retryable []*ReadError
+	for _, err := range s.Errors {
+		if err.Retryable {
+			retryable = append(retryable, err)
+		}
+	}
+	return retryable
+}
+
+// ErrorHandler is called for every error as soon as it occurs during a read.
+//
+// Handlers may be called concurrently and must not block for long, since the
+// read waits for them.
+type ErrorHandler func(err *ReadError)
+
+// errorHandlerKey is the context key holding the error handler of a read.
+type errorHandlerKey struct{}
+
+// WithErrorHandler returns a context that makes Read report every error to the
+// given handler.
+//
+// Arguments:
+// - ctx: The parent context.
+// - handler: The handler to call.
+//
+// Returns:
+// - The derived context.
+func WithErrorHandler(ctx context.Context, handler ErrorHandler) context.Context {
+	return context.WithValue(ctx, errorHandlerKey{}, handler)
+}
+
+// errorHandlerFromContext returns the handler set with WithErrorHandler.
+//
+// Arguments:
+// - ctx: The context to read from.
+//
+// Returns:
+// - The handler, or nil if none is set.
+func errorHandlerFromContext(ctx context.Context) ErrorHandler {
+	handler, _ := ctx.Value(errorHandlerKey{}).(ErrorHandler)
+	return handler
+}
+
+// apiCall describes an API request for error reporting.
+type apiCall struct {
+	operation  string
+	objectID   string
+	objectType common.ObjectType
+	databaseID string
+}
+
+// notionError is the error object Notion responds with, see
+// https://developers.notion.com/reference/status-codes.
+type notionError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newReadError describes a failed API request.
+//
+// Arguments:
+// - call: The request that failed.
+// - err: The error returned for the request.
+//
+// Returns:
+// - The read error wrapping err.
+func newReadError(call apiCall, err error) *ReadError {
+	readErr := &ReadError{
+		ObjectID:   call.objectID,
+		ObjectType: call.objectType,
+		Operation:  call.operation,
+		Message:    err.Error(),
+		OccurredAt: time.Now(),
+		err:        err,
+	}
+
+	readErr.StatusCode, readErr.Code = errorStatus(err)
+	if readErr.StatusCode == 0 {
+		readErr.Retryable = transient(err)
+	} else {
+		readErr.Retryable = retryable(readErr.StatusCode, readErr.Code)
+	}
+
+	return readErr
+}
+
+// errorStatus returns the HTTP status and Notion error code of an error
+// returned by the client or by an asset download.
+//
+// The client turns 429, 401 and 403 responses into dedicated errors and keeps
+// the body of other failed responses as the message of a *client.HTTPError,
+// from which the Notion error code is decoded.
+//
+// Arguments:
+// - err: The error returned for a request.
+//
+// Returns:
+// - The HTTP status, zero if no response was received.
+// - The Notion error code, if known.
+func errorStatus(err error) (int, string) {
+	var rateLimitErr *client.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return http.StatusTooManyRequests, "rate_limited"
+	}
+	var authenticationErr *client.AuthenticationError
+	if errors.As(err, &authenticationErr) {
+		return http.StatusUnauthorized, authenticationErr.Code
+	}
+	var authorizationErr *client.AuthorizationError
+	if errors.As(err, &authorizationErr) {
+		return http.StatusForbidden, authorizationErr.Code
+	}
+
+	var assetErr *assetStatusError
+	if errors.As(err, &assetErr) {
+		return assetErr.status, ""
+	}
+
+	var httpErr *client.HTTPError
+	if !errors.As(err, &httpErr) {
+		return 0, ""
+	}
+	code := httpErr.Code
+	if code == "" {
+		var body notionError
+		if json.Unmarshal([]byte(httpErr.Message), &body) == nil {
+			code = body.Code
+		}
+	}
+	return httpErr.StatusCode, code
+}
+
+// transient reports whether a request that failed without a response may
+// succeed when retried.
+//
+// Arguments:
+// - err: The error returned for a request.
+//
+// Returns:
+// - True for network failures, timeouts and an open circuit breaker, false
+// for errors such as malformed requests or responses.
+func transient(err error) bool {
+	var networkErr *client.NetworkError
+	var timeoutErr *client.TimeoutError
+	var breakerErr *client.CircuitBreakerError
+	return errors.As(err, &networkErr) || errors.As(err, &timeoutErr) ||
+		errors.As(err, &breakerErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryable reports whether a request that failed with the given status and
+// Notion error code may succeed when retried.
+//
+// Arguments:
+// - status: The HTTP status.
+// - code: The Notion error code, if any.
+//
+// Returns:
+// - True if the failure is transient.
+func retryable(status int, code string) bool {
+	switch code {
+	case "rate_limited", "conflict_error", "internal_server_error", "service_unavailable", "database_connection_unavailable", "gateway_timeout":
+		return true
+	}
+
+	switch status {
+	case http.StatusConflict, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// errorLog collects the errors of a single read and forwards them to the
+// read's error handler.
+type errorLog struct {
+	mu      sync.Mutex
+	errors  []*ReadError
+	handler ErrorHandler
+}
+
+// newErrorLog creates the error log for a read.
+//
+// Arguments:
+// - ctx: The context of the read, holding the optional error handler.
+//
+// Returns:
+// - A new error log.
+func newErrorLog(ctx context.Context) *errorLog {
+	return &errorLog{handler: errorHandlerFromContext(ctx)}
+}
+
+// report records an error and passes it to the handler.
+//
+// Errors caused by the read being cancelled are not reported.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - err: The error to report, wrapped in a ReadError if it isn't one.
+// - operation: The operation used if err is not a ReadError.
+func (l *errorLog) report(ctx context.Context, err error, operation string) {
+	if l == nil || err == nil || ctx.Err() != nil {
+		return
+	}
+
+	var readErr *ReadError
+	if !errors.As(err, &readErr) {
+		readErr = newReadError(apiCall{operation: operation}, err)
+	}
+
+	l.mu.Lock()
+	l.errors = append(l.errors, readErr)
+	l.mu.Unlock()
+
+	if l.handler != nil {
+		l.handler(readErr)
+	}
+}
+
+// count returns the number of errors reported so far.
+//
+// Returns:
+// - The number of errors.
+func (l *errorLog) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.errors)
+}
+
+// summary returns the summary of the errors reported so far.
+//
+// Arguments:
+// - runID: The run ID of the read.
+//
+// Returns:
+// - The error summary.
+func (l *errorLog) summary(runID string) *ErrorSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return &ErrorSummary{
+		RunID:       runID,
+		Errors:      append([]*ReadError(nil), l.errors...),
+		CompletedAt: time.Now(),
+	}
+}
+
+// finishErrors stores the error summary of a read that ended and logs it.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - log: The error log of the read.
+//
+// Returns:
+// - The error summary.
+func (ns *Plugin) finishErrors(ctx context.Context, log *errorLog) *ErrorSummary {
+	summary := log.summary(RunIDFromContext(ctx))
+
+	ns.mu.Lock()
+	ns.lastErrors = summary
+	ns.mu.Unlock()
+
+	if len(summary.Errors) > 0 {
+		multilog.Warn("notion.Read", "read finished with errors", map[string]interface{}{
+			"run_id":    summary.RunID,
+			"errors":    len(summary.Errors),
+			"retryable": len(summary.Retryable()),
+		})
+	}
+	return summary
+}
+
+// LastErrorSummary returns the errors of the most recently finished read.
+//
+// Returns:
+// - The error summary, or nil if no read has finished yet.
+func (ns *Plugin) LastErrorSummary() *ErrorSummary {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	return ns.lastErrors
+}
@@ -0,0 +1,145 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/client"
+	"github.com/cmskitdev/common"
+)
+
+func TestNewReadErrorClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		code      string
+		retryable bool
+	}{
+		{
+			name:      "rate limited",
+			err:       &client.RateLimitError{RetryAfter: time.Second},
+			status:    http.StatusTooManyRequests,
+			code:      "rate_limited",
+			retryable: true,
+		},
+		{
+			name:   "unauthorized",
+			err:    &client.AuthenticationError{Code: "unauthorized"},
+			status: http.StatusUnauthorized,
+			code:   "unauthorized",
+		},
+		{
+			name:   "restricted",
+			err:    &client.AuthorizationError{Code: "restricted_resource"},
+			status: http.StatusForbidden,
+			code:   "restricted_resource",
+		},
+		{
+			name:   "not found",
+			err:    &client.HTTPError{StatusCode: http.StatusNotFound, Message: `{"object":"error","status":404,"code":"object_not_found","message":"Could not find page."}`},
+			status: http.StatusNotFound,
+			code:   "object_not_found",
+		},
+		{
+			name:      "conflict",
+			err:       &client.HTTPError{StatusCode: http.StatusConflict, Message: `{"object":"error","status":409,"code":"conflict_error","message":"Conflict."}`},
+			status:    http.StatusConflict,
+			code:      "conflict_error",
+			retryable: true,
+		},
+		{
+			name:      "server error without a body",
+			err:       &client.HTTPError{StatusCode: http.StatusBadGateway, Message: "Bad Gateway"},
+			status:    http.StatusBadGateway,
+			retryable: true,
+		},
+		{
+			name:   "wrapped",
+			err:    fmt.Errorf("query: %w", &client.HTTPError{StatusCode: http.StatusBadRequest, Message: `{"code":"validation_error"}`}),
+			status: http.StatusBadRequest,
+			code:   "validation_error",
+		},
+		{
+			name:      "asset download",
+			err:       &assetStatusError{url: "https://example.com/a.png", status: http.StatusServiceUnavailable},
+			status:    http.StatusServiceUnavailable,
+			retryable: true,
+		},
+		{
+			name:      "network",
+			err:       &client.NetworkError{Operation: "POST", Underlying: errors.New("connection reset")},
+			retryable: true,
+		},
+		{
+			name:      "timeout",
+			err:       &client.TimeoutError{Timeout: time.Second},
+			retryable: true,
+		},
+		{
+			name:      "circuit breaker",
+			err:       &client.CircuitBreakerError{State: "open"},
+			retryable: true,
+		},
+		{
+			name:      "deadline",
+			err:       fmt.Errorf("search: %w", context.DeadlineExceeded),
+			retryable: true,
+		},
+		{
+			name: "malformed response",
+			err:  errors.New("failed to decode response"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call := apiCall{operation: OperationGetPage, objectID: "a", objectType: common.ObjectTypePage}
+			readErr := newReadError(call, tt.err)
+
+			if readErr.StatusCode != tt.status || readErr.Code != tt.code || readErr.Retryable != tt.retryable {
+				t.Errorf("read error = %d %q retryable %v, want %d %q retryable %v",
+					readErr.StatusCode, readErr.Code, readErr.Retryable, tt.status, tt.code, tt.retryable)
+			}
+			if readErr.Operation != OperationGetPage || readErr.ObjectID != "a" || readErr.ObjectType != common.ObjectTypePage {
+				t.Errorf("read error = %+v, want the call described", readErr)
+			}
+			if !errors.Is(readErr, tt.err) {
+				t.Error("the read error does not wrap the underlying error")
+			}
+		})
+	}
+}
+
+func TestErrorLogReportsToHandler(t *testing.T) {
+	var handled []*ReadError
+	ctx := WithErrorHandler(context.Background(), func(err *ReadError) {
+		handled = append(handled, err)
+	})
+	log := newErrorLog(ctx)
+
+	readErr := newReadError(apiCall{operation: OperationSearch}, &client.RateLimitError{})
+	log.report(ctx, readErr, "")
+	log.report(ctx, errors.New("disk full"), OperationSaveCheckpoint)
+	log.report(ctx, nil, OperationSearch)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	log.report(cancelled, errors.New("context canceled"), OperationSearch)
+
+	if len(handled) != 2 || handled[0] != readErr || handled[1].Operation != OperationSaveCheckpoint {
+		t.Fatalf("handled = %v, want the read error and the wrapped error", handled)
+	}
+
+	summary := log.summary("run")
+	if summary.RunID != "run" || len(summary.Errors) != 2 {
+		t.Errorf("summary = %+v, want 2 errors for the run", summary)
+	}
+	if retryable := summary.Retryable(); len(retryable) != 1 || retryable[0] != readErr {
+		t.Errorf("retryable = %v, want the rate limit error", retryable)
+	}
+}
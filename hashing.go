@@ -0,0 +1,224 @@
+package notion
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// volatileFields are the fields left out of content hashes because they
+// change without the content changing: the expiry of signed file URLs and the
+// edit stamps Notion bumps when anything below an object is edited.
+var volatileFields = map[string]bool{
+	"expiry_time":      true,
+	"last_edited_time": true,
+	"last_edited_by":   true,
+}
+
+// contentHash returns the hash of the content of an item, see
+// NotionSourceConfig.SkipUnchanged.
+//
+// The item data is encoded to JSON and decoded again, so the hash covers what
+// the item serializes to with object keys sorted, without volatile fields and
+// without the queries of Notion-hosted file URLs, which are signed per
+// request.
+//
+// Arguments:
+// - item: The item.
+//
+// Returns:
+// - The hex-encoded SHA-256 hash, or empty if the item is not hashed.
+// - An error if the item data could not be encoded.
+func contentHash(item *engine.DataItemContainer[any]) (string, error) {
+	switch item.Type {
+	case common.ObjectTypePage, common.ObjectTypeCollection, common.ObjectTypeBlock, common.ObjectTypeComment:
+	default:
+		return "", nil
+	}
+	if _, ok := item.Data.(*Tombstone); ok {
+		return "", nil
+	}
+
+	data, err := json.Marshal(item.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", item.Type, item.ID, err)
+	}
+	var content interface{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return "", fmt.Errorf("failed to decode %s %s: %w", item.Type, item.ID, err)
+	}
+
+	canonical, err := json.Marshal(stripVolatile(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s %s: %w", item.Type, item.ID, err)
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// stripVolatile removes the volatile fields from decoded JSON in place.
+//
+// Arguments:
+// - value: The decoded JSON.
+//
+// Returns:
+// - The value without volatile fields.
+func stripVolatile(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if volatileFields[key] {
+				delete(value, key)
+				continue
+			}
+			value[key] = stripVolatile(field)
+		}
+		// Notion-hosted files are {"type": "file", "file": {"url": ...}}
+		// with a URL signed per request in its query, while the path changes
+		// with the file. External files keep their URL.
+		if file, ok := value["file"].(map[string]interface{}); ok && value["type"] == "file" {
+			if raw, ok := file["url"].(string); ok {
+				if fileURL, err := url.Parse(raw); err == nil {
+					fileURL.RawQuery = ""
+					file["url"] = fileURL.String()
+				}
+			}
+		}
+	case []interface{}:
+		for i, element := range value {
+			value[i] = stripVolatile(element)
+		}
+	}
+	return value
+}
+
+// contentHashes tracks the content hashes of a read that skips unchanged
+// items, see NotionSourceConfig.SkipUnchanged.
+//
+// A nil *contentHashes means unchanged items are emitted.
+type contentHashes struct {
+	mu       sync.Mutex
+	previous map[string]string
+	seen     map[string]string
+}
+
+// newContentHashes creates the content hashes of a read.
+//
+// Arguments:
+// - checkpoint: The checkpoint saved by the previous read.
+//
+// Returns:
+// - The content hashes.
+func newContentHashes(checkpoint *Checkpoint) *contentHashes {
+	h := &contentHashes{
+		previous: checkpoint.Hashes,
+		seen:     make(map[string]string),
+	}
+	if h.previous == nil {
+		h.previous = make(map[string]string)
+	}
+	return h
+}
+
+// unchanged reports whether an item has the hash it had when last emitted.
+//
+// Arguments:
+// - key: The type and ID of the item, see itemKey.
+// - hash: The content hash of the item.
+//
+// Returns:
+// - True if the item must be skipped.
+func (h *contentHashes) unchanged(key string, hash string) bool {
+	if h == nil || hash == "" {
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	last, ok := h.seen[key]
+	if !ok {
+		last, ok = h.previous[key]
+	}
+	if !ok || last != hash {
+		return false
+	}
+	h.seen[key] = hash
+	return true
+}
+
+// record records the hash of an item once it has been emitted, so that an
+// item whose send was cancelled is emitted again by the next read.
+//
+// Arguments:
+// - key: The type and ID of the item, see itemKey.
+// - hash: The content hash of the item.
+func (h *contentHashes) record(key string, hash string) {
+	if h == nil || hash == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seen[key] = hash
+}
+
+// known returns the hashes to remember for the next read.
+//
+// Arguments:
+// - full: Whether the read saw every object. Otherwise the hashes of the
+// objects it did not see are kept.
+//
+// Returns:
+// - The hashes by type and ID.
+func (h *contentHashes) known(full bool) map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	known := make(map[string]string, len(h.seen))
+	if !full {
+		for id, hash := range h.previous {
+			known[id] = hash
+		}
+	}
+	for id, hash := range h.seen {
+		known[id] = hash
+	}
+	return known
+}
+
+// hashContent stores the content hash of an item in its "content_hash"
+// metadata property.
+//
+// Arguments:
+// - item: The item.
+//
+// Returns:
+// - The hash, or empty if the item is not hashed.
+func hashContent(item *engine.DataItemContainer[any]) string {
+	hash, err := contentHash(item)
+	if err != nil {
+		multilog.Warn("notion.emit", "failed to hash item", map[string]interface{}{
+			"id":    item.ID,
+			"error": err.Error(),
+		})
+		return ""
+	}
+	if hash == "" {
+		return ""
+	}
+
+	if item.Metadata.Properties == nil {
+		item.Metadata.Properties = make(map[string]interface{})
+	}
+	item.Metadata.Properties["content_hash"] = hash
+	return hash
+}
@@ -0,0 +1,184 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestStripVolatile(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "edit stamps",
+			value: `{"id":"a","last_edited_time":"2025-01-01T00:00:00Z","last_edited_by":{"id":"u"},"created_time":"2025-01-01T00:00:00Z"}`,
+			want:  `{"id":"a","created_time":"2025-01-01T00:00:00Z"}`,
+		},
+		{
+			name:  "nested hosted file",
+			value: `{"image":{"type":"file","file":{"url":"https://files/a?sig=1","expiry_time":"2025-01-01T01:00:00Z"},"caption":[]}}`,
+			want:  `{"image":{"type":"file","file":{"url":"https://files/a"},"caption":[]}}`,
+		},
+		{
+			name:  "files in a list",
+			value: `[{"type":"file","name":"a.pdf","file":{"url":"https://files/a?sig=1"}}]`,
+			want:  `[{"type":"file","name":"a.pdf","file":{"url":"https://files/a"}}]`,
+		},
+		{
+			name:  "external file",
+			value: `{"type":"external","external":{"url":"https://example.com/a.png"}}`,
+			want:  `{"type":"external","external":{"url":"https://example.com/a.png"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value, want interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got := stripVolatile(value); !reflect.DeepEqual(got, want) {
+				t.Errorf("stripVolatile(%s) = %v, want %v", tt.value, got, want)
+			}
+		})
+	}
+}
+
+func TestContentHashIgnoresEditStamps(t *testing.T) {
+	page := newWorkspacePage("Page")
+	page.ID = types.PageID(missingPageID)
+	page.LastEditedTime = time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	item := &engine.DataItemContainer[any]{ID: missingPageID, Type: common.ObjectTypePage, Data: page}
+
+	hash, err := contentHash(item)
+	if err != nil || hash == "" {
+		t.Fatalf("contentHash = %q, %v, want a hash", hash, err)
+	}
+	for i := 0; i < 10; i++ {
+		// Maps are encoded in a random order, which must not change the hash.
+		if again, _ := contentHash(item); again != hash {
+			t.Fatalf("hash = %s, want %s every time", again, hash)
+		}
+	}
+
+	page.LastEditedTime = page.LastEditedTime.Add(time.Hour)
+	if touched, _ := contentHash(item); touched != hash {
+		t.Error("the hash changed with the edit time")
+	}
+	page.Properties["title"] = *types.NewTitleProperty("Renamed")
+	if renamed, _ := contentHash(item); renamed == hash {
+		t.Error("the hash did not change with the title")
+	}
+
+	tombstone := &engine.DataItemContainer[any]{ID: missingPageID, Type: common.ObjectTypePage, Data: &Tombstone{ID: missingPageID}}
+	if hash, err := contentHash(tombstone); hash != "" || err != nil {
+		t.Errorf("tombstone hash = %q, %v, want none", hash, err)
+	}
+}
+
+func TestContentHashFollowsHostedFiles(t *testing.T) {
+	image := &types.Block{
+		ID:   types.BlockID(missingPageID),
+		Type: types.BlockTypeImage,
+		Image: &types.FileBlock{
+			Type: types.FileBlockTypeFile,
+			File: &types.File{Type: "file", File: &types.NotionHostedFileType{
+				URL:        "https://files.example.com/a/image.png?X-Amz-Signature=1",
+				ExpiryTime: "2025-01-01T01:00:00Z",
+			}},
+		},
+	}
+	item := &engine.DataItemContainer[any]{ID: missingPageID, Type: common.ObjectTypeBlock, Data: image}
+	hash, _ := contentHash(item)
+
+	// Every read signs the file again.
+	image.Image.File.File = &types.NotionHostedFileType{
+		URL:        "https://files.example.com/a/image.png?X-Amz-Signature=2",
+		ExpiryTime: "2025-01-01T02:00:00Z",
+	}
+	if resigned, _ := contentHash(item); resigned != hash {
+		t.Error("the hash changed with the signature")
+	}
+
+	// Replacing the file changes its path.
+	image.Image.File.File = &types.NotionHostedFileType{
+		URL:        "https://files.example.com/b/image.png?X-Amz-Signature=3",
+		ExpiryTime: "2025-01-01T03:00:00Z",
+	}
+	if replaced, _ := contentHash(item); replaced == hash {
+		t.Error("the hash did not change with the file")
+	}
+}
+
+func TestReadSkipsUnchangedItems(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	unchanged := workspace.AddPage(newWorkspacePage("Unchanged"))
+	edited := workspace.AddPage(newWorkspacePage("Edited"))
+	block := workspace.AddBlocks(string(edited.ID), newParagraph("text"))[0]
+
+	config := testConfig()
+	config.SkipUnchanged = true
+	config.Checkpoints = NewMemoryCheckpointStore()
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	first := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+	assertEmittedOnce(t, "page", itemIDs(first, common.ObjectTypePage), []string{string(unchanged.ID), string(edited.ID)})
+	for _, item := range first {
+		if item.Metadata.Properties["content_hash"] == nil {
+			t.Errorf("%s %s has no content_hash", item.Type, item.ID)
+		}
+	}
+
+	// Editing a block bumps the edit time of its page, but not its content.
+	edited.LastEditedTime = edited.LastEditedTime.Add(time.Minute)
+	if items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock); len(items) != 0 {
+		t.Errorf("emitted %d items, want none unchanged", len(items))
+	}
+
+	edited.Properties["title"] = *types.NewTitleProperty("Renamed")
+	second := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+	assertEmittedOnce(t, "page", itemIDs(second, common.ObjectTypePage), []string{string(edited.ID)})
+	if blocks := itemIDs(second, common.ObjectTypeBlock); len(blocks) != 0 {
+		t.Errorf("blocks = %v, want the unchanged block %s skipped", blocks, block.ID)
+	}
+}
+
+func TestEmitRecordsHashesOfSentItems(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+	scope := readScope{errors: newErrorLog(context.Background()), hashes: newContentHashes(&Checkpoint{})}
+	page := newWorkspacePage("Page")
+	page.ID = types.PageID(missingPageID)
+	item := func() *engine.DataItemContainer[any] {
+		return &engine.DataItemContainer[any]{ID: missingPageID, Type: common.ObjectTypePage, Data: page}
+	}
+	key := itemKey(item())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ns.emit(ctx, scope, item(), make(chan engine.DataItemContainer[any])) {
+		t.Fatal("emit succeeded although its send was cancelled")
+	}
+	if _, ok := scope.hashes.known(true)[key]; ok {
+		t.Fatal("the hash of an item that was not sent was recorded")
+	}
+
+	if !ns.emit(context.Background(), scope, item(), make(chan engine.DataItemContainer[any], 1)) {
+		t.Fatal("emit failed")
+	}
+	if _, ok := scope.hashes.known(true)[key]; !ok {
+		t.Error("the hash of the sent item was not recorded")
+	}
+}
@@ -0,0 +1,204 @@
+package notion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/client"
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+// testToken is the integration token the test servers require.
+const testToken = "secret_test"
+
+// newTestClient creates a client for a notiontest server.
+//
+// The client retries once with a short backoff, and its circuit breaker only
+// opens once every request failed, so injected failures reach the plugin
+// quickly without tripping it.
+//
+// Arguments:
+// - t: The test.
+// - server: The server.
+//
+// Returns:
+// - The client.
+func newTestClient(t *testing.T, server *notiontest.Server) *client.Client {
+	t.Helper()
+
+	config := client.DefaultConfig()
+	config.APIKey = testToken
+	config.BaseURL = server.APIURL()
+	config.MaxRetries = 1
+	config.BaseBackoff = time.Millisecond
+	config.MaxBackoff = 10 * time.Millisecond
+	config.CircuitBreakerThreshold = 1
+
+	c, err := client.NewClient(config)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return c
+}
+
+// newTestPlugin starts a notiontest server for a workspace and creates a
+// plugin reading from it. Both are closed when the test ends.
+//
+// The rate limit is lifted unless the configuration sets one.
+//
+// Arguments:
+// - t: The test.
+// - workspace: The workspace to serve.
+// - config: The source configuration.
+//
+// Returns:
+// - The plugin.
+// - The server.
+func newTestPlugin(t *testing.T, workspace *notiontest.Workspace, config NotionSourceConfig) (*Plugin, *notiontest.Server) {
+	t.Helper()
+
+	server := notiontest.NewServer(workspace)
+	server.Token = testToken
+	t.Cleanup(server.Close)
+
+	ns := NewNotionSource(newTestClient(t, server), config)
+	t.Cleanup(func() { _ = ns.Close() })
+	return ns, server
+}
+
+// testConfig returns the default configuration without a rate limit.
+//
+// Returns:
+// - The source configuration.
+func testConfig() NotionSourceConfig {
+	config := DefaultNotionSourceConfig()
+	config.RequestsPerSecond = 0
+	return config
+}
+
+// readAll runs a read to completion.
+//
+// Arguments:
+// - t: The test.
+// - ctx: The context of the read.
+// - ns: The plugin.
+// - objTypes: The object types to read.
+//
+// Returns:
+// - The items emitted, in order.
+func readAll(t *testing.T, ctx context.Context, ns *Plugin, objTypes ...common.ObjectType) []engine.DataItemContainer[any] {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	results, err := ns.Read(ctx, &engine.ReadRequest{Types: objTypes})
+	if err != nil {
+		t.Fatalf("failed to start read: %v", err)
+	}
+
+	var items []engine.DataItemContainer[any]
+	for item := range results {
+		items = append(items, item)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("read did not finish: %v", ctx.Err())
+	}
+	return items
+}
+
+// itemIDs returns the IDs of items of a type.
+//
+// Arguments:
+// - items: The items.
+// - objType: The object type.
+//
+// Returns:
+// - The IDs in order.
+func itemIDs(items []engine.DataItemContainer[any], objType common.ObjectType) []string {
+	var ids []string
+	for _, item := range items {
+		if item.Type == objType {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+// assertEmittedOnce checks that items were emitted exactly once each, in any
+// order.
+//
+// Arguments:
+// - t: The test.
+// - kind: The kind of item, for messages.
+// - got: The IDs emitted.
+// - want: The IDs expected.
+func assertEmittedOnce(t *testing.T, kind string, got []string, want []string) {
+	t.Helper()
+
+	counts := make(map[string]int)
+	for _, id := range got {
+		counts[id]++
+	}
+	for _, id := range want {
+		if counts[id] != 1 {
+			t.Errorf("%s %s was emitted %d times, want once", kind, id, counts[id])
+		}
+		delete(counts, id)
+	}
+	for id := range counts {
+		t.Errorf("unexpected %s %s", kind, id)
+	}
+}
+
+// countRequests returns how many requests a server received for an endpoint.
+//
+// Arguments:
+// - server: The server.
+// - request: The request, as "METHOD /path".
+//
+// Returns:
+// - The number of requests.
+func countRequests(server *notiontest.Server, request string) int {
+	n := 0
+	for _, r := range server.Requests() {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+// newWorkspacePage returns a top-level page with a title.
+//
+// Arguments:
+// - title: The title.
+//
+// Returns:
+// - The page, to be added to a workspace.
+func newWorkspacePage(title string) *types.Page {
+	workspace := true
+	return &types.Page{
+		Parent: &types.Parent{Type: types.ParentTypeWorkspace, Workspace: &workspace},
+		PropertyAccessor: types.PropertyAccessor[types.Property]{
+			PropertyContainer: &types.PropertyContainer[types.Property]{
+				Properties: map[string]types.Property{"title": *types.NewTitleProperty(title)},
+			},
+		},
+	}
+}
+
+// newParagraph returns a paragraph block.
+//
+// Arguments:
+// - text: The text of the paragraph.
+//
+// Returns:
+// - The block, to be added to a workspace.
+func newParagraph(text string) *types.Block {
+	return types.NewParagraphBlock([]types.RichText{*types.NewTextRichText(text, nil)})
+}
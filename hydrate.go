@@ -0,0 +1,182 @@
+package notion
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// hydrationPool is a fixed-size pool of workers that fetch the full page for
+// the shallow pages returned by search and process it.
+//
+// Pages are submitted through an unbuffered channel, so a submitted page is
+// always in the hands of a worker and nothing is left queued on shutdown.
+type hydrationPool struct {
+	ns      *Plugin
+	scope   readScope
+	results chan<- engine.DataItemContainer[any]
+	jobs    chan *types.Page
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+// newHydrationPool starts NotionSourceConfig.MaxConcurrent hydration workers.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - The running pool, which must be closed by the caller.
+func (ns *Plugin) newHydrationPool(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any]) *hydrationPool {
+	pool := &hydrationPool{
+		ns:      ns,
+		scope:   scope,
+		results: results,
+		jobs:    make(chan *types.Page),
+	}
+
+	size := max(1, ns.config.MaxConcurrent)
+	pool.workers.Add(size)
+	for i := 0; i < size; i++ {
+		go pool.work(ctx)
+	}
+
+	return pool
+}
+
+// work processes submitted pages until the pool is closed.
+//
+// Arguments:
+// - ctx: The context for the request.
+func (p *hydrationPool) work(ctx context.Context) {
+	defer p.workers.Done()
+
+	for page := range p.jobs {
+		if ctx.Err() == nil {
+			p.ns.hydratePage(ctx, page, p.scope, p.results)
+		}
+		p.pending.Done()
+	}
+}
+
+// submit hands a page to the next idle worker.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - page: The shallow page to hydrate.
+//
+// Returns:
+// - False if the context was cancelled before a worker took the page.
+func (p *hydrationPool) submit(ctx context.Context, page *types.Page) bool {
+	p.pending.Add(1)
+	select {
+	case p.jobs <- page:
+		return true
+	case <-ctx.Done():
+		p.pending.Done()
+		return false
+	}
+}
+
+// wait blocks until every submitted page has been processed.
+func (p *hydrationPool) wait() {
+	p.pending.Wait()
+}
+
+// close stops the pool and blocks until all workers have exited.
+func (p *hydrationPool) close() {
+	close(p.jobs)
+	p.workers.Wait()
+}
+
+// hydratePages searches pages and hydrates them with a worker pool.
+//
+// Each page of search results is processed concurrently, but the search only
+// moves on to the next page once all of it has been processed so that the
+// saved crawl cursor never skips pages still being hydrated.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - searchReq: The search request, its StartCursor is managed by the search.
+// - accept: Returns the page to process for a search result, or nil to skip
+// it, and false to stop the search.
+// - results: The channel to send the results to.
+func (ns *Plugin) hydratePages(ctx context.Context, scope readScope, searchReq types.SearchRequest, accept func(result *types.SearchResult) (*types.Page, bool), results chan<- engine.DataItemContainer[any]) {
+	pool := ns.newHydrationPool(ctx, scope, results)
+	defer pool.close()
+
+	paginateBatches(ctx, scope, "search:page", ns.searchFetch(ctx, searchReq), func(batch []types.SearchResult) bool {
+		defer pool.wait()
+
+		for i := range batch {
+			page, more := accept(&batch[i])
+			if page != nil && !pool.submit(ctx, page) {
+				return false
+			}
+			if !more {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// hydratePage fetches the full version of a shallow page and processes it
+// like any other page, so its blocks and comments are read by the regular
+// stages.
+//
+// If the page cannot be fetched the error is reported and the shallow page is
+// processed instead, so a failed hydration never loses the page.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - page: The shallow page from the search results.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) hydratePage(ctx context.Context, page *types.Page, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	full, err := ns.getPage(ctx, page.ID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		scope.errors.report(ctx, err, OperationGetPage)
+		full = page
+	}
+
+	return ns.processPage(ctx, full, scope.pages, scope, results)
+}
+
+// getPage fetches a single page without its blocks, which the block walk
+// lists itself, honoring MaxDepth and the crawl state.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - pageID: The ID of the page.
+//
+// Returns:
+// - The page.
+// - An error if the request failed.
+func (ns *Plugin) getPage(ctx context.Context, pageID types.PageID) (*types.Page, error) {
+	call := apiCall{
+		operation:  OperationGetPage,
+		objectID:   string(pageID),
+		objectType: common.ObjectTypePage,
+	}
+	return doRequest(ctx, ns, call, func() (*types.Page, error) {
+		result := ns.client.Pages().GetSimple(ctx, pageID)
+		if result.IsError() {
+			return nil, result.Error
+		}
+		return &result.Data, nil
+	})
+}
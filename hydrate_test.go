@@ -0,0 +1,101 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestHydratedReadEmitsEveryPageOnce(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	var pages, blocks []string
+	for i := 0; i < 5; i++ {
+		page := workspace.AddPage(newWorkspacePage(fmt.Sprintf("Page %d", i)))
+		pages = append(pages, string(page.ID))
+		for _, block := range workspace.AddBlocks(string(page.ID), newParagraph("one"), newParagraph("two")) {
+			blocks = append(blocks, string(block.ID))
+		}
+	}
+
+	config := testConfig()
+	config.HydratePages = true
+	config.PageSize = 2
+	config.MaxConcurrent = 3
+	ns, server := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+	assertEmittedOnce(t, "pages", itemIDs(items, common.ObjectTypePage), pages)
+	assertEmittedOnce(t, "blocks", itemIDs(items, common.ObjectTypeBlock), blocks)
+	for _, id := range pages {
+		if n := countRequests(server, "GET /v1/pages/"+id); n != 1 {
+			t.Errorf("page %s was fetched %d times, want once", id, n)
+		}
+	}
+	if errs := ns.LastErrorSummary().Errors; len(errs) != 0 {
+		t.Errorf("errors = %v, want none", errs)
+	}
+}
+
+func TestHydratedReadWalksBlocksAndComments(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	page := workspace.AddPage(newWorkspacePage("Page"))
+	top := workspace.AddBlocks(string(page.ID), newParagraph("top"))[0]
+	workspace.AddBlocks(string(top.ID), newParagraph("nested"))
+	comment := workspace.AddComments(types.NewComment(
+		&types.CommentParent{Type: types.CommentParentTypeBlock, BlockID: &top.ID},
+		[]types.RichText{*types.NewTextRichText("on the top block", nil)},
+	))[0]
+
+	config := testConfig()
+	config.HydratePages = true
+	config.MaxDepth = 1
+	ns, server := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock, common.ObjectTypeComment)
+
+	assertEmittedOnce(t, "blocks", itemIDs(items, common.ObjectTypeBlock), []string{string(top.ID)})
+	assertEmittedOnce(t, "comments", itemIDs(items, common.ObjectTypeComment), []string{string(comment.ID)})
+	// Only the page is listed, the nested block is below MaxDepth.
+	if n := countRequests(server, "GET /v1/blocks/"+string(page.ID)+"/children"); n != 1 {
+		t.Errorf("the page's children were listed %d times, want once", n)
+	}
+	if n := countRequests(server, "GET /v1/blocks/"+string(top.ID)+"/children"); n != 0 {
+		t.Errorf("the top block's children were listed %d times, want never", n)
+	}
+	// Every request goes through the plugin.
+	if requests, sent := ns.GetMetrics().RequestsMade, int64(len(server.Requests())); requests != sent {
+		t.Errorf("requests made = %d, want the %d sent", requests, sent)
+	}
+}
+
+func TestHydrationFailureProcessesTheShallowPage(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+
+	ctx := context.Background()
+	scope := readScope{pages: true, errors: newErrorLog(ctx)}
+	results := make(chan engine.DataItemContainer[any], 1)
+	page := newWorkspacePage("Shallow")
+	page.ID = missingPageID
+
+	if !ns.hydratePage(ctx, page, scope, results) {
+		t.Fatal("hydratePage stopped the read")
+	}
+
+	select {
+	case item := <-results:
+		if item.ID != missingPageID || item.Data.(*types.Page) != page {
+			t.Errorf("item = %s %T, want the shallow page", item.ID, item.Data)
+		}
+	default:
+		t.Fatal("the shallow page was not emitted")
+	}
+	if errs := scope.errors.summary("").Errors; len(errs) != 1 || errs[0].Operation != OperationGetPage || errs[0].ObjectID != missingPageID {
+		t.Errorf("errors = %v, want the failed hydration", errs)
+	}
+}
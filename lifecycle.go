@@ -0,0 +1,156 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// ErrClosed is returned by Read, and by the writes of a Destination, once the
+// plugin has been closed.
+var ErrClosed = errors.New("plugin is closed")
+
+// defaultCloseTimeout is how long Close waits for reads to exit when
+// NotionSourceConfig.CloseTimeout is unset.
+const defaultCloseTimeout = 30 * time.Second
+
+// activeRuns tracks the reads and writes in flight so that Close can cancel
+// them and wait for them to exit.
+type activeRuns struct {
+	mu      sync.Mutex
+	closed  bool
+	next    uint64
+	cancels map[uint64]context.CancelFunc
+	errs    []error
+	wg      sync.WaitGroup
+}
+
+// start registers a read or write.
+//
+// Arguments:
+// - ctx: The context of the read or write.
+//
+// Returns:
+// - A context cancelled when the plugin is closed.
+// - A function to call once the read or write has exited.
+// - ErrClosed if the plugin is closed.
+func (a *activeRuns) start(ctx context.Context) (context.Context, func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return nil, nil, ErrClosed
+	}
+	if a.cancels == nil {
+		a.cancels = make(map[uint64]context.CancelFunc)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	id := a.next
+	a.next++
+	a.cancels[id] = cancel
+	a.wg.Add(1)
+
+	return ctx, func() {
+		cancel()
+
+		a.mu.Lock()
+		delete(a.cancels, id)
+		a.mu.Unlock()
+
+		a.wg.Done()
+	}, nil
+}
+
+// fail records an error that occurred while a read was shutting down, such as
+// crawl state that could not be flushed.
+//
+// Arguments:
+// - err: The error.
+func (a *activeRuns) fail(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.errs = append(a.errs, err)
+}
+
+// shutdown stops new reads and writes from starting and cancels the running
+// ones.
+//
+// Returns:
+// - The number of reads and writes cancelled.
+func (a *activeRuns) shutdown() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closed = true
+	for _, cancel := range a.cancels {
+		cancel()
+	}
+	return len(a.cancels)
+}
+
+// running returns the number of reads and writes that have not exited.
+//
+// Returns:
+// - The number of running reads and writes.
+func (a *activeRuns) running() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.cancels)
+}
+
+// Close implements DataSource.Close.
+//
+// Close cancels every running read and write and waits up to
+// NotionSourceConfig.CloseTimeout for them to exit. Interrupted reads flush
+// their crawl state before exiting, so they can be resumed with the same run
+// ID. Reads and writes started after Close fail with ErrClosed.
+//
+// Returns:
+// - An error if reads did not exit in time or failed to flush their state.
+func (ns *Plugin) Close() error {
+	defer ns.updateEndTime()
+
+	cancelled := ns.active.shutdown()
+
+	timeout := ns.config.CloseTimeout
+	if timeout <= 0 {
+		timeout = defaultCloseTimeout
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ns.active.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var errs []error
+	select {
+	case <-done:
+	case <-timer.C:
+		errs = append(errs, fmt.Errorf("%d reads and writes did not exit within %s", ns.active.running(), timeout))
+	}
+
+	ns.active.mu.Lock()
+	errs = append(errs, ns.active.errs...)
+	ns.active.errs = nil
+	ns.active.mu.Unlock()
+
+	if err := errors.Join(errs...); err != nil {
+		multilog.Error("notion.Close", "failed to close cleanly", map[string]interface{}{
+			"cancelled": cancelled,
+			"error":     err.Error(),
+		})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+)
+
+// failingCrawlStateStore is a crawl state store that cannot save.
+type failingCrawlStateStore struct {
+	*MemoryCrawlStateStore
+}
+
+func (s failingCrawlStateStore) Save(ctx context.Context, state *CrawlState) error {
+	return errors.New("disk full")
+}
+
+// startBlockedRead starts a read of a workspace of pages whose results are
+// unbuffered, and receives its first item, so that the read is blocked
+// sending the second one.
+//
+// Arguments:
+// - t: The test.
+// - config: The source configuration.
+//
+// Returns:
+// - The plugin.
+// - The results of the read.
+func startBlockedRead(t *testing.T, config NotionSourceConfig) (*Plugin, <-chan engine.DataItemContainer[any]) {
+	t.Helper()
+
+	workspace := notiontest.NewWorkspace()
+	for i := 0; i < 3; i++ {
+		workspace.AddPage(newWorkspacePage(fmt.Sprintf("Page %d", i)))
+	}
+	config.MaxConcurrent = 0
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	results, err := ns.Read(WithRunID(context.Background(), "run"), &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}})
+	if err != nil {
+		t.Fatalf("failed to start read: %v", err)
+	}
+	if _, ok := <-results; !ok {
+		t.Fatal("the read ended before emitting")
+	}
+	return ns, results
+}
+
+func TestCloseCancelsRunningReads(t *testing.T) {
+	store := NewMemoryCrawlStateStore()
+	config := testConfig()
+	config.CrawlState = store
+	ns, results := startBlockedRead(t, config)
+
+	if err := ns.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	select {
+	case _, ok := <-results:
+		if ok {
+			t.Error("the read emitted after Close returned")
+		}
+	default:
+		t.Error("the results were not closed once Close returned")
+	}
+	if summary := ns.RunSummary("run"); summary == nil || summary.Completed {
+		t.Errorf("summary = %+v, want an interrupted run", summary)
+	}
+
+	// The interrupted read flushed its crawl state so it can be resumed.
+	state, err := store.Load(context.Background(), "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Emitted) != 1 {
+		t.Errorf("emitted = %v, want the page read before Close", state.Emitted)
+	}
+
+	if _, err := ns.Read(context.Background(), &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}}); !errors.Is(err, ErrClosed) {
+		t.Errorf("read after Close = %v, want ErrClosed", err)
+	}
+	if err := ns.Close(); err != nil {
+		t.Errorf("closing again = %v, want nil", err)
+	}
+}
+
+func TestCloseReportsCrawlStateFailures(t *testing.T) {
+	config := testConfig()
+	config.CrawlState = failingCrawlStateStore{NewMemoryCrawlStateStore()}
+	ns, _ := startBlockedRead(t, config)
+
+	if err := ns.Close(); err == nil {
+		t.Error("Close succeeded although the crawl state could not be flushed")
+	}
+}
+
+func TestCloseTimesOut(t *testing.T) {
+	config := testConfig()
+	config.CloseTimeout = 10 * time.Millisecond
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), config)
+
+	// A run that ignores cancellation.
+	_, done, err := ns.active.start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+
+	started := time.Now()
+	if err := ns.Close(); err == nil {
+		t.Error("Close succeeded although a run did not exit")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Close took %s, want the close timeout", elapsed)
+	}
+}
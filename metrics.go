@@ -1,81 +1,208 @@
 package notion
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
 
-// GetMetrics returns current source metrics (copy without mutex)
-func (ns *Plugin) GetMetrics() NotionSourceMetrics {
-	ns.metrics.mu.RLock()
-	defer ns.metrics.mu.RUnlock()
-
-	// Return a copy without the mutex to avoid lock copying
-	return NotionSourceMetrics{
-		ObjectsRead:       ns.metrics.ObjectsRead,
-		PagesRead:         ns.metrics.PagesRead,
-		BlocksRead:        ns.metrics.BlocksRead,
-		CommentsRead:      ns.metrics.CommentsRead,
-		DatabasesRead:     ns.metrics.DatabasesRead,
-		UsersRead:         ns.metrics.UsersRead,
-		RequestsMade:      ns.metrics.RequestsMade,
-		ErrorsEncountered: ns.metrics.ErrorsEncountered,
-		TotalDuration:     ns.metrics.TotalDuration,
-		StartTime:         ns.metrics.StartTime,
-		EndTime:           ns.metrics.EndTime,
+	"github.com/cmskitdev/common"
+)
+
+// NotionSourceMetrics tracks counters for the objects and requests handled by
+// the Notion source. The same counters are exported through OpenTelemetry,
+// see NotionSourceConfig.MeterProvider.
+//
+// The plugin keeps lifetime aggregates, see Plugin.GetMetrics, and every read
+// gets metrics of its own, see RunSummary.
+type NotionSourceMetrics struct {
+	// Runs is the number of reads the metrics cover.
+	Runs              int64
+	ObjectsRead       int64
+	PagesRead         int64
+	BlocksRead        int64
+	CommentsRead      int64
+	DatabasesRead     int64
+	UsersRead         int64
+	PagesCreated      int64
+	PagesUpdated      int64
+	BlocksAppended    int64
+	RequestsMade      int64
+	ErrorsEncountered int64
+	ThrottledRequests int64
+	ThrottleTime      time.Duration
+	RateLimitWait     time.Duration
+	// TotalDuration is how long the read took, or for lifetime aggregates the
+	// total time spent in finished reads.
+	TotalDuration time.Duration
+	// StartTime is when the read started, or for lifetime aggregates when the
+	// plugin was created.
+	StartTime time.Time
+	// EndTime is when the read ended, or for lifetime aggregates when the last
+	// read ended or the plugin was closed.
+	EndTime *time.Time
+}
+
+// metricsRecorder guards a set of metrics updated concurrently.
+//
+// A nil *metricsRecorder ignores updates.
+type metricsRecorder struct {
+	mu      sync.RWMutex
+	metrics NotionSourceMetrics
+}
+
+// newMetricsRecorder creates a recorder starting now.
+//
+// Returns:
+// - A new recorder.
+func newMetricsRecorder() *metricsRecorder {
+	return &metricsRecorder{
+		metrics: NotionSourceMetrics{
+			StartTime: time.Now(),
+		},
+	}
+}
+
+// update applies a change to the metrics.
+//
+// Arguments:
+// - change: The change to apply.
+func (r *metricsRecorder) update(change func(m *NotionSourceMetrics)) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(&r.metrics)
+}
+
+// snapshot returns a copy of the metrics.
+//
+// Returns:
+// - The metrics.
+func (r *metricsRecorder) snapshot() NotionSourceMetrics {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	metrics := r.metrics
+	if metrics.EndTime != nil {
+		end := *metrics.EndTime
+		metrics.EndTime = &end
 	}
+	return metrics
+}
+
+// runMetricsKey is the context key holding the metrics of a read.
+type runMetricsKey struct{}
+
+// withRunMetrics returns a context recording metrics into a read's recorder
+// in addition to the plugin's lifetime aggregates.
+//
+// Arguments:
+// - ctx: The parent context.
+// - run: The recorder of the read.
+//
+// Returns:
+// - The derived context.
+func withRunMetrics(ctx context.Context, run *metricsRecorder) context.Context {
+	return context.WithValue(ctx, runMetricsKey{}, run)
+}
+
+// runMetricsFromContext returns the recorder set with withRunMetrics.
+//
+// Arguments:
+// - ctx: The context to read from.
+//
+// Returns:
+// - The recorder, or nil outside of a read.
+func runMetricsFromContext(ctx context.Context) *metricsRecorder {
+	run, _ := ctx.Value(runMetricsKey{}).(*metricsRecorder)
+	return run
+}
+
+// GetMetrics returns the metrics aggregated over the plugin's lifetime.
+//
+// Returns:
+// - A copy of the lifetime metrics.
+func (ns *Plugin) GetMetrics() NotionSourceMetrics {
+	return ns.metrics.snapshot()
 }
 
 // Helper methods for metrics
 
-func (ns *Plugin) incrementPageCount() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	ns.metrics.PagesRead++
-	ns.metrics.ObjectsRead++
+// updateMetrics applies a change to the lifetime metrics and to the metrics
+// of the read running under ctx, if any.
+func (ns *Plugin) updateMetrics(ctx context.Context, change func(m *NotionSourceMetrics)) {
+	ns.metrics.update(change)
+	runMetricsFromContext(ctx).update(change)
+}
+
+func (ns *Plugin) incrementObjectCount(ctx context.Context, objType common.ObjectType) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		switch objType {
+		case common.ObjectTypePage:
+			m.PagesRead++
+		case common.ObjectTypeBlock:
+			m.BlocksRead++
+		case common.ObjectTypeComment:
+			m.CommentsRead++
+		case common.ObjectTypeCollection:
+			m.DatabasesRead++
+		case common.ObjectTypeUser:
+			m.UsersRead++
+		default:
+			return
+		}
+		m.ObjectsRead++
+	})
+}
+
+func (ns *Plugin) incrementPagesCreated(ctx context.Context) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		m.PagesCreated++
+	})
 }
 
-func (ns *Plugin) incrementBlockCount() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	ns.metrics.BlocksRead++
-	ns.metrics.ObjectsRead++
+func (ns *Plugin) incrementPagesUpdated(ctx context.Context) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		m.PagesUpdated++
+	})
 }
 
-func (ns *Plugin) incrementCommentCount() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	ns.metrics.CommentsRead++
-	ns.metrics.ObjectsRead++
+func (ns *Plugin) incrementBlocksAppended(ctx context.Context, n int) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		m.BlocksAppended += int64(n)
+	})
 }
 
-func (ns *Plugin) incrementDatabaseCount() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	ns.metrics.DatabasesRead++
-	ns.metrics.ObjectsRead++
+func (ns *Plugin) incrementRequestCount(ctx context.Context) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		m.RequestsMade++
+	})
 }
 
-func (ns *Plugin) incrementUserCount() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	ns.metrics.UsersRead++
-	ns.metrics.ObjectsRead++
+func (ns *Plugin) incrementErrorCount(ctx context.Context) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		m.ErrorsEncountered++
+	})
 }
 
-func (ns *Plugin) incrementRequestCount() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	ns.metrics.RequestsMade++
+func (ns *Plugin) recordThrottle(ctx context.Context, delay time.Duration) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		m.ThrottledRequests++
+		m.ThrottleTime += delay
+	})
 }
 
-func (ns *Plugin) incrementErrorCount() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	ns.metrics.ErrorsEncountered++
+func (ns *Plugin) addRateLimitWait(ctx context.Context, waited time.Duration) {
+	ns.updateMetrics(ctx, func(m *NotionSourceMetrics) {
+		m.RateLimitWait += waited
+	})
 }
 
 func (ns *Plugin) updateEndTime() {
-	ns.metrics.mu.Lock()
-	defer ns.metrics.mu.Unlock()
-	now := time.Now()
-	ns.metrics.EndTime = &now
-	ns.metrics.TotalDuration = now.Sub(ns.metrics.StartTime)
+	ns.metrics.update(func(m *NotionSourceMetrics) {
+		now := time.Now()
+		m.EndTime = &now
+	})
 }
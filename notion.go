@@ -18,18 +18,32 @@ import (
 type Plugin struct {
 	client  *client.Client
 	config  NotionSourceConfig
-	metrics *NotionSourceMetrics
+	metrics *metricsRecorder
+	limiter *rateLimiter
+	otel    *telemetry
+	assets  *assetDownloader
+	active  activeRuns
 	mu      sync.RWMutex
+
+	// lastErrors is the error summary of the most recently finished read.
+	lastErrors *ErrorSummary
+	// runs holds the summaries of the most recent reads by run ID, runOrder
+	// their run IDs oldest first and lastRun the most recent one.
+	runs     map[string]*RunSummary
+	runOrder []string
+	lastRun  *RunSummary
 }
 
 // NewNotionSource creates a new Notion data source
 func NewNotionSource(client *client.Client, config NotionSourceConfig) *Plugin {
 	return &Plugin{
-		client: client,
-		config: config,
-		metrics: &NotionSourceMetrics{
-			StartTime: time.Now(),
-		},
+		client:  client,
+		config:  config,
+		metrics: newMetricsRecorder(),
+		limiter: newRateLimiter(config.RequestsPerSecond),
+		otel:    newTelemetry(config),
+		assets:  newAssetDownloader(config.Assets),
+		runs:    make(map[string]*RunSummary),
 	}
 }
 
@@ -43,39 +57,215 @@ func (ns *Plugin) Read(ctx context.Context, req *engine.ReadRequest) (<-chan eng
 	bufferSize := ns.config.PageSize * ns.config.MaxConcurrent * 10
 	results := make(chan engine.DataItemContainer[any], bufferSize)
 
+	scope := ns.newReadScope(req)
+
+	roots, err := newRootCrawl(ns.config)
+	if err != nil {
+		return nil, err
+	}
+	scope.roots = roots
+	scope.relations = newRelationGraph(ns.config)
+	scope.ancestry = newAncestry(ns.config)
+	scope.assets = ns.assets.newFetches()
+
+	checkpoint, err := ns.loadCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ns.config.Incremental {
+		scope.marks = newWatermarks(checkpoint)
+	}
+
+	progress, err := ns.loadCrawlProgress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scope.progress = progress
+
+	// Reads started without a run ID get one, which only resumable reads
+	// need to be given up front.
+	if RunIDFromContext(ctx) == "" {
+		ctx = WithRunID(ctx, newRunID())
+	}
+	run := newMetricsRecorder()
+	ctx = withRunMetrics(ctx, run)
+
+	scope.errors = newErrorLog(ctx)
+	if ns.config.DetectDeletions {
+		scope.presence = newPresence(checkpoint, progress)
+	}
+	if ns.config.SkipUnchanged {
+		scope.hashes = newContentHashes(checkpoint)
+	}
+
+	ctx, done, err := ns.active.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	scope.validation = newValidation(ns.config, cancel)
+
+	started := time.Now()
 	go func() {
-		defer ns.updateEndTime()
+		defer done()
+		defer cancel()
+		ctx, span := ns.otel.startStage(ctx, "read")
+		defer endSpan(ctx, span)
 		defer close(results)
+		defer ns.finishRun(ctx, scope, run)
+		defer func() { ns.finishCrawl(ctx, scope) }()
 
-		var wg sync.WaitGroup
-
-		for _, objType := range req.Types {
-			switch objType {
-			case common.ObjectTypePage:
-				if ns.config.IncludePages {
-					wg.Add(1)
-					go func() {
-						defer wg.Done()
-						ns.searchPages(ctx, results)
-					}()
-				}
-			case common.ObjectTypeCollection:
-				if ns.config.IncludeCollections {
-					wg.Add(1)
-					go func() {
-						defer wg.Done()
-						ns.readDatabases(ctx, results)
-					}()
-				}
+		// Users are listed before anything else so the canonical records win
+		// over the partial references discovered by the other stages.
+		if scope.users != nil && !ns.readUsers(ctx, scope, results) {
+			return
+		}
+
+		if scope.roots != nil {
+			ns.readRoots(ctx, scope, results)
+		} else {
+			ns.readWorkspace(ctx, scope, results)
+
+			if scope.presence != nil && scope.marks != nil {
+				ns.sweep(ctx, scope)
 			}
 		}
 
-		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+
+		failed := scope.errors.count() > 0
+		complete := !failed
+		if scope.presence != nil {
+			complete = ns.emitDeletions(ctx, scope, results)
+		}
+
+		// Only a read that ran to completion may advance the checkpoint,
+		// otherwise objects skipped by a cancelled read would be lost.
+		if checkpoint != nil && ctx.Err() == nil {
+			if err := ns.saveCheckpoint(ctx, checkpoint, scope, complete, !failed); err != nil {
+				ns.incrementErrorCount(ctx)
+				scope.errors.report(ctx, err, OperationSaveCheckpoint)
+				multilog.Error("notion.Read", "failed to save checkpoint", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+
+		if ns.config.Watch {
+			// The initial crawl is done, so its progress is flushed and
+			// changes are read from the poll feed instead.
+			ns.finishCrawl(ctx, scope)
+			scope.progress = nil
+			ns.watch(ctx, started, checkpoint, scope, results)
+		}
 	}()
 
 	return results, nil
 }
 
+// readWorkspace reads the pages and databases of the whole workspace through
+// /search, running the page and database stages in parallel.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+func (ns *Plugin) readWorkspace(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any]) {
+	var wg sync.WaitGroup
+
+	if scope.pages || scope.blocks || scope.comments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ns.searchPages(ctx, scope, results)
+		}()
+	}
+
+	if scope.databases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ns.readDatabases(ctx, scope, results)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// readScope captures which stages a single Read runs, derived from the
+// requested object types and the source configuration.
+type readScope struct {
+	pages     bool
+	databases bool
+	rows      bool
+	blocks    bool
+	comments  bool
+
+	// users is the set of users emitted so far, nil unless users are read.
+	users *userSet
+	// marks tracks the incremental high-water marks, nil unless incremental.
+	marks *watermarks
+	// progress tracks the resumable crawl state, nil unless resumable.
+	progress *crawlProgress
+	// errors collects the errors of the read.
+	errors *errorLog
+	// roots holds the configured roots, nil unless the read is root-scoped.
+	roots *rootCrawl
+	// inArchive is set below an archived or trashed page, database or block.
+	inArchive bool
+	// presence tracks the objects seen, nil unless deletions are detected.
+	presence *presence
+	// relations tracks the relations followed, nil unless they are followed.
+	relations *relationGraph
+	// hashes tracks the content hashes, nil unless unchanged items are
+	// skipped.
+	hashes *contentHashes
+	// validation validates the emitted items, nil unless they are validated.
+	validation *validation
+	// ancestry resolves the ancestors of emitted items, nil unless they are
+	// resolved.
+	ancestry *ancestry
+	// assets holds the files downloaded, nil unless files are downloaded.
+	assets *assetFetches
+	// hop is how many relations away from the crawled pages the current page
+	// is.
+	hop int
+	// filtered holds the databases whose rows are filtered by
+	// NotionSourceConfig.DatabaseQueries.
+	filtered map[string]bool
+}
+
+// newReadScope builds the read scope for a request.
+//
+// Arguments:
+// - req: The read request.
+//
+// Returns:
+// - The stages to run for the request.
+func (ns *Plugin) newReadScope(req *engine.ReadRequest) readScope {
+	scope := readScope{filtered: ns.config.filteredDatabases()}
+	for _, objType := range req.Types {
+		switch objType {
+		case common.ObjectTypePage:
+			scope.pages = ns.config.IncludePages
+		case common.ObjectTypeCollection:
+			scope.databases = ns.config.IncludeCollections
+			scope.rows = ns.config.IncludeCollections && ns.config.IncludeDatabaseRows
+		case common.ObjectTypeBlock:
+			scope.blocks = ns.config.IncludeBlocks
+		case common.ObjectTypeComment:
+			scope.comments = ns.config.IncludeComments
+		case common.ObjectTypeUser:
+			if ns.config.IncludeUsers {
+				scope.users = newUserSet()
+			}
+		}
+	}
+	return scope
+}
+
 // Validate implements DataSource.Validate
 func (ns *Plugin) Validate(req *engine.ReadRequest) error {
 	if ns.client == nil {
@@ -114,29 +304,47 @@ func (ns *Plugin) Config() engine.SourceConfig {
 			"include_blocks":      ns.config.IncludeBlocks,
 			"include_comments":    ns.config.IncludeComments,
 			"include_users":       ns.config.IncludeUsers,
+			"include_rows":        ns.config.IncludeDatabaseRows,
 			"max_depth":           ns.config.MaxDepth,
 			"page_size":           ns.config.PageSize,
 			"requests_per_second": ns.config.RequestsPerSecond,
 			"max_concurrent":      ns.config.MaxConcurrent,
+			"hydrate_pages":       ns.config.HydratePages,
+			"incremental":         ns.config.Incremental,
+			"root_page_ids":       ns.config.RootPageIDs,
+			"root_database_ids":   ns.config.RootDatabaseIDs,
+			"archived":            ns.config.Archived,
+			"detect_deletions":    ns.config.DetectDeletions,
+			"skip_unchanged":      ns.config.SkipUnchanged,
+			"relation_hops":       ns.config.RelationHops,
+			"ancestry":            ns.config.Ancestry,
+			"validation":          ns.config.Validation,
+			"watch":               ns.config.Watch,
+			"watch_interval":      ns.config.WatchInterval.String(),
 		},
 	}
 }
 
-// Close implements DataSource.Close
-func (ns *Plugin) Close() error {
-	ns.updateEndTime()
-	return nil
-}
-
-// searchPages performs paginated search for pages with streaming.
+// searchPages performs paginated search for pages.
+//
+// Pages are emitted when the scope includes pages, the block tree of each page
+// is walked when the scope includes blocks, and comments on every visited page
+// and block are read when the scope includes comments.
+//
+// Incremental reads sort the search by last_edited_time and stop at the first
+// page edited before the checkpoint.
+//
+// With NotionSourceConfig.HydratePages the shallow search results are fetched
+// again in full by a pool of MaxConcurrent workers before being processed.
 //
 // Arguments:
 // - ctx: The context for the request.
+// - scope: The stages to run for the current read.
 // - results: The channel to send the results to.
-//
-// Returns:
-// - The processed pages.
-func (ns *Plugin) searchPages(ctx context.Context, results chan<- engine.DataItemContainer[any]) {
+func (ns *Plugin) searchPages(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any]) {
+	ctx, span := ns.otel.startStage(ctx, "search_pages")
+	defer endSpan(ctx, span)
+
 	searchReq := types.SearchRequest{
 		Query: ns.config.SearchQuery,
 		Filter: &types.SearchFilter{
@@ -145,96 +353,282 @@ func (ns *Plugin) searchPages(ctx context.Context, results chan<- engine.DataIte
 		},
 		PageSize: &ns.config.PageSize,
 	}
+	if scope.marks != nil {
+		searchReq.Sort = lastEditedDescending()
+	}
 
 	multilog.Debug("notion.searchPages", "searchReq", map[string]interface{}{
 		"searchReq": searchReq,
 	})
 
-	ns.incrementRequestCount()
-	// Use streaming query for pagination.
-	searchResults := ns.client.Registry.Search().Stream(ctx, searchReq)
+	accept := func(result *types.SearchResult) (*types.Page, bool) {
+		page := result.Page
+		if page == nil {
+			return nil, true
+		}
 
-	// Process paginated results as they stream in.
-	for result := range searchResults {
-		if result.IsError() {
-			ns.incrementErrorCount()
-			continue
+		if !scope.marks.changed(checkpointKeyPages, page.LastEditedTime) {
+			return nil, false
 		}
 
-		if result.Data.Page != nil {
-			item := ns.convertPageDataToDataItem(result.Data.Page)
-			select {
-			case results <- *item:
-				ns.incrementPageCount()
-			case <-ctx.Done():
-				return
-			}
+		// Rows of databases are read by the database query stage instead so
+		// that per-database filters apply to them. They are still observed,
+		// since the rows a filter leaves out are only ever seen here.
+		if scope.rows && page.IsInDatabase() {
+			admit, _ := ns.config.admitArchived(scope.archiveState(page.Archived, page.InTrash))
+			scope.observePage(page, (page.Archived || page.InTrash) && !admit)
+			return nil, true
 		}
+
+		return page, true
+	}
+
+	if ns.config.HydratePages {
+		ns.hydratePages(ctx, scope, searchReq, accept, results)
+		return
 	}
+
+	ns.search(ctx, scope, "search:page", searchReq, func(result *types.SearchResult) bool {
+		page, more := accept(result)
+		if page == nil {
+			return more
+		}
+		return ns.processPage(ctx, page, scope.pages, scope, results)
+	})
 }
 
-// getPage processes a single page.
+// search pages through /search, resuming from the crawl state when the read
+// is resumable.
 //
 // Arguments:
 // - ctx: The context for the request.
-// - page: The page to process.
-// - results: The channel to send the results to.
+// - scope: The stages to run for the current read.
+// - key: The crawl state key of the search.
+// - searchReq: The search request, its StartCursor is managed by search.
+// - visit: Visits a single result, returning false to stop the search.
+func (ns *Plugin) search(ctx context.Context, scope readScope, key string, searchReq types.SearchRequest, visit func(result *types.SearchResult) bool) {
+	paginate(ctx, scope, key, ns.searchFetch(ctx, searchReq), visit)
+}
+
+// searchFetch returns the fetch function paginating through /search.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - searchReq: The search request, its StartCursor is set for every page.
 //
 // Returns:
-// - The processed page.
-func (ns *Plugin) getPage(ctx context.Context, page *types.Page, results chan<- engine.DataItemContainer[any]) {
-	// Simple concurrency control using a semaphore pattern
-	semaphore := make(chan struct{}, ns.config.MaxConcurrent)
-	semaphore <- struct{}{}
-	defer func() { <-semaphore }()
+// - The fetch function for paginate.
+func (ns *Plugin) searchFetch(ctx context.Context, searchReq types.SearchRequest) func(cursor *string) (*listPage[types.SearchResult], error) {
+	return func(cursor *string) (*listPage[types.SearchResult], error) {
+		searchReq.StartCursor = cursor
+		page, err := ns.searchPage(ctx, searchReq)
+		if err != nil {
+			return nil, err
+		}
+		return &listPage[types.SearchResult]{results: page.Results, nextCursor: page.NextCursor, hasMore: page.HasMore}, nil
+	}
+}
 
-	select {
-	case <-ctx.Done():
-		return
-	default:
+// searchPage fetches a single page of search results.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - searchReq: The search request.
+//
+// Returns:
+// - The page of search results.
+// - An error if the request failed.
+func (ns *Plugin) searchPage(ctx context.Context, searchReq types.SearchRequest) (*types.SearchResponse, error) {
+	call := apiCall{operation: OperationSearch}
+	if searchReq.Filter != nil {
+		call.objectType = searchObjectType(searchReq.Filter.Value)
 	}
+	return doRequest(ctx, ns, call, func() (*types.SearchResponse, error) {
+		return callAPI[types.SearchResponse](ctx, ns.client.Registry, &searchReq)
+	})
+}
 
-	// Get full page details
-	opts := client.GetPageOptions{
-		IncludeBlocks:   ns.config.IncludeBlocks,
-		IncludeChildren: ns.config.MaxDepth > 1,
-		IncludeComments: ns.config.IncludeComments,
+// searchObjectType returns the object type searched for by a search filter.
+//
+// Arguments:
+// - value: The value of the "object" search filter.
+//
+// Returns:
+// - The object type, or an empty type for unknown values.
+func searchObjectType(value string) (objType common.ObjectType) {
+	switch value {
+	case "page":
+		objType = common.ObjectTypePage
+	case "database":
+		objType = common.ObjectTypeCollection
 	}
+	return objType
+}
 
-	ns.incrementRequestCount()
-	result := ns.client.Pages().Get(ctx, page.ID, opts)
+// emit sends an item to the results channel and counts it.
+//
+// Items already emitted by a previous attempt of a resumed run are skipped.
+// The files the item references are downloaded first when assets are
+// configured, see NotionSourceConfig.Assets.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - item: The item to emit.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) emit(ctx context.Context, scope readScope, item *engine.DataItemContainer[any], results chan<- engine.DataItemContainer[any]) bool {
+	key := itemKey(item)
+	if scope.progress.emitted(key) {
+		return true
+	}
 
-	if result.IsError() {
-		ns.incrementErrorCount()
-		return
+	if valid, proceed := scope.validation.check(ctx, scope, item); !valid {
+		return proceed
+	}
+
+	hash := hashContent(item)
+	if scope.hashes.unchanged(key, hash) {
+		return true
 	}
 
-	// Convert and send immediately
-	item := ns.convertPageToDataItem(&result.Data)
+	ns.attachAncestors(ctx, scope, item)
+	ns.assets.localize(ctx, scope, item)
 
 	select {
 	case results <- *item:
-		ns.incrementPageCount()
 	case <-ctx.Done():
+		return false
+	}
+
+	scope.progress.markEmitted(key)
+	scope.hashes.record(key, hash)
+	ns.incrementObjectCount(ctx, item.Type)
+	ns.otel.recordObject(ctx, item)
+	return true
+}
+
+// itemKey returns the key identifying an emitted item. Items are keyed by
+// type and ID, since child_page blocks share the ID of their page.
+//
+// Arguments:
+// - item: The item.
+//
+// Returns:
+// - The key.
+func itemKey(item *engine.DataItemContainer[any]) string {
+	return string(item.Type) + ":" + item.ID
+}
+
+// finishCrawl persists the crawl state when a read ends: a completed run's
+// state is removed, an interrupted run's state is saved for resuming.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages run for the read.
+func (ns *Plugin) finishCrawl(ctx context.Context, scope readScope) {
+	if scope.progress == nil {
 		return
 	}
 
-	// Process blocks if included
-	if ns.config.IncludeBlocks && len(result.Data.Blocks) > 0 {
-		for _, block := range result.Data.Blocks {
-			blockItem := ns.convertBlockToDataItem(block, string(page.ID))
-			select {
-			case results <- *blockItem:
-				ns.incrementBlockCount()
-			case <-ctx.Done():
-				return
-			}
+	var err error
+	if ctx.Err() == nil {
+		err = scope.progress.complete(ctx)
+	} else {
+		err = scope.progress.flush(ctx)
+	}
+
+	if err != nil {
+		ns.incrementErrorCount(ctx)
+		scope.errors.report(ctx, err, OperationSaveCrawlState)
+		multilog.Error("notion.Read", "failed to persist crawl state", map[string]interface{}{
+			"run_id": RunIDFromContext(ctx),
+			"error":  err.Error(),
+		})
+
+		// Interrupted reads are not reported once cancelled, so Close is
+		// told instead.
+		if ctx.Err() != nil {
+			ns.active.fail(fmt.Errorf("failed to flush crawl state for run %s: %w", RunIDFromContext(ctx), err))
 		}
 	}
 }
 
-// readDatabases reads databases from the workspace
-func (ns *Plugin) readDatabases(ctx context.Context, results chan<- engine.DataItemContainer[any]) {
+// processPage emits a page followed by the users, comments and blocks that
+// belong to it according to the scope.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - page: The page to process.
+// - emit: Whether the page itself is emitted.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) processPage(ctx context.Context, page *types.Page, emit bool, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	if !scope.relations.visit(string(page.ID)) {
+		return true
+	}
+	scope.ancestry.learnPage(page)
+
+	state := scope.archiveState(page.Archived, page.InTrash)
+	admit, descend := ns.config.admitArchived(state)
+	scope.observePage(page, state != "" && !admit)
+	scope.inArchive = state != ""
+
+	var edges []RelationEdge
+	if admit || descend {
+		edges = ns.pageRelations(ctx, scope, page)
+	}
+
+	if emit && admit {
+		item := markTombstone(ns.convertPageDataToDataItem(page), state)
+		if len(edges) > 0 {
+			item.Metadata.Properties["relations"] = edges
+		}
+		if !ns.emit(ctx, scope, item, results) {
+			return false
+		}
+	}
+
+	if !descend {
+		return true
+	}
+
+	if !ns.expandRelations(ctx, edges, scope, results) {
+		return false
+	}
+
+	if !ns.discoverUsers(ctx, scope, results, pageUserRefs(page)...) {
+		return false
+	}
+
+	if scope.comments && !ns.readComments(ctx, string(page.ID), string(page.ID), scope, results) {
+		return false
+	}
+
+	// Root-scoped reads walk the blocks even when they aren't emitted, since
+	// that is where child pages and databases are found.
+	if !scope.blocks && scope.roots == nil {
+		return true
+	}
+
+	return ns.walkBlocks(ctx, string(page.ID), string(page.ID), 1, scope, results)
+}
+
+// readDatabases reads databases from the workspace.
+//
+// Incremental reads only emit databases edited since the checkpoint. Unless
+// rows are queried, the search stops at the first unchanged database; rows
+// need every database visited because editing a row does not touch its
+// database.
+func (ns *Plugin) readDatabases(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any]) {
+	ctx, span := ns.otel.startStage(ctx, "read_databases")
+	defer endSpan(ctx, span)
+
 	// Search for databases
 	searchReq := types.SearchRequest{
 		Filter: &types.SearchFilter{
@@ -243,26 +637,67 @@ func (ns *Plugin) readDatabases(ctx context.Context, results chan<- engine.DataI
 		},
 		PageSize: &ns.config.PageSize,
 	}
+	if scope.marks != nil {
+		searchReq.Sort = lastEditedDescending()
+	}
 
-	ns.incrementRequestCount()
-	searchResults := ns.client.Registry.Search().Stream(ctx, searchReq)
+	ns.search(ctx, scope, "search:database", searchReq, func(result *types.SearchResult) bool {
+		database := result.Database
+		if database == nil {
+			return true
+		}
 
-	for result := range searchResults {
-		if result.IsError() {
-			ns.incrementErrorCount()
-			continue
+		changed := scope.marks.changed(checkpointKeyDatabases, database.LastEditedTime)
+		if !changed && !scope.rows {
+			return false
 		}
 
-		if result.Data.Database != nil {
-			item := ns.convertDatabaseToDataItem(result.Data.Database)
-			select {
-			case results <- *item:
-				ns.incrementDatabaseCount()
-			case <-ctx.Done():
-				return
-			}
+		return ns.processDatabase(ctx, database, changed, scope, results)
+	})
+}
+
+// processDatabase emits a database and, when rows are in scope, its rows and
+// everything below them.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - database: The database to process.
+// - emit: Whether the database itself is emitted.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) processDatabase(ctx context.Context, database *types.Database, emit bool, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	state := scope.archiveState(database.Archived, database.InTrash)
+	admit, descend := ns.config.admitArchived(state)
+	scope.observeDatabase(database, state != "" && !admit)
+	scope.inArchive = state != ""
+	scope.relations.learn(database)
+	scope.ancestry.learnDatabase(database)
+
+	if emit && admit && scope.databases {
+		if !ns.emit(ctx, scope, markTombstone(ns.convertDatabaseToDataItem(database), state), results) {
+			return false
+		}
+
+		if !ns.discoverUsers(ctx, scope, results, database.CreatedBy, database.LastEditedBy) {
+			return false
 		}
 	}
+
+	return !descend || !scope.rows || ns.queryDatabase(ctx, database.ID, scope, results)
+}
+
+// lastEditedDescending returns the search sort used by incremental reads.
+//
+// Returns:
+// - A sort on last_edited_time, newest first.
+func lastEditedDescending() *types.SearchSort {
+	return &types.SearchSort{
+		Timestamp: "last_edited_time",
+		Direction: "descending",
+	}
 }
 
 // Data conversion methods
@@ -294,26 +729,29 @@ func (ns *Plugin) convertPageDataToDataItem(page *types.Page) *engine.DataItemCo
 				StartedAt: now,
 			},
 			Properties: map[string]interface{}{
-				"archived":   page.Archived,
-				"created_by": page.CreatedBy,
-				"edited_by":  page.LastEditedBy,
+				"archived":    page.Archived,
+				"in_trash":    page.InTrash,
+				"parent_id":   page.GetParentID(),
+				"database_id": pageDatabaseID(page),
+				"created_by":  page.CreatedBy,
+				"edited_by":   page.LastEditedBy,
 			},
 		},
 	}
 }
 
-func (ns *Plugin) convertPageToDataItem(page *client.GetPageResult) *engine.DataItemContainer[any] {
+func (ns *Plugin) convertBlockToDataItem(block *types.Block, pageID string, parentID string, depth int) *engine.DataItemContainer[any] {
 	now := time.Now()
 	return &engine.DataItemContainer[any]{
-		ID:   string(page.Page.ID),
-		Type: common.ObjectTypePage,
-		Data: page,
+		ID:   string(block.ID),
+		Type: common.ObjectTypeBlock,
+		Data: block,
 		Metadata: engine.ItemMetadata{
 			SourceType:  "notion",
-			SourceID:    string(page.Page.ID),
-			OriginalID:  string(page.Page.ID),
-			CreatedAt:   page.Page.CreatedTime,
-			ModifiedAt:  page.Page.LastEditedTime,
+			SourceID:    string(block.ID),
+			OriginalID:  string(block.ID),
+			CreatedAt:   block.CreatedTime,
+			ModifiedAt:  block.LastEditedTime,
 			ProcessedAt: &now,
 			ValidationState: engine.ValidationState{
 				IsValid:     false,
@@ -328,27 +766,32 @@ func (ns *Plugin) convertPageToDataItem(page *client.GetPageResult) *engine.Data
 				StartedAt: now,
 			},
 			Properties: map[string]interface{}{
-				"archived":     page.Page.Archived,
-				"blocks_count": len(page.Blocks),
-				"created_by":   page.Page.CreatedBy,
-				"edited_by":    page.Page.LastEditedBy,
+				"page_id":      pageID,
+				"parent_id":    parentID,
+				"depth":        depth,
+				"block_type":   block.Type,
+				"has_children": block.HasChildren,
+				"archived":     block.Archived,
+				"in_trash":     block.InTrash,
+				"created_by":   block.CreatedBy,
+				"edited_by":    block.LastEditedBy,
 			},
 		},
 	}
 }
 
-func (ns *Plugin) convertBlockToDataItem(block *types.Block, parentID string) *engine.DataItemContainer[any] {
+func (ns *Plugin) convertCommentToDataItem(comment *types.Comment, pageID string) *engine.DataItemContainer[any] {
 	now := time.Now()
 	return &engine.DataItemContainer[any]{
-		ID:   string(block.ID),
-		Type: common.ObjectTypeBlock,
-		Data: block,
+		ID:   string(comment.ID),
+		Type: common.ObjectTypeComment,
+		Data: comment,
 		Metadata: engine.ItemMetadata{
 			SourceType:  "notion",
-			SourceID:    string(block.ID),
-			OriginalID:  string(block.ID),
-			CreatedAt:   block.CreatedTime,
-			ModifiedAt:  block.LastEditedTime,
+			SourceID:    string(comment.ID),
+			OriginalID:  string(comment.ID),
+			CreatedAt:   comment.CreatedTime,
+			ModifiedAt:  comment.LastEditedTime,
 			ProcessedAt: &now,
 			ValidationState: engine.ValidationState{
 				IsValid:     false,
@@ -363,14 +806,62 @@ func (ns *Plugin) convertBlockToDataItem(block *types.Block, parentID string) *e
 				StartedAt: now,
 			},
 			Properties: map[string]interface{}{
-				"parent_id":  parentID,
-				"created_by": block.CreatedBy,
-				"edited_by":  block.LastEditedBy,
+				"discussion_id": string(comment.DiscussionID),
+				"page_id":       pageID,
+				"parent_id":     comment.GetParentID(),
+				"block_comment": comment.IsBlockComment(),
+				"created_by":    comment.CreatedBy,
 			},
 		},
 	}
 }
 
+func (ns *Plugin) convertUserToDataItem(user *types.User, discovered bool) *engine.DataItemContainer[any] {
+	now := time.Now()
+	properties := map[string]interface{}{
+		"user_type":  user.Type,
+		"name":       user.GetDisplayName(),
+		"email":      user.GetEmail(),
+		"discovered": discovered,
+	}
+	if user.Bot != nil {
+		properties["workspace_name"] = user.Bot.WorkspaceName
+		if user.Bot.Owner != nil {
+			properties["bot_owner_type"] = user.Bot.Owner.Type
+			if user.Bot.Owner.User != nil {
+				properties["bot_owner_user_id"] = string(user.Bot.Owner.User.ID)
+			}
+		}
+	}
+
+	return &engine.DataItemContainer[any]{
+		ID:   string(user.ID),
+		Type: common.ObjectTypeUser,
+		Data: user,
+		Metadata: engine.ItemMetadata{
+			SourceType:  "notion",
+			SourceID:    string(user.ID),
+			OriginalID:  string(user.ID),
+			CreatedAt:   user.CreatedTime,
+			ModifiedAt:  user.LastEditedTime,
+			ProcessedAt: &now,
+			ValidationState: engine.ValidationState{
+				IsValid:     false,
+				ValidatedAt: now,
+			},
+			TransformState: engine.TransformState{
+				IsTransformed: false,
+			},
+			ProcessingState: engine.ProcessingState{
+				Phase:     engine.PhaseRead,
+				Status:    engine.ProcessingStatusPending,
+				StartedAt: now,
+			},
+			Properties: properties,
+		},
+	}
+}
+
 func (ns *Plugin) convertDatabaseToDataItem(database *types.Database) *engine.DataItemContainer[any] {
 	now := time.Now()
 	return &engine.DataItemContainer[any]{
@@ -398,9 +889,44 @@ func (ns *Plugin) convertDatabaseToDataItem(database *types.Database) *engine.Da
 			},
 			Properties: map[string]interface{}{
 				"archived":   database.Archived,
+				"in_trash":   database.InTrash,
 				"created_by": database.CreatedBy,
 				"edited_by":  database.LastEditedBy,
 			},
 		},
 	}
 }
+
+func (ns *Plugin) convertTombstoneToDataItem(tombstone *Tombstone) *engine.DataItemContainer[any] {
+	now := time.Now()
+	return &engine.DataItemContainer[any]{
+		ID:   tombstone.ID,
+		Type: tombstone.Type,
+		Data: tombstone,
+		Metadata: engine.ItemMetadata{
+			SourceType:  "notion",
+			SourceID:    tombstone.ID,
+			OriginalID:  tombstone.ID,
+			ModifiedAt:  tombstone.DetectedAt,
+			ProcessedAt: &now,
+			ValidationState: engine.ValidationState{
+				IsValid:     false,
+				ValidatedAt: now,
+			},
+			TransformState: engine.TransformState{
+				IsTransformed: false,
+			},
+			ProcessingState: engine.ProcessingState{
+				Phase:     engine.PhaseRead,
+				Status:    ProcessingStatusDeleted,
+				StartedAt: now,
+			},
+			Properties: map[string]interface{}{
+				"tombstone":        true,
+				"tombstone_reason": tombstone.Reason,
+				"parent_id":        tombstone.ParentID,
+				"database_id":      tombstone.DatabaseID,
+			},
+		},
+	}
+}
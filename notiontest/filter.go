@@ -0,0 +1,411 @@
+package notiontest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cmskitdev/notion/types"
+)
+
+// matches reports whether a database row matches a query filter.
+//
+// Compound filters, timestamp filters and the title, rich_text, number,
+// checkbox, select, status, date and files property filters are supported.
+//
+// Arguments:
+// - page: The row.
+// - filter: The filter, or nil to match every row.
+//
+// Returns:
+// - True if the row matches.
+// - An error if the filter is not supported.
+func matches(page *types.Page, filter *types.QueryFilter) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+
+	if len(filter.And) > 0 {
+		for _, f := range filter.And {
+			ok, err := matches(page, f)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if len(filter.Or) > 0 {
+		for _, f := range filter.Or {
+			ok, err := matches(page, f)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	if filter.Timestamp != nil {
+		switch {
+		case filter.Timestamp.CreatedTime != nil:
+			return matchDate(page.CreatedTime, true, filter.Timestamp.CreatedTime)
+		case filter.Timestamp.LastEditedTime != nil:
+			return matchDate(page.LastEditedTime, true, filter.Timestamp.LastEditedTime)
+		}
+		return false, fmt.Errorf("timestamp filter has no condition")
+	}
+
+	if filter.Property == nil {
+		return false, fmt.Errorf("filter has no property")
+	}
+	var property types.Property
+	if page.PropertyContainer != nil {
+		property = page.Properties[*filter.Property]
+	}
+
+	switch {
+	case filter.RichText != nil:
+		return matchText(textOf(property), filter.RichText), nil
+	case filter.Number != nil:
+		return matchNumber(property.Number, filter.Number), nil
+	case filter.Checkbox != nil:
+		checked := property.Checkbox != nil && *property.Checkbox
+		if filter.Checkbox.Equals != nil {
+			return checked == *filter.Checkbox.Equals, nil
+		}
+		if filter.Checkbox.DoesNotEqual != nil {
+			return checked != *filter.Checkbox.DoesNotEqual, nil
+		}
+		return false, fmt.Errorf("checkbox filter has no condition")
+	case filter.Select != nil:
+		var name *string
+		if property.Select != nil {
+			name = property.Select.Name
+		}
+		return matchOption(name, filter.Select.Equals, filter.Select.DoesNotEqual, filter.Select.IsEmpty, filter.Select.IsNotEmpty), nil
+	case filter.Status != nil:
+		var name *string
+		if property.Status != nil {
+			name = property.Status.Name
+		}
+		return matchOption(name, filter.Status.Equals, filter.Status.DoesNotEqual, filter.Status.IsEmpty, filter.Status.IsNotEmpty), nil
+	case filter.Date != nil:
+		if property.Date == nil || property.Date.Start == "" {
+			return matchDate(time.Time{}, false, filter.Date)
+		}
+		start, _, err := parseDate(property.Date.Start)
+		if err != nil {
+			return false, err
+		}
+		return matchDate(start, true, filter.Date)
+	case filter.Files != nil:
+		empty := len(property.Files) == 0
+		if filter.Files.IsEmpty != nil {
+			return empty == *filter.Files.IsEmpty, nil
+		}
+		if filter.Files.IsNotEmpty != nil {
+			return empty != *filter.Files.IsNotEmpty, nil
+		}
+		return false, fmt.Errorf("files filter has no condition")
+	}
+
+	return false, fmt.Errorf("filter on property %q is not supported", *filter.Property)
+}
+
+// matchText applies a rich text filter.
+//
+// Arguments:
+// - value: The plain text of the property.
+// - filter: The filter.
+//
+// Returns:
+// - True if the value matches.
+func matchText(value string, filter *types.RichTextFilter) bool {
+	switch {
+	case filter.Equals != nil:
+		return value == *filter.Equals
+	case filter.DoesNotEqual != nil:
+		return value != *filter.DoesNotEqual
+	case filter.Contains != nil:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(*filter.Contains))
+	case filter.DoesNotContain != nil:
+		return !strings.Contains(strings.ToLower(value), strings.ToLower(*filter.DoesNotContain))
+	case filter.StartsWith != nil:
+		return strings.HasPrefix(value, *filter.StartsWith)
+	case filter.EndsWith != nil:
+		return strings.HasSuffix(value, *filter.EndsWith)
+	case filter.IsEmpty != nil:
+		return (value == "") == *filter.IsEmpty
+	case filter.IsNotEmpty != nil:
+		return (value != "") == *filter.IsNotEmpty
+	}
+	return true
+}
+
+// matchNumber applies a number filter.
+//
+// Arguments:
+// - number: The number property value.
+// - filter: The filter.
+//
+// Returns:
+// - True if the value matches.
+func matchNumber(number *types.NumberProperty, filter *types.NumberFilter) bool {
+	if number == nil || number.Number == nil {
+		if filter.IsEmpty != nil {
+			return *filter.IsEmpty
+		}
+		if filter.IsNotEmpty != nil {
+			return !*filter.IsNotEmpty
+		}
+		return false
+	}
+
+	value := *number.Number
+	switch {
+	case filter.Equals != nil:
+		return value == *filter.Equals
+	case filter.DoesNotEqual != nil:
+		return value != *filter.DoesNotEqual
+	case filter.GreaterThan != nil:
+		return value > *filter.GreaterThan
+	case filter.LessThan != nil:
+		return value < *filter.LessThan
+	case filter.GreaterThanOrEqualTo != nil:
+		return value >= *filter.GreaterThanOrEqualTo
+	case filter.LessThanOrEqualTo != nil:
+		return value <= *filter.LessThanOrEqualTo
+	case filter.IsEmpty != nil:
+		return !*filter.IsEmpty
+	case filter.IsNotEmpty != nil:
+		return *filter.IsNotEmpty
+	}
+	return true
+}
+
+// matchOption applies a select or status filter.
+//
+// Arguments:
+// - name: The name of the selected option, or nil.
+// - equals: The equals condition.
+// - notEquals: The does_not_equal condition.
+// - isEmpty: The is_empty condition.
+// - isNotEmpty: The is_not_empty condition.
+//
+// Returns:
+// - True if the value matches.
+func matchOption(name, equals, notEquals *string, isEmpty, isNotEmpty *bool) bool {
+	value := ""
+	if name != nil {
+		value = *name
+	}
+
+	switch {
+	case equals != nil:
+		return value == *equals
+	case notEquals != nil:
+		return value != *notEquals
+	case isEmpty != nil:
+		return (value == "") == *isEmpty
+	case isNotEmpty != nil:
+		return (value != "") == *isNotEmpty
+	}
+	return true
+}
+
+// matchDate applies a date filter.
+//
+// Arguments:
+// - value: The date.
+// - set: Whether the date is set.
+// - filter: The filter.
+//
+// Returns:
+// - True if the value matches.
+// - An error if the filter value is not a valid date.
+func matchDate(value time.Time, set bool, filter *types.DateFilter) (bool, error) {
+	if filter.IsEmpty != nil {
+		return set != *filter.IsEmpty, nil
+	}
+	if filter.IsNotEmpty != nil {
+		return set == *filter.IsNotEmpty, nil
+	}
+
+	var condition *string
+	var compare func(value, bound time.Time) bool
+	switch {
+	case filter.Equals != nil:
+		condition, compare = filter.Equals, time.Time.Equal
+	case filter.Before != nil:
+		condition, compare = filter.Before, time.Time.Before
+	case filter.After != nil:
+		condition, compare = filter.After, time.Time.After
+	case filter.OnOrBefore != nil:
+		condition, compare = filter.OnOrBefore, func(value, bound time.Time) bool { return !value.After(bound) }
+	case filter.OnOrAfter != nil:
+		condition, compare = filter.OnOrAfter, func(value, bound time.Time) bool { return !value.Before(bound) }
+	default:
+		return false, fmt.Errorf("date filter has no condition")
+	}
+
+	bound, dateOnly, err := parseDate(*condition)
+	if err != nil {
+		return false, err
+	}
+	if !set {
+		return false, nil
+	}
+	if dateOnly {
+		// Date conditions compare whole days.
+		value = value.UTC().Truncate(24 * time.Hour)
+	}
+	return compare(value, bound), nil
+}
+
+// parseDate parses an ISO 8601 date or date-time.
+//
+// Arguments:
+// - value: The date.
+//
+// Returns:
+// - The date.
+// - True if the value has no time.
+// - An error if the value is not a valid date.
+func parseDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	return t, true, nil
+}
+
+// textOf returns the plain text value of a property, used to filter and sort
+// on it.
+//
+// Arguments:
+// - property: The property.
+//
+// Returns:
+// - The plain text.
+func textOf(property types.Property) string {
+	switch {
+	case property.Title != nil:
+		return types.ToPlainText(property.Title)
+	case property.RichText != nil:
+		return types.ToPlainText(property.RichText)
+	case property.Select != nil && property.Select.Name != nil:
+		return *property.Select.Name
+	case property.Status != nil && property.Status.Name != nil:
+		return *property.Status.Name
+	case property.Date != nil:
+		return property.Date.Start
+	case property.URL != nil:
+		return *property.URL
+	case property.Email != nil:
+		return *property.Email
+	case property.PhoneNumber != nil:
+		return *property.PhoneNumber
+	}
+	return ""
+}
+
+// sortRows orders database rows by query sorts, the first sort taking
+// precedence. Rows are left in the order they were added without sorts.
+//
+// Arguments:
+// - rows: The rows.
+// - sorts: The sorts.
+//
+// Returns:
+// - An error if a sort is not supported.
+func sortRows(rows []*types.Page, sorts []*types.QuerySort) error {
+	type comparison func(a, b *types.Page) int
+
+	comparisons := make([]comparison, 0, len(sorts))
+	for _, s := range sorts {
+		var compare comparison
+		var direction string
+		switch {
+		case s.TimestampSort != nil && s.TimestampSort.Timestamp != nil:
+			timestamp := *s.TimestampSort.Timestamp
+			if timestamp != "created_time" && timestamp != "last_edited_time" {
+				return fmt.Errorf("invalid sort timestamp %q", timestamp)
+			}
+			direction = s.TimestampSort.Direction
+			compare = func(a, b *types.Page) int {
+				if timestamp == "created_time" {
+					return a.CreatedTime.Compare(b.CreatedTime)
+				}
+				return a.LastEditedTime.Compare(b.LastEditedTime)
+			}
+		case s.PropertySort != nil && s.PropertySort.Property != nil:
+			name := *s.PropertySort.Property
+			direction = s.PropertySort.Direction
+			compare = func(a, b *types.Page) int {
+				return compareProperty(propertyOf(a, name), propertyOf(b, name))
+			}
+		default:
+			return fmt.Errorf("sort has no property or timestamp")
+		}
+
+		switch direction {
+		case "ascending":
+			comparisons = append(comparisons, compare)
+		case "descending":
+			comparisons = append(comparisons, func(a, b *types.Page) int { return compare(b, a) })
+		default:
+			return fmt.Errorf("invalid sort direction %q", direction)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, compare := range comparisons {
+			if c := compare(rows[i], rows[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// propertyOf returns a property of a page.
+//
+// Arguments:
+// - page: The page.
+// - name: The name of the property.
+//
+// Returns:
+// - The property, empty if the page does not have it.
+func propertyOf(page *types.Page, name string) types.Property {
+	if page.PropertyContainer == nil {
+		return types.Property{}
+	}
+	return page.Properties[name]
+}
+
+// compareProperty orders two property values, numbers numerically and
+// everything else by plain text.
+//
+// Arguments:
+// - a: The first property.
+// - b: The second property.
+//
+// Returns:
+// - A negative number, zero or a positive number if a sorts before, with or
+// after b.
+func compareProperty(a, b types.Property) int {
+	if a.Number != nil && b.Number != nil && a.Number.Number != nil && b.Number.Number != nil {
+		switch {
+		case *a.Number.Number < *b.Number.Number:
+			return -1
+		case *a.Number.Number > *b.Number.Number:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(textOf(a), textOf(b))
+}
@@ -0,0 +1,472 @@
+package notiontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cmskitdev/notion/types"
+)
+
+// search serves POST /v1/search.
+//
+// Pages and databases whose title contains the query are returned, leaving out
+// archived and trashed ones. Results are in the order they were added unless
+// sorted by last_edited_time.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	var req types.SearchRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	kind := ""
+	if req.Filter != nil {
+		if req.Filter.Property != "object" || (req.Filter.Value != "page" && req.Filter.Value != "database") {
+			writeError(w, http.StatusBadRequest, CodeValidationError, "body.filter should be a page or database object filter.")
+			return
+		}
+		kind = req.Filter.Value
+	}
+	if req.Sort != nil && (req.Sort.Timestamp != "last_edited_time" ||
+		(req.Sort.Direction != "ascending" && req.Sort.Direction != "descending")) {
+		writeError(w, http.StatusBadRequest, CodeValidationError, "body.sort should sort by last_edited_time ascending or descending.")
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	query := strings.ToLower(req.Query)
+	var hits []searchHit
+	for _, id := range ws.order {
+		if page, ok := ws.pages[id]; ok && (kind == "" || kind == "page") {
+			if !page.Archived && !page.InTrash && strings.Contains(strings.ToLower(pageTitle(page)), query) {
+				hits = append(hits, searchHit{id: id, edited: page.LastEditedTime, object: page})
+			}
+		}
+		if database, ok := ws.databases[id]; ok && (kind == "" || kind == "database") {
+			if !database.Archived && !database.InTrash && strings.Contains(strings.ToLower(database.GetTitle()), query) {
+				hits = append(hits, searchHit{id: id, edited: database.LastEditedTime, object: database})
+			}
+		}
+	}
+
+	if req.Sort != nil {
+		descending := req.Sort.Direction == "descending"
+		sort.SliceStable(hits, func(i, j int) bool {
+			if descending {
+				return hits[i].edited.After(hits[j].edited)
+			}
+			return hits[i].edited.Before(hits[j].edited)
+		})
+	}
+
+	page, next, err := paginate(hits, func(hit searchHit) string { return hit.id }, req.StartCursor, req.PageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	results := make([]any, len(page))
+	for i, hit := range page {
+		results[i] = hit.object
+	}
+
+	data, err := listResponse("page_or_database", results, next)
+	respond(w, data, err)
+}
+
+// searchHit is a page or database matched by a search.
+type searchHit struct {
+	id     string
+	edited time.Time
+	object any
+}
+
+// getPage serves GET /v1/pages/{id}.
+func (s *Server) getPage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	page, ok := ws.pages[canonical(id)]
+	if !ok {
+		writeNotFound(w, "page", id)
+		return
+	}
+
+	data, err := json.Marshal(page)
+	respond(w, data, err)
+}
+
+// createPage serves POST /v1/pages.
+//
+// Pages created in a database may only set properties of its schema.
+func (s *Server) createPage(w http.ResponseWriter, r *http.Request) {
+	var req types.PageCreateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if err := req.Parent.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+	if len(req.Children) > maxAppendBlocks {
+		writeError(w, http.StatusBadRequest, CodeValidationError, fmt.Sprintf("body.children.length should be ≤ %d.", maxAppendBlocks))
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	parentID := canonical(req.Parent.GetParentID())
+	switch req.Parent.Type {
+	case types.ParentTypePage:
+		if _, ok := ws.pages[parentID]; !ok {
+			writeNotFound(w, "page", parentID)
+			return
+		}
+	case types.ParentTypeDatabase:
+		database, ok := ws.databases[parentID]
+		if !ok {
+			writeNotFound(w, "database", parentID)
+			return
+		}
+		for name := range req.Properties {
+			if _, ok := database.Properties[name]; !ok {
+				writeError(w, http.StatusBadRequest, CodeValidationError, fmt.Sprintf("%s is not a property that exists.", name))
+				return
+			}
+		}
+	}
+
+	parent := req.Parent
+	page := &types.Page{
+		Parent: &parent,
+		Icon:   req.Icon,
+		Cover:  req.Cover,
+		PropertyAccessor: types.PropertyAccessor[types.Property]{
+			PropertyContainer: &types.PropertyContainer[types.Property]{Properties: req.Properties},
+		},
+	}
+	ws.addPage(page)
+
+	children := make([]*types.Block, len(req.Children))
+	for i := range req.Children {
+		children[i] = &req.Children[i]
+	}
+	ws.appendBlocks(string(page.ID), "", children)
+
+	data, err := json.Marshal(page)
+	respond(w, data, err)
+}
+
+// updatePage serves PATCH /v1/pages/{id}.
+//
+// The given properties replace the page's properties of the same name.
+func (s *Server) updatePage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req types.PageUpdateRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	page, ok := ws.pages[canonical(id)]
+	if !ok {
+		writeNotFound(w, "page", id)
+		return
+	}
+	restores := (req.Archived != nil && !*req.Archived) || (req.InTrash != nil && !*req.InTrash)
+	if (page.Archived || page.InTrash) && !restores {
+		writeError(w, http.StatusBadRequest, CodeValidationError, "Can't edit block that is archived. You must unarchive the block before editing.")
+		return
+	}
+
+	for name, property := range req.Properties {
+		page.SetProperty(name, property)
+	}
+	if req.Archived != nil {
+		page.Archived = *req.Archived
+	}
+	if req.InTrash != nil {
+		page.InTrash = *req.InTrash
+	}
+	if req.Icon != nil {
+		page.Icon = req.Icon
+	}
+	if req.Cover != nil {
+		page.Cover = req.Cover
+	}
+	page.LastEditedTime = ws.now()
+
+	data, err := json.Marshal(page)
+	respond(w, data, err)
+}
+
+// getBlock serves GET /v1/blocks/{id}.
+func (s *Server) getBlock(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	block, ok := ws.blocks[canonical(id)]
+	if !ok {
+		writeNotFound(w, "block", id)
+		return
+	}
+
+	data, err := json.Marshal(block)
+	respond(w, data, err)
+}
+
+// listChildren serves GET /v1/blocks/{id}/children.
+func (s *Server) listChildren(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	cursor, size, err := pageQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	if !ws.exists(canonical(id)) {
+		writeNotFound(w, "block", id)
+		return
+	}
+
+	page, next, err := paginate(ws.childBlocks(canonical(id)), blockID, cursor, size)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	data, err := listResponse("block", page, next)
+	respond(w, data, err)
+}
+
+// appendChildren serves PATCH /v1/blocks/{id}/children.
+func (s *Server) appendChildren(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req types.BlockAppendRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if len(req.Children) > maxAppendBlocks {
+		writeError(w, http.StatusBadRequest, CodeValidationError, fmt.Sprintf("body.children.length should be ≤ %d.", maxAppendBlocks))
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	parentID := canonical(id)
+	if !ws.exists(parentID) {
+		writeNotFound(w, "block", id)
+		return
+	}
+
+	after := ""
+	if req.After != nil {
+		after = canonical(string(*req.After))
+		found := false
+		for _, child := range ws.children[parentID] {
+			found = found || child == after
+		}
+		if !found {
+			writeNotFound(w, "block", after)
+			return
+		}
+	}
+
+	children := make([]*types.Block, len(req.Children))
+	for i := range req.Children {
+		children[i] = &req.Children[i]
+	}
+	ws.appendBlocks(parentID, after, children)
+
+	data, err := listResponse("block", children, nil)
+	respond(w, data, err)
+}
+
+// getDatabase serves GET /v1/databases/{id}.
+func (s *Server) getDatabase(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	database, ok := ws.databases[canonical(id)]
+	if !ok {
+		writeNotFound(w, "database", id)
+		return
+	}
+
+	data, err := json.Marshal(database)
+	respond(w, data, err)
+}
+
+// queryDatabase serves POST /v1/databases/{id}/query.
+//
+// Archived and trashed rows are left out. See matches and sortRows for the
+// supported filters and sorts.
+func (s *Server) queryDatabase(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req types.Query
+	if !decode(w, r, &req) {
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	if _, ok := ws.databases[canonical(id)]; !ok {
+		writeNotFound(w, "database", id)
+		return
+	}
+
+	var rows []*types.Page
+	for _, row := range ws.rows(canonical(id)) {
+		if row.Archived || row.InTrash {
+			continue
+		}
+		ok, err := matches(row, req.Filter)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+			return
+		}
+		if ok {
+			rows = append(rows, row)
+		}
+	}
+	if err := sortRows(rows, req.Sorts); err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	page, next, err := paginate(rows, pageID, req.StartCursor, req.PageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	data, err := listResponse("page_or_database", page, next)
+	respond(w, data, err)
+}
+
+// listComments serves GET /v1/comments.
+func (s *Server) listComments(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("block_id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, CodeValidationError, "block_id should be defined, instead was `undefined`.")
+		return
+	}
+	cursor, size, err := pageQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	if !ws.exists(canonical(id)) {
+		writeNotFound(w, "block", id)
+		return
+	}
+
+	page, next, err := paginate(ws.comments[canonical(id)], commentID, cursor, size)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	data, err := listResponse("comment", page, next)
+	respond(w, data, err)
+}
+
+// listUsers serves GET /v1/users.
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	cursor, size, err := pageQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	page, next, err := paginate(ws.users, userID, cursor, size)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	data, err := listResponse("user", page, next)
+	respond(w, data, err)
+}
+
+// getUser serves GET /v1/users/{id}.
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	for _, user := range ws.users {
+		if string(user.ID) == canonical(id) {
+			data, err := json.Marshal(user)
+			respond(w, data, err)
+			return
+		}
+	}
+	writeNotFound(w, "user", id)
+}
+
+// pageTitle returns the plain text title of a page.
+//
+// Arguments:
+// - page: The page.
+//
+// Returns:
+// - The title, empty if the page has no title property.
+func pageTitle(page *types.Page) string {
+	if page.PropertyContainer == nil {
+		return ""
+	}
+	for _, property := range page.Properties {
+		if property.Type == types.PropertyTypeTitle {
+			return types.ToPlainText(property.Title)
+		}
+	}
+	return ""
+}
+
+func pageID(page *types.Page) string          { return string(page.ID) }
+func blockID(block *types.Block) string       { return string(block.ID) }
+func commentID(comment *types.Comment) string { return string(comment.ID) }
+func userID(user *types.User) string          { return string(user.ID) }
@@ -0,0 +1,456 @@
+// Package notiontest provides an in-process stand-in for the Notion API, so
+// that the plugin and client code can be tested end to end without a network
+// connection or an API key.
+//
+// A Server serves the search, page, block, database, comment, user and file
+// upload endpoints from a Workspace, with Notion's pagination cursors and
+// error objects. Rate limiting and failures can be injected with
+// Server.Throttle and Server.Fail.
+//
+// Example:
+//
+//	workspace := notiontest.NewWorkspace()
+//	page := workspace.AddPage(&types.Page{...})
+//	workspace.AddBlocks(string(page.ID), types.NewParagraphBlock(...))
+//
+//	server := notiontest.NewServer(workspace)
+//	defer server.Close()
+//
+//	// Point the client at server.APIURL(), or use server.Client(), which
+//	// sends requests for any host to the server.
+package notiontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmskitdev/notion/types"
+)
+
+// Error codes returned in Notion error objects.
+//
+// See https://developers.notion.com/reference/status-codes.
+const (
+	CodeInvalidJSON         = "invalid_json"
+	CodeInvalidRequestURL   = "invalid_request_url"
+	CodeInvalidRequest      = "invalid_request"
+	CodeValidationError     = "validation_error"
+	CodeUnauthorized        = "unauthorized"
+	CodeRestrictedResource  = "restricted_resource"
+	CodeObjectNotFound      = "object_not_found"
+	CodeConflictError       = "conflict_error"
+	CodeRateLimited         = "rate_limited"
+	CodeInternalServerError = "internal_server_error"
+	CodeServiceUnavailable  = "service_unavailable"
+)
+
+// maxPageSize is the largest page size Notion accepts, and the default.
+const maxPageSize = 100
+
+// maxAppendBlocks is the most blocks Notion accepts in one append.
+const maxAppendBlocks = 100
+
+// Server is an in-process stand-in for the Notion API, backed by a Workspace.
+type Server struct {
+	*httptest.Server
+
+	// Workspace is the content served.
+	Workspace *Workspace
+	// Token is the integration token requests must carry as a bearer token.
+	// Any token is accepted when empty. Set it before sending requests.
+	Token string
+
+	mu       sync.Mutex
+	faults   []fault
+	requests []string
+}
+
+// fault is an error response injected with Server.Throttle or Server.Fail.
+type fault struct {
+	status     int
+	code       string
+	message    string
+	retryAfter time.Duration
+}
+
+// NewServer starts a server for a workspace. Close it once done.
+//
+// Arguments:
+// - workspace: The workspace to serve, or nil for an empty one.
+//
+// Returns:
+// - The started server.
+func NewServer(workspace *Workspace) *Server {
+	if workspace == nil {
+		workspace = NewWorkspace()
+	}
+
+	s := &Server{Workspace: workspace}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/search", s.search)
+	mux.HandleFunc("GET /v1/pages/{id}", s.getPage)
+	mux.HandleFunc("POST /v1/pages", s.createPage)
+	mux.HandleFunc("PATCH /v1/pages/{id}", s.updatePage)
+	mux.HandleFunc("GET /v1/blocks/{id}", s.getBlock)
+	mux.HandleFunc("GET /v1/blocks/{id}/children", s.listChildren)
+	mux.HandleFunc("PATCH /v1/blocks/{id}/children", s.appendChildren)
+	mux.HandleFunc("GET /v1/databases/{id}", s.getDatabase)
+	mux.HandleFunc("POST /v1/databases/{id}/query", s.queryDatabase)
+	mux.HandleFunc("GET /v1/comments", s.listComments)
+	mux.HandleFunc("GET /v1/users", s.listUsers)
+	mux.HandleFunc("GET /v1/users/{id}", s.getUser)
+	mux.HandleFunc("POST /v1/file_uploads", s.createUpload)
+	mux.HandleFunc("GET /v1/file_uploads", s.listUploads)
+	mux.HandleFunc("GET /v1/file_uploads/{id}", s.getUpload)
+	mux.HandleFunc("POST /v1/file_uploads/{id}/send", s.sendUpload)
+	mux.HandleFunc("GET /files/{id}/{name}", s.serveFile)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequestURL, "Invalid request URL.")
+	})
+
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// APIURL returns the base URL of the API, the equivalent of
+// https://api.notion.com/v1.
+//
+// Returns:
+// - The base URL.
+func (s *Server) APIURL() string {
+	return s.URL + "/v1"
+}
+
+// Client returns an HTTP client that sends requests for any host to the
+// server, so clients with a fixed base URL can be tested unchanged.
+//
+// Returns:
+// - The HTTP client.
+func (s *Server) Client() *http.Client {
+	target, _ := url.Parse(s.URL)
+	return &http.Client{
+		Transport: &redirectTransport{target: target, next: s.Server.Client().Transport},
+	}
+}
+
+// Throttle answers the next requests with 429 rate_limited responses.
+//
+// Arguments:
+// - n: The number of requests to throttle.
+// - retryAfter: The delay sent in the Retry-After header.
+func (s *Server) Throttle(n int, retryAfter time.Duration) {
+	s.inject(n, fault{
+		status:     http.StatusTooManyRequests,
+		code:       CodeRateLimited,
+		message:    "You have been rate limited. Please try again in a few minutes.",
+		retryAfter: retryAfter,
+	})
+}
+
+// Fail answers the next requests with an error object.
+//
+// Arguments:
+// - n: The number of requests to fail.
+// - status: The HTTP status code.
+// - code: The Notion error code, such as CodeInternalServerError.
+// - message: The error message.
+func (s *Server) Fail(n int, status int, code string, message string) {
+	s.inject(n, fault{status: status, code: code, message: message})
+}
+
+// inject queues an error response for the next requests.
+//
+// Arguments:
+// - n: The number of requests.
+// - f: The error response.
+func (s *Server) inject(n int, f fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.faults = append(s.faults, f)
+	}
+}
+
+// Requests returns the requests served so far, as "METHOD /path", including
+// the ones answered with injected errors.
+//
+// Returns:
+// - The requests in the order they were received.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// AddFile adds a Notion-hosted file, served by the server.
+//
+// Arguments:
+// - name: The file name.
+// - contentType: The content type.
+// - data: The file content.
+//
+// Returns:
+// - The file, with the URL it is served at.
+func (s *Server) AddFile(name string, contentType string, data []byte) *types.File {
+	w := s.Workspace
+	w.mu.Lock()
+	f := &file{
+		id:          newID(),
+		name:        name,
+		contentType: contentType,
+		data:        data,
+		status:      types.FileUploadStatusUploaded,
+		created:     w.now(),
+	}
+	w.files[f.id] = f
+	w.mu.Unlock()
+
+	return &types.File{
+		Type: "file",
+		File: &types.NotionHostedFileType{
+			URL:        s.fileURL(f),
+			ExpiryTime: f.created.Add(time.Hour).Format(time.RFC3339),
+		},
+		Name: &name,
+	}
+}
+
+// intercept records requests, checks the token and answers with injected
+// errors before passing requests on.
+//
+// Arguments:
+// - next: The API handler.
+//
+// Returns:
+// - The handler.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		var f *fault
+		if len(s.faults) > 0 {
+			f = &s.faults[0]
+			s.faults = s.faults[1:]
+		}
+		token := s.Token
+		s.mu.Unlock()
+
+		if f != nil {
+			if f.retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(f.retryAfter.Seconds()))))
+			}
+			writeError(w, f.status, f.code, f.message)
+			return
+		}
+
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token && strings.HasPrefix(r.URL.Path, "/v1/") {
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "API token is invalid.")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// redirectTransport sends every request to the server.
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+//
+// Arguments:
+// - req: The request.
+//
+// Returns:
+// - The response of the server.
+// - An error if the request could not be sent.
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return t.next.RoundTrip(req)
+}
+
+// writeJSON writes a JSON response.
+//
+// Arguments:
+// - w: The response writer.
+// - status: The HTTP status code.
+// - data: The encoded body.
+func writeJSON(w http.ResponseWriter, status int, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// writeError writes a Notion error object.
+//
+// Arguments:
+// - w: The response writer.
+// - status: The HTTP status code.
+// - code: The Notion error code.
+// - message: The error message.
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	data, _ := json.Marshal(map[string]any{
+		"object":     "error",
+		"status":     status,
+		"code":       code,
+		"message":    message,
+		"request_id": newID(),
+	})
+	writeJSON(w, status, data)
+}
+
+// writeNotFound writes an object_not_found error.
+//
+// Arguments:
+// - w: The response writer.
+// - kind: The kind of object, such as "page".
+// - id: The requested ID.
+func writeNotFound(w http.ResponseWriter, kind string, id string) {
+	writeError(w, http.StatusNotFound, CodeObjectNotFound,
+		fmt.Sprintf("Could not find %s with ID: %s. Make sure the relevant pages and databases are shared with your integration.", kind, id))
+}
+
+// decode decodes a JSON request body, writing an invalid_json error if it
+// cannot be decoded.
+//
+// Arguments:
+// - w: The response writer.
+// - r: The request.
+// - v: The value to decode into.
+//
+// Returns:
+// - True if the body was decoded.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidJSON, fmt.Sprintf("Error parsing JSON body: %s", err))
+		return false
+	}
+	return true
+}
+
+// pageQuery reads the pagination parameters of a GET request.
+//
+// Arguments:
+// - r: The request.
+//
+// Returns:
+// - The start cursor, or nil.
+// - The page size, or nil.
+// - An error if the page size is not a number.
+func pageQuery(r *http.Request) (*string, *int, error) {
+	var cursor *string
+	var size *int
+
+	query := r.URL.Query()
+	if value := query.Get("start_cursor"); value != "" {
+		cursor = &value
+	}
+	if value := query.Get("page_size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("page_size should be a number, instead was %q", value)
+		}
+		size = &n
+	}
+	return cursor, size, nil
+}
+
+// paginate returns one page of results. Cursors are the ID of the first
+// result of the next page, like Notion's.
+//
+// Arguments:
+// - items: All results.
+// - id: The ID of a result.
+// - cursor: The start cursor, or nil for the first page.
+// - size: The page size, or nil for the default.
+//
+// Returns:
+// - The results of the page.
+// - The cursor of the next page, or nil if this is the last page.
+// - An error if the cursor or page size is invalid.
+func paginate[T any](items []T, id func(T) string, cursor *string, size *int) ([]T, *string, error) {
+	limit := maxPageSize
+	if size != nil {
+		if *size < 1 || *size > maxPageSize {
+			return nil, nil, fmt.Errorf("page_size should be between 1 and %d, instead was %d", maxPageSize, *size)
+		}
+		limit = *size
+	}
+
+	start := 0
+	if cursor != nil {
+		start = -1
+		for i, item := range items {
+			if id(item) == canonical(*cursor) {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return nil, nil, fmt.Errorf("start_cursor provided is invalid: %s", *cursor)
+		}
+	}
+
+	end := min(start+limit, len(items))
+	var next *string
+	if end < len(items) {
+		value := id(items[end])
+		next = &value
+	}
+	return items[start:end], next, nil
+}
+
+// listResponse encodes a list response.
+//
+// Arguments:
+// - kind: The type of the results, such as "block".
+// - results: The results of the page.
+// - next: The cursor of the next page, or nil.
+//
+// Returns:
+// - The encoded response.
+// - An error if a result cannot be encoded.
+func listResponse(kind string, results any, next *string) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"object":      types.ObjectTypeList,
+		"results":     results,
+		"next_cursor": next,
+		"has_more":    next != nil,
+		"type":        kind,
+		kind:          struct{}{},
+	})
+}
+
+// respond encodes a response, writing an internal_server_error if it cannot
+// be encoded.
+//
+// Arguments:
+// - w: The response writer.
+// - data: The encoded response.
+// - err: The encoding error.
+func respond(w http.ResponseWriter, data []byte, err error) {
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
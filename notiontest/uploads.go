@@ -0,0 +1,241 @@
+package notiontest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/cmskitdev/notion/types"
+)
+
+// maxUploadSize is the largest single part upload Notion accepts.
+const maxUploadSize = 20 << 20
+
+// uploadExpiry is how long a pending upload can be sent for.
+const uploadExpiry = time.Hour
+
+// createUpload serves POST /v1/file_uploads.
+//
+// Only single part uploads are supported.
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request) {
+	var req types.CreateFileUploadRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Mode != "" && req.Mode != "single_part" {
+		writeError(w, http.StatusBadRequest, CodeValidationError, "Only single_part uploads are supported.")
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	f := &file{
+		id:      newID(),
+		name:    req.Filename,
+		status:  types.FileUploadStatusPending,
+		created: ws.now(),
+	}
+	ws.files[f.id] = f
+
+	data, err := json.Marshal(s.uploadResponse(f))
+	respond(w, data, err)
+}
+
+// sendUpload serves POST /v1/file_uploads/{id}/send, which takes the file
+// content as the "file" part of a multipart/form-data body.
+func (s *Server) sendUpload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	part, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, "body.file should be defined, instead was `undefined`.")
+		return
+	}
+	defer part.Close()
+
+	content, err := io.ReadAll(io.LimitReader(part, maxUploadSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	if len(content) > maxUploadSize {
+		writeError(w, http.StatusBadRequest, CodeValidationError, "File is larger than the single part upload limit of 20MB.")
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	f, ok := ws.files[canonical(id)]
+	if !ok {
+		writeNotFound(w, "file upload", id)
+		return
+	}
+	if f.status != types.FileUploadStatusPending {
+		writeError(w, http.StatusBadRequest, CodeValidationError, "File upload is not pending, its status is "+string(f.status)+".")
+		return
+	}
+
+	f.data = content
+	f.contentType = header.Header.Get("Content-Type")
+	if f.contentType == "" || f.contentType == "application/octet-stream" {
+		f.contentType = http.DetectContentType(content)
+	}
+	if f.name == "" {
+		f.name = header.Filename
+	}
+	f.status = types.FileUploadStatusUploaded
+
+	data, err := json.Marshal(s.uploadResponse(f))
+	respond(w, data, err)
+}
+
+// getUpload serves GET /v1/file_uploads/{id}.
+func (s *Server) getUpload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	f, ok := ws.files[canonical(id)]
+	if !ok {
+		writeNotFound(w, "file upload", id)
+		return
+	}
+
+	data, err := json.Marshal(s.uploadResponse(f))
+	respond(w, data, err)
+}
+
+// listUploads serves GET /v1/file_uploads, newest first.
+func (s *Server) listUploads(w http.ResponseWriter, r *http.Request) {
+	cursor, size, err := pageQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	ws := s.Workspace
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	uploads := make([]types.FileUploadResponse, 0, len(ws.files))
+	for _, f := range ws.files {
+		uploads = append(uploads, s.uploadResponse(f))
+	}
+	sortUploads(uploads)
+
+	page, next, err := paginate(uploads, func(upload types.FileUploadResponse) string { return upload.ID }, cursor, size)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeValidationError, err.Error())
+		return
+	}
+
+	data, err := listResponse("file_upload", page, next)
+	respond(w, data, err)
+}
+
+// serveFile serves GET /files/{id}/{name}, the content of uploaded and
+// Notion-hosted files.
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	ws := s.Workspace
+	ws.mu.RLock()
+	f, ok := ws.files[canonical(r.PathValue("id"))]
+	var content []byte
+	var contentType string
+	if ok {
+		content, contentType = f.data, f.contentType
+		ok = f.status == types.FileUploadStatusUploaded
+	}
+	ws.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	_, _ = w.Write(content)
+}
+
+// uploadResponse returns the file upload object of a file with the workspace
+// lock held.
+//
+// Arguments:
+// - f: The file.
+//
+// Returns:
+// - The file upload object.
+func (s *Server) uploadResponse(f *file) types.FileUploadResponse {
+	created := f.created.Format(time.RFC3339)
+	response := types.FileUploadResponse{
+		Object:         "file_upload",
+		ID:             f.id,
+		CreatedTime:    created,
+		LastEditedTime: created,
+		Status:         f.status,
+	}
+	if f.name != "" {
+		response.Filename = &f.name
+	}
+
+	switch f.status {
+	case types.FileUploadStatusPending:
+		expiry := f.created.Add(uploadExpiry).Format(time.RFC3339)
+		uploadURL := s.APIURL() + "/file_uploads/" + f.id + "/send"
+		response.ExpiryTime = &expiry
+		response.UploadURL = &uploadURL
+	case types.FileUploadStatusUploaded:
+		length := int64(len(f.data))
+		response.ContentLength = &length
+		if f.contentType != "" {
+			response.ContentType = &f.contentType
+		}
+	}
+	return response
+}
+
+// fileURL returns the URL a file is served at.
+//
+// Arguments:
+// - f: The file.
+//
+// Returns:
+// - The URL.
+func (s *Server) fileURL(f *file) string {
+	return s.URL + "/files/" + f.id + "/" + url.PathEscape(f.name)
+}
+
+// sortUploads orders file uploads newest first, by ID within the same minute.
+//
+// Arguments:
+// - uploads: The file uploads.
+func sortUploads(uploads []types.FileUploadResponse) {
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploadBefore(uploads[i], uploads[j])
+	})
+}
+
+// uploadBefore reports whether a file upload is listed before another.
+//
+// Arguments:
+// - a: The first upload.
+// - b: The second upload.
+//
+// Returns:
+// - True if a is listed first.
+func uploadBefore(a, b types.FileUploadResponse) bool {
+	if a.CreatedTime != b.CreatedTime {
+		return a.CreatedTime > b.CreatedTime
+	}
+	return a.ID < b.ID
+}
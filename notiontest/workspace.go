@@ -0,0 +1,446 @@
+package notiontest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmskitdev/notion/types"
+)
+
+// Workspace is the in-memory content served by a Server.
+//
+// Objects are stored as given and encoded every time they are served, so
+// changes made to them are visible to later requests. Changes must not be
+// made while requests are being served. Methods are safe for concurrent use.
+type Workspace struct {
+	// Clock returns the current time, used for the timestamps of objects
+	// created through the API. Defaults to time.Now. Like Notion, times are
+	// truncated to the minute.
+	Clock func() time.Time
+
+	mu        sync.RWMutex
+	pages     map[string]*types.Page
+	databases map[string]*types.Database
+	blocks    map[string]*types.Block
+	// children holds the IDs of the child blocks of each page or block, in
+	// order.
+	children map[string][]string
+	// comments holds the comments on each page or block.
+	comments map[string][]*types.Comment
+	users    []*types.User
+	files    map[string]*file
+	// order holds the IDs of the pages and databases in the order they were
+	// added, which is the order search returns them in when unsorted.
+	order []string
+}
+
+// file is a file uploaded through the file upload API or added with
+// Server.AddFile.
+type file struct {
+	id          string
+	name        string
+	contentType string
+	data        []byte
+	status      types.FileUploadStatus
+	created     time.Time
+}
+
+// NewWorkspace creates an empty workspace.
+//
+// Returns:
+// - A new workspace.
+func NewWorkspace() *Workspace {
+	return &Workspace{
+		pages:     make(map[string]*types.Page),
+		databases: make(map[string]*types.Database),
+		blocks:    make(map[string]*types.Block),
+		children:  make(map[string][]string),
+		comments:  make(map[string][]*types.Comment),
+		files:     make(map[string]*file),
+	}
+}
+
+// AddPage adds a page, or replaces the page with the same ID. Pages with a
+// database parent are the rows of that database.
+//
+// The ID, object type and timestamps are filled in when unset.
+//
+// Arguments:
+// - page: The page.
+//
+// Returns:
+// - The page.
+func (w *Workspace) AddPage(page *types.Page) *types.Page {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.addPage(page)
+	return page
+}
+
+// addPage adds a page with the lock held.
+//
+// Arguments:
+// - page: The page.
+func (w *Workspace) addPage(page *types.Page) {
+	if page.ID == "" {
+		page.ID = types.PageID(newID())
+	}
+	page.ID = types.PageID(canonical(string(page.ID)))
+	page.Object = types.ObjectTypePage
+	w.stamp(&page.BaseObject)
+	if page.URL == "" {
+		page.URL = notionURL(string(page.ID))
+	}
+	if page.PropertyContainer == nil {
+		page.PropertyContainer = &types.PropertyContainer[types.Property]{}
+	}
+
+	if _, ok := w.pages[string(page.ID)]; !ok {
+		w.order = append(w.order, string(page.ID))
+	}
+	w.pages[string(page.ID)] = page
+}
+
+// AddDatabase adds a database, or replaces the database with the same ID.
+//
+// The ID, object type and timestamps are filled in when unset.
+//
+// Arguments:
+// - database: The database.
+//
+// Returns:
+// - The database.
+func (w *Workspace) AddDatabase(database *types.Database) *types.Database {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if database.ID == "" {
+		database.ID = types.DatabaseID(newID())
+	}
+	database.ID = types.DatabaseID(canonical(string(database.ID)))
+	database.Object = types.ObjectTypeDatabase
+	w.stamp(&database.BaseObject)
+	if database.URL == "" {
+		database.URL = notionURL(string(database.ID))
+	}
+
+	if _, ok := w.databases[string(database.ID)]; !ok {
+		w.order = append(w.order, string(database.ID))
+	}
+	w.databases[string(database.ID)] = database
+	return database
+}
+
+// AddBlocks appends blocks to the children of a page or block.
+//
+// The ID, object type, timestamps and parent of each block are filled in when
+// unset, and the parent block is marked as having children.
+//
+// Arguments:
+// - parentID: The ID of the page or block.
+// - blocks: The blocks to append.
+//
+// Returns:
+// - The blocks.
+func (w *Workspace) AddBlocks(parentID string, blocks ...*types.Block) []*types.Block {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.appendBlocks(canonical(parentID), "", blocks)
+	return blocks
+}
+
+// appendBlocks appends blocks with the lock held.
+//
+// Arguments:
+// - parentID: The canonical ID of the page or block.
+// - after: The ID of the child to insert the blocks after, or empty to append
+// them at the end.
+// - blocks: The blocks to append.
+func (w *Workspace) appendBlocks(parentID string, after string, blocks []*types.Block) {
+	ids := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.ID == "" {
+			block.ID = types.BlockID(newID())
+		}
+		block.ID = types.BlockID(canonical(string(block.ID)))
+		block.Object = types.ObjectTypeBlock
+		w.stamp(&block.BaseObject)
+		if block.Parent == nil {
+			block.Parent = w.parentOf(parentID)
+		}
+
+		w.blocks[string(block.ID)] = block
+		ids = append(ids, string(block.ID))
+	}
+
+	children := w.children[parentID]
+	at := len(children)
+	for i, id := range children {
+		if id == after {
+			at = i + 1
+			break
+		}
+	}
+	w.children[parentID] = append(children[:at:at], append(ids, children[at:]...)...)
+
+	if parent, ok := w.blocks[parentID]; ok && len(blocks) > 0 {
+		parent.HasChildren = true
+	}
+}
+
+// AddComments adds comments to the pages or blocks they are on.
+//
+// The ID, object type, timestamps and discussion ID of each comment are filled
+// in when unset.
+//
+// Arguments:
+// - comments: The comments, with their parent set.
+//
+// Returns:
+// - The comments.
+func (w *Workspace) AddComments(comments ...*types.Comment) []*types.Comment {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, comment := range comments {
+		if comment.ID == "" {
+			comment.ID = types.CommentID(newID())
+		}
+		comment.ID = types.CommentID(canonical(string(comment.ID)))
+		if comment.DiscussionID == "" {
+			comment.DiscussionID = types.DiscussionID(newID())
+		}
+		comment.Object = types.ObjectTypeComment
+		w.stamp(&comment.BaseObject)
+
+		parentID := commentParentID(comment)
+		w.comments[parentID] = append(w.comments[parentID], comment)
+	}
+	return comments
+}
+
+// AddUsers adds users to the workspace.
+//
+// Arguments:
+// - users: The users.
+//
+// Returns:
+// - The users.
+func (w *Workspace) AddUsers(users ...*types.User) []*types.User {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, user := range users {
+		if user.ID == "" {
+			user.ID = types.UserID(newID())
+		}
+		user.ID = types.UserID(canonical(string(user.ID)))
+		user.Object = types.ObjectTypeUser
+		w.users = append(w.users, user)
+	}
+	return users
+}
+
+// Page returns a page, including pages created through the API.
+//
+// Arguments:
+// - id: The ID of the page, dashed or not.
+//
+// Returns:
+// - The page, or nil if it does not exist.
+func (w *Workspace) Page(id string) *types.Page {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.pages[canonical(id)]
+}
+
+// Database returns a database.
+//
+// Arguments:
+// - id: The ID of the database, dashed or not.
+//
+// Returns:
+// - The database, or nil if it does not exist.
+func (w *Workspace) Database(id string) *types.Database {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.databases[canonical(id)]
+}
+
+// Children returns the child blocks of a page or block, including blocks
+// appended through the API.
+//
+// Arguments:
+// - id: The ID of the page or block, dashed or not.
+//
+// Returns:
+// - The child blocks in order.
+func (w *Workspace) Children(id string) []*types.Block {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.childBlocks(canonical(id))
+}
+
+// childBlocks returns the child blocks of a page or block with the lock held.
+//
+// Arguments:
+// - id: The canonical ID of the page or block.
+//
+// Returns:
+// - The child blocks in order.
+func (w *Workspace) childBlocks(id string) []*types.Block {
+	ids := w.children[id]
+	blocks := make([]*types.Block, 0, len(ids))
+	for _, child := range ids {
+		blocks = append(blocks, w.blocks[child])
+	}
+	return blocks
+}
+
+// rows returns the pages of a database with the lock held.
+//
+// Arguments:
+// - databaseID: The canonical ID of the database.
+//
+// Returns:
+// - The rows in the order they were added.
+func (w *Workspace) rows(databaseID string) []*types.Page {
+	var rows []*types.Page
+	for _, id := range w.order {
+		page, ok := w.pages[id]
+		if !ok || page.Parent == nil || page.Parent.Type != types.ParentTypeDatabase {
+			continue
+		}
+		if canonical(page.Parent.GetParentID()) == databaseID {
+			rows = append(rows, page)
+		}
+	}
+	return rows
+}
+
+// parentOf returns the parent of a block appended to a page or block with the
+// lock held.
+//
+// Arguments:
+// - id: The canonical ID of the page or block.
+//
+// Returns:
+// - The parent.
+func (w *Workspace) parentOf(id string) *types.Parent {
+	if _, ok := w.pages[id]; ok {
+		pageID := types.PageID(id)
+		return &types.Parent{Type: types.ParentTypePage, PageID: &pageID}
+	}
+	blockID := types.BlockID(id)
+	return &types.Parent{Type: types.ParentTypeBlock, BlockID: &blockID}
+}
+
+// exists reports whether a page or block exists with the lock held.
+//
+// Arguments:
+// - id: The canonical ID of the page or block.
+//
+// Returns:
+// - True if it exists.
+func (w *Workspace) exists(id string) bool {
+	if _, ok := w.pages[id]; ok {
+		return true
+	}
+	_, ok := w.blocks[id]
+	return ok
+}
+
+// stamp fills in the timestamps of an object when unset.
+//
+// Arguments:
+// - object: The object.
+func (w *Workspace) stamp(object *types.BaseObject) {
+	if object.CreatedTime.IsZero() {
+		object.CreatedTime = w.now()
+	}
+	if object.LastEditedTime.IsZero() {
+		object.LastEditedTime = object.CreatedTime
+	}
+}
+
+// now returns the current time, truncated to the minute like Notion does.
+//
+// Returns:
+// - The current time.
+func (w *Workspace) now() time.Time {
+	clock := w.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	return clock().UTC().Truncate(time.Minute)
+}
+
+// commentParentID returns the ID of the page or block a comment is on.
+//
+// Arguments:
+// - comment: The comment.
+//
+// Returns:
+// - The canonical ID, or empty if the comment has no parent.
+func commentParentID(comment *types.Comment) string {
+	if comment.Parent == nil {
+		return ""
+	}
+	switch {
+	case comment.Parent.PageID != nil:
+		return canonical(string(*comment.Parent.PageID))
+	case comment.Parent.BlockID != nil:
+		return canonical(string(*comment.Parent.BlockID))
+	}
+	return ""
+}
+
+// canonical returns the dashed form of a Notion ID.
+//
+// Arguments:
+// - id: The ID, dashed or not.
+//
+// Returns:
+// - The dashed ID, or the ID as given if it is not a valid Notion ID.
+func canonical(id string) string {
+	parsed, err := types.IDParser.Parse(id)
+	if err != nil {
+		return id
+	}
+	return parsed
+}
+
+// newID returns a random dashed UUID.
+//
+// Returns:
+// - The ID.
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// notionURL returns the notion.so URL of a page or database.
+//
+// Arguments:
+// - id: The ID of the page or database.
+//
+// Returns:
+// - The URL.
+func notionURL(id string) string {
+	return "https://www.notion.so/" + strings.ReplaceAll(id, "-", "")
+}
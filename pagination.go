@@ -0,0 +1,84 @@
+package notion
+
+import (
+	"context"
+)
+
+// listPage is a single page of a cursor-paginated listing.
+type listPage[T any] struct {
+	results    []T
+	nextCursor *string
+	hasMore    bool
+}
+
+// paginate drives a cursor-paginated listing, visiting every result in order.
+//
+// A failed fetch is reported to the read's error log and ends the listing.
+// The read goes on with its other listings, but a read with errors does not
+// advance the checkpoint.
+//
+// Listings with a non-empty key are resumable: they start from the cursor
+// saved by a previous attempt of the same run, record the next cursor once a
+// page has been fully visited, and are skipped entirely once completed.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - key: The crawl state key of the listing, or empty if not resumable.
+// - fetch: Fetches the page starting at the given cursor.
+// - visit: Visits a single result, returning false to stop the listing.
+//
+// Returns:
+// - False if the listing was stopped by visit or the context was cancelled,
+// true otherwise.
+func paginate[T any](ctx context.Context, scope readScope, key string, fetch func(cursor *string) (*listPage[T], error), visit func(result *T) bool) bool {
+	return paginateBatches(ctx, scope, key, fetch, func(results []T) bool {
+		for i := range results {
+			if !visit(&results[i]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// paginateBatches is like paginate but visits the results one API page at a
+// time, so that a page can be processed concurrently as long as visit returns
+// only once all of it has been processed.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - key: The crawl state key of the listing, or empty if not resumable.
+// - fetch: Fetches the page starting at the given cursor.
+// - visit: Visits the results of a page, returning false to stop the listing.
+//
+// Returns:
+// - False if the listing was stopped by visit or the context was cancelled,
+// true otherwise.
+func paginateBatches[T any](ctx context.Context, scope readScope, key string, fetch func(cursor *string) (*listPage[T], error), visit func(results []T) bool) bool {
+	if scope.progress.done(key) {
+		return true
+	}
+
+	cursor := scope.progress.cursor(key)
+	for {
+		page, err := fetch(cursor)
+		if err != nil {
+			scope.errors.report(ctx, err, "")
+			return ctx.Err() == nil
+		}
+
+		if !visit(page.results) {
+			return false
+		}
+
+		if !page.hasMore || page.nextCursor == nil {
+			scope.progress.finish(ctx, key)
+			return true
+		}
+
+		scope.progress.advance(ctx, key, *page.nextCursor)
+		cursor = page.nextCursor
+	}
+}
@@ -0,0 +1,189 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cmskitdev/client"
+)
+
+// rateLimiter is a token bucket shared by every request a Plugin makes.
+//
+// Besides the steady rate it supports pausing: when the API answers with 429
+// Too Many Requests, every worker waits until the Retry-After delay passed.
+type rateLimiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// newRateLimiter creates a rate limiter.
+//
+// Arguments:
+// - rate: The sustained number of requests per second, zero or less for no limit.
+//
+// Returns:
+// - A new rate limiter with a burst of one second's worth of requests.
+func newRateLimiter(rate float64) *rateLimiter {
+	burst := math.Max(1, math.Ceil(rate))
+	return &rateLimiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a request may be made.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - How long the caller waited.
+// - The context error if the context ended while waiting.
+func (l *rateLimiter) wait(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return time.Since(start), nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Since(start), ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token if one is available.
+//
+// Returns:
+// - Zero if a token was taken, otherwise how long to wait before retrying.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// pause stops all requests for the given duration.
+//
+// Arguments:
+// - d: How long to pause.
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	// Start from an empty bucket so the workers don't all burst at once when
+	// the pause ends.
+	l.tokens = 0
+	l.last = l.pausedUntil
+}
+
+// defaultRetryAfter is how long requests pause after a 429 response without a
+// usable Retry-After header.
+const defaultRetryAfter = time.Second
+
+// throttleDelay reports whether an error is a 429 response and how long the
+// API asked to wait.
+//
+// Arguments:
+// - err: The error returned for a request.
+//
+// Returns:
+// - The Retry-After delay.
+// - True if the request was throttled.
+func throttleDelay(err error) (time.Duration, bool) {
+	var rateLimitErr *client.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return 0, false
+	}
+	if rateLimitErr.RetryAfter > 0 {
+		return rateLimitErr.RetryAfter, true
+	}
+	return defaultRetryAfter, true
+}
+
+// doRequest performs an API request through the plugin's rate limiter.
+//
+// The client retries throttled requests itself, honoring Retry-After, and does
+// not report those attempts. A 429 that reaches the plugin has used up the
+// client's retries: every worker of the plugin pauses for the Retry-After
+// delay, and the request is tried again until it succeeds, fails otherwise or
+// the context ends.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - ns: The plugin making the request.
+// - call: Describes the request for error reporting and telemetry.
+// - do: Performs the request.
+//
+// Returns:
+// - The response.
+// - A *ReadError if the request failed, or the context error if the context
+// ended.
+func doRequest[T any](ctx context.Context, ns *Plugin, call apiCall, do func() (T, error)) (T, error) {
+	ctx, span := ns.otel.startCall(ctx, call)
+	defer endSpan(ctx, span)
+
+	for {
+		waited, err := ns.limiter.wait(ctx)
+		ns.addRateLimitWait(ctx, waited)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+
+		ns.incrementRequestCount(ctx)
+		started := time.Now()
+		response, err := do()
+		ns.otel.recordAttempt(ctx, call, time.Since(started), err)
+		if err == nil {
+			return response, nil
+		}
+
+		if ctx.Err() != nil {
+			return response, ctx.Err()
+		}
+
+		if delay, throttled := throttleDelay(err); throttled {
+			ns.limiter.pause(delay)
+			ns.recordThrottle(ctx, delay)
+			ns.otel.recordThrottle(ctx, call, delay)
+			continue
+		}
+
+		ns.incrementErrorCount(ctx)
+		readErr := newReadError(call, err)
+		ns.otel.recordError(ctx, call, readErr)
+		return response, readErr
+	}
+}
@@ -0,0 +1,96 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/client"
+)
+
+func TestRateLimiterBurstsThenWaits(t *testing.T) {
+	limiter := newRateLimiter(20)
+
+	for i := 0; i < 20; i++ {
+		if delay := limiter.reserve(); delay != 0 {
+			t.Fatalf("request %d of the burst waited %s", i, delay)
+		}
+	}
+	if delay := limiter.reserve(); delay <= 0 || delay > 50*time.Millisecond {
+		t.Errorf("delay after the burst = %s, want up to 50ms", delay)
+	}
+
+	waited, err := limiter.wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited <= 0 {
+		t.Error("wait returned without waiting for a token")
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	limiter := newRateLimiter(0)
+
+	for i := 0; i < 1000; i++ {
+		if delay := limiter.reserve(); delay != 0 {
+			t.Fatalf("request %d waited %s without a rate limit", i, delay)
+		}
+	}
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	limiter := newRateLimiter(0)
+	limiter.pause(time.Hour)
+	limiter.pause(time.Minute)
+
+	if delay := limiter.reserve(); delay < 59*time.Minute {
+		t.Errorf("delay = %s, want the longest pause", delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait = %v, want the context error", err)
+	}
+}
+
+func TestRateLimiterResumesWithEmptyBucket(t *testing.T) {
+	limiter := newRateLimiter(10)
+	limiter.pause(20 * time.Millisecond)
+
+	if _, err := limiter.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The token taken after the pause was refilled at the sustained rate, so
+	// the next request waits instead of bursting.
+	if delay := limiter.reserve(); delay <= 0 {
+		t.Error("requests burst after the pause ended")
+	}
+}
+
+func TestThrottleDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		delay     time.Duration
+		throttled bool
+	}{
+		{name: "retry after", err: &client.RateLimitError{RetryAfter: 5 * time.Second}, delay: 5 * time.Second, throttled: true},
+		{name: "no retry after", err: &client.RateLimitError{}, delay: defaultRetryAfter, throttled: true},
+		{name: "wrapped", err: fmt.Errorf("search: %w", &client.RateLimitError{RetryAfter: 2 * time.Second}), delay: 2 * time.Second, throttled: true},
+		{name: "server error", err: &client.HTTPError{StatusCode: 500}},
+		{name: "other", err: errors.New("boom")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, throttled := throttleDelay(tt.err)
+			if delay != tt.delay || throttled != tt.throttled {
+				t.Errorf("throttleDelay = %s, %v, want %s, %v", delay, throttled, tt.delay, tt.throttled)
+			}
+		})
+	}
+}
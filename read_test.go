@@ -0,0 +1,211 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+)
+
+func TestReadPaginatesSearch(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	var want []string
+	for i := 0; i < 5; i++ {
+		page := workspace.AddPage(newWorkspacePage(fmt.Sprintf("Page %d", i)))
+		want = append(want, string(page.ID))
+	}
+
+	config := testConfig()
+	config.PageSize = 2
+	ns, server := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	got := itemIDs(items, common.ObjectTypePage)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+	if n := countRequests(server, "POST /v1/search"); n != 3 {
+		t.Errorf("search requests = %d, want 3", n)
+	}
+	if summary := ns.LastErrorSummary(); len(summary.Errors) != 0 {
+		t.Errorf("errors = %v, want none", summary.Errors)
+	}
+}
+
+func TestReadWalksBlocks(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	page := workspace.AddPage(newWorkspacePage("Page"))
+	blocks := workspace.AddBlocks(string(page.ID), newParagraph("one"), newParagraph("two"), newParagraph("three"))
+	nested := workspace.AddBlocks(string(blocks[0].ID), newParagraph("nested"))
+
+	config := testConfig()
+	config.PageSize = 2
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+	if got := itemIDs(items, common.ObjectTypePage); len(got) != 1 || got[0] != string(page.ID) {
+		t.Errorf("pages = %v, want [%s]", got, page.ID)
+	}
+	got := itemIDs(items, common.ObjectTypeBlock)
+	want := []string{string(blocks[0].ID), string(nested[0].ID), string(blocks[1].ID), string(blocks[2].ID)}
+	if len(got) != len(want) {
+		t.Fatalf("blocks = %v, want %v", got, want)
+	}
+	seen := make(map[string]bool)
+	for _, id := range got {
+		seen[id] = true
+	}
+	for _, id := range want {
+		if !seen[id] {
+			t.Errorf("block %s was not read", id)
+		}
+	}
+}
+
+func TestReadRetriesThrottledRequests(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	workspace.AddPage(newWorkspacePage("Page"))
+
+	ns, server := newTestPlugin(t, workspace, testConfig())
+	// The client retries once on its own, so the second 429 reaches the
+	// plugin.
+	server.Throttle(2, 0)
+
+	started := time.Now()
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	if len(items) != 1 {
+		t.Errorf("items = %d, want 1", len(items))
+	}
+	if n := countRequests(server, "POST /v1/search"); n != 3 {
+		t.Errorf("search requests = %d, want 3", n)
+	}
+	if summary := ns.LastErrorSummary(); len(summary.Errors) != 0 {
+		t.Errorf("errors = %v, want none", summary.Errors)
+	}
+
+	metrics := ns.GetMetrics()
+	if metrics.ThrottledRequests != 1 || metrics.ThrottleTime != defaultRetryAfter {
+		t.Errorf("throttled = %d for %s, want 1 for %s", metrics.ThrottledRequests, metrics.ThrottleTime, defaultRetryAfter)
+	}
+	if metrics.ErrorsEncountered != 0 {
+		t.Errorf("errors encountered = %d, want 0", metrics.ErrorsEncountered)
+	}
+	// The retry waits for the pause to end.
+	if elapsed := time.Since(started); elapsed < defaultRetryAfter {
+		t.Errorf("read took %s, want at least %s", elapsed, defaultRetryAfter)
+	}
+}
+
+func TestReadRetriesThrottledRequestsUntilTheContextEnds(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	workspace.AddPage(newWorkspacePage("Page"))
+
+	ns, server := newTestPlugin(t, workspace, testConfig())
+	server.Throttle(100, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRetryAfter+defaultRetryAfter/2)
+	defer cancel()
+	results, err := ns.Read(ctx, &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}})
+	if err != nil {
+		t.Fatalf("failed to start read: %v", err)
+	}
+	var items int
+	for range results {
+		items++
+	}
+
+	if items != 0 {
+		t.Errorf("items = %d, want 0", items)
+	}
+	// Two attempts before the pause and two after it.
+	if n := countRequests(server, "POST /v1/search"); n != 4 {
+		t.Errorf("search requests = %d, want 4", n)
+	}
+	if summary := ns.LastErrorSummary(); len(summary.Errors) != 0 {
+		t.Errorf("errors = %v, want none for throttled requests", summary.Errors)
+	}
+	if throttled := ns.GetMetrics().ThrottledRequests; throttled != 2 {
+		t.Errorf("throttled requests = %d, want 2", throttled)
+	}
+}
+
+func TestReadReportsFailedListings(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		code      string
+		failures  int
+		requests  int
+		retryable bool
+	}{
+		{name: "server error", status: http.StatusInternalServerError, code: notiontest.CodeInternalServerError, failures: 2, requests: 2, retryable: true},
+		{name: "not found", status: http.StatusNotFound, code: notiontest.CodeObjectNotFound, failures: 1, requests: 1, retryable: false},
+		{name: "validation", status: http.StatusBadRequest, code: notiontest.CodeValidationError, failures: 1, requests: 1, retryable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := notiontest.NewWorkspace()
+			page := workspace.AddPage(newWorkspacePage("Page"))
+			workspace.AddBlocks(string(page.ID), newParagraph("text"))
+
+			ns, server := newTestPlugin(t, workspace, testConfig())
+			server.Fail(tt.failures, tt.status, tt.code, "injected")
+
+			var handled []*ReadError
+			ctx := WithErrorHandler(context.Background(), func(err *ReadError) {
+				handled = append(handled, err)
+			})
+			items := readAll(t, ctx, ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+			if len(items) != 0 {
+				t.Errorf("items = %d, want 0", len(items))
+			}
+			if n := countRequests(server, "POST /v1/search"); n != tt.requests {
+				t.Errorf("search requests = %d, want %d", n, tt.requests)
+			}
+			if len(handled) != 1 {
+				t.Fatalf("handled errors = %v, want 1", handled)
+			}
+			readErr := handled[0]
+			if readErr.Operation != OperationSearch || readErr.ObjectType != common.ObjectTypePage {
+				t.Errorf("error = %+v, want a page search error", readErr)
+			}
+			if readErr.StatusCode != tt.status || readErr.Code != tt.code || readErr.Retryable != tt.retryable {
+				t.Errorf("error = %d %q retryable %v, want %d %q retryable %v",
+					readErr.StatusCode, readErr.Code, readErr.Retryable, tt.status, tt.code, tt.retryable)
+			}
+
+			summary := ns.LastRunSummary()
+			if !summary.Completed || len(summary.Errors.Errors) != 1 || summary.Metrics.ErrorsEncountered != 1 {
+				t.Errorf("summary = %+v, want a completed read with 1 error", summary)
+			}
+		})
+	}
+}
+
+func TestReadRequiresValidToken(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	workspace.AddPage(newWorkspacePage("Page"))
+
+	ns, server := newTestPlugin(t, workspace, testConfig())
+	server.Token = "secret_other"
+
+	readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	summary := ns.LastErrorSummary()
+	if len(summary.Errors) != 1 {
+		t.Fatalf("errors = %v, want 1", summary.Errors)
+	}
+	if readErr := summary.Errors[0]; readErr.StatusCode != http.StatusUnauthorized || readErr.Retryable {
+		t.Errorf("error = %+v, want a permanent 401", readErr)
+	}
+}
@@ -0,0 +1,260 @@
+package notion
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// RelationEdge is a link from a page to a related page through a relation
+// property. The edges of a page are stored in the "relations" metadata
+// property of its item when NotionSourceConfig.RelationHops is set.
+type RelationEdge struct {
+	// Property is the name of the relation property.
+	Property string `json:"property"`
+	// PageID is the ID of the related page.
+	PageID string `json:"page_id"`
+	// DatabaseID is the ID of the database the relation points to, taken from
+	// the schema of the page's database, if known.
+	DatabaseID string `json:"database_id,omitempty"`
+}
+
+// relationGraph holds the state of a read that follows relation properties,
+// see NotionSourceConfig.RelationHops.
+//
+// A nil *relationGraph means relations are not followed.
+type relationGraph struct {
+	hops int
+
+	mu    sync.Mutex
+	pages map[string]struct{}
+	// schemas maps database IDs to the target database of each of their
+	// relation properties, by property name.
+	schemas map[string]map[string]string
+}
+
+// newRelationGraph creates the relation graph of a read.
+//
+// Arguments:
+// - config: The source configuration.
+//
+// Returns:
+// - The relation graph, or nil if relations are not followed.
+func newRelationGraph(config NotionSourceConfig) *relationGraph {
+	if config.RelationHops <= 0 {
+		return nil
+	}
+	return &relationGraph{
+		hops:    config.RelationHops,
+		pages:   make(map[string]struct{}),
+		schemas: make(map[string]map[string]string),
+	}
+}
+
+// visit marks a page as processed, so that a page reached both by the crawl
+// and through a relation is only read once.
+//
+// Arguments:
+// - id: The ID of the page.
+//
+// Returns:
+// - True if the page had not been visited before.
+func (g *relationGraph) visit(id string) bool {
+	if g == nil {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.pages[id]; ok {
+		return false
+	}
+	g.pages[id] = struct{}{}
+	return true
+}
+
+// visited reports whether a page has been processed already.
+//
+// Arguments:
+// - id: The ID of the page.
+//
+// Returns:
+// - True if the page was visited.
+func (g *relationGraph) visited(id string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, ok := g.pages[id]
+	return ok
+}
+
+// learn records the relation targets of a database schema.
+//
+// Arguments:
+// - database: The database.
+func (g *relationGraph) learn(database *types.Database) {
+	if g == nil {
+		return
+	}
+
+	targets := make(map[string]string)
+	for name, property := range database.Properties {
+		if property.Relation != nil && property.Relation.DatabaseID != "" {
+			targets[name] = string(property.Relation.DatabaseID)
+		}
+	}
+
+	g.remember(string(database.ID), targets)
+}
+
+// remember stores the relation targets of a database.
+//
+// Arguments:
+// - databaseID: The ID of the database.
+// - targets: The target database IDs by property name.
+func (g *relationGraph) remember(databaseID string, targets map[string]string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.schemas[databaseID] = targets
+}
+
+// schema returns the relation targets learned for a database.
+//
+// Arguments:
+// - databaseID: The ID of the database.
+//
+// Returns:
+// - The target database IDs by property name, and false if the database has
+// not been seen yet.
+func (g *relationGraph) schema(databaseID string) (map[string]string, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	targets, ok := g.schemas[databaseID]
+	return targets, ok
+}
+
+// relationTargets returns the relation targets of a page's database, fetching
+// the database the first time it is needed.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - databaseID: The ID of the database.
+//
+// Returns:
+// - The target database IDs by property name, empty if unknown.
+func (ns *Plugin) relationTargets(ctx context.Context, scope readScope, databaseID string) map[string]string {
+	if databaseID == "" {
+		return nil
+	}
+	if targets, ok := scope.relations.schema(databaseID); ok {
+		return targets
+	}
+
+	database, err := ns.getDatabase(ctx, types.DatabaseID(databaseID))
+	if err != nil {
+		// Edges are still recorded without their database, so the failure is
+		// only reported once.
+		scope.errors.report(ctx, err, OperationGetDatabase)
+		scope.relations.remember(databaseID, nil)
+		return nil
+	}
+
+	scope.relations.learn(database)
+	targets, _ := scope.relations.schema(databaseID)
+	return targets
+}
+
+// pageRelations returns the relation edges of a page.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - page: The page.
+//
+// Returns:
+// - The edges ordered by property name.
+func (ns *Plugin) pageRelations(ctx context.Context, scope readScope, page *types.Page) []RelationEdge {
+	if scope.relations == nil || page.PropertyContainer == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(page.Properties))
+	for name, property := range page.Properties {
+		if property.Type == types.PropertyTypeRelation && len(property.Relation) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	targets := ns.relationTargets(ctx, scope, pageDatabaseID(page))
+
+	var edges []RelationEdge
+	for _, name := range names {
+		for _, related := range page.Properties[name].Relation {
+			edges = append(edges, RelationEdge{
+				Property:   name,
+				PageID:     related.ID,
+				DatabaseID: targets[name],
+			})
+		}
+	}
+	return edges
+}
+
+// expandRelations reads the pages a page relates to, up to
+// NotionSourceConfig.RelationHops relations away from the crawled pages.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - edges: The relation edges of the page.
+// - scope: The stages to run for the page.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) expandRelations(ctx context.Context, edges []RelationEdge, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	if len(edges) == 0 || scope.hop >= scope.relations.hops {
+		return true
+	}
+
+	// Related pages are not descendants of the page, so they are not below an
+	// archived ancestor. They are otherwise read like crawled pages, including
+	// the subtree below them.
+	scope.hop++
+	scope.inArchive = false
+
+	for _, edge := range edges {
+		id, err := types.ParsePageID(edge.PageID)
+		if err != nil {
+			continue
+		}
+
+		if scope.relations.visited(string(id)) {
+			continue
+		}
+
+		page, err := ns.getPage(ctx, id)
+		if err != nil {
+			scope.errors.report(ctx, err, OperationGetPage)
+			if ctx.Err() != nil {
+				return false
+			}
+			continue
+		}
+
+		if !ns.processPage(ctx, page, scope.pages || scope.rows, scope, results) {
+			return false
+		}
+	}
+	return true
+}
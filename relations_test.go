@@ -0,0 +1,153 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+// relatedWorkspace holds a post related to its author, who is related back to
+// the post and to their company.
+type relatedWorkspace struct {
+	workspace *notiontest.Workspace
+	posts     *types.Database
+	authors   *types.Database
+	post      *types.Page
+	author    *types.Page
+	company   *types.Page
+}
+
+// newRelatedWorkspace creates a relatedWorkspace.
+//
+// Returns:
+// - The workspace.
+func newRelatedWorkspace() *relatedWorkspace {
+	w := &relatedWorkspace{workspace: notiontest.NewWorkspace()}
+
+	companies := w.workspace.AddDatabase(newWorkspaceDatabase("Companies"))
+	w.authors = newWorkspaceDatabase("Authors")
+	w.authors.ID = types.DatabaseID(newTestID(1))
+	w.posts = newWorkspaceDatabase("Posts")
+	w.posts.ID = types.DatabaseID(newTestID(2))
+	w.authors.Properties["Posts"] = types.DatabaseProperty{Type: types.PropertyTypeRelation, Relation: &types.RelationConfig{DatabaseID: w.posts.ID}}
+	w.authors.Properties["Company"] = types.DatabaseProperty{Type: types.PropertyTypeRelation, Relation: &types.RelationConfig{DatabaseID: companies.ID}}
+	w.posts.Properties["Author"] = types.DatabaseProperty{Type: types.PropertyTypeRelation, Relation: &types.RelationConfig{DatabaseID: w.authors.ID}}
+	w.workspace.AddDatabase(w.authors)
+	w.workspace.AddDatabase(w.posts)
+
+	w.company = w.workspace.AddPage(newRow(companies, "Company", true))
+	w.post = newRow(w.posts, "Post", true)
+	w.post.ID = types.PageID(newTestID(3))
+	w.author = newRow(w.authors, "Author", true)
+	w.author.ID = types.PageID(newTestID(4))
+	w.post.Properties["Author"] = relationTo(w.author)
+	w.author.Properties["Posts"] = relationTo(w.post)
+	w.author.Properties["Company"] = relationTo(w.company)
+	w.workspace.AddPage(w.post)
+	w.workspace.AddPage(w.author)
+	return w
+}
+
+func TestReadFollowsRelations(t *testing.T) {
+	tests := []struct {
+		name  string
+		hops  int
+		pages func(w *relatedWorkspace) []string
+	}{
+		{
+			name:  "disabled",
+			hops:  0,
+			pages: func(w *relatedWorkspace) []string { return []string{string(w.post.ID)} },
+		},
+		{
+			name:  "one hop",
+			hops:  1,
+			pages: func(w *relatedWorkspace) []string { return []string{string(w.post.ID), string(w.author.ID)} },
+		},
+		{
+			name: "two hops",
+			hops: 2,
+			pages: func(w *relatedWorkspace) []string {
+				return []string{string(w.post.ID), string(w.author.ID), string(w.company.ID)}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newRelatedWorkspace()
+			config := testConfig()
+			config.RelationHops = tt.hops
+			config.RootPageIDs = []string{string(w.post.ID)}
+			ns, server := newTestPlugin(t, w.workspace, config)
+
+			items := readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+			assertEmittedOnce(t, "page", itemIDs(items, common.ObjectTypePage), tt.pages(w))
+			if errs := ns.LastErrorSummary().Errors; len(errs) != 0 {
+				t.Errorf("errors = %v, want none", errs)
+			}
+			if tt.hops == 0 {
+				return
+			}
+
+			post := findItem(items, string(w.post.ID))
+			edges, _ := post.Metadata.Properties["relations"].([]RelationEdge)
+			want := RelationEdge{Property: "Author", PageID: string(w.author.ID), DatabaseID: string(w.authors.ID)}
+			if len(edges) != 1 || edges[0] != want {
+				t.Errorf("post relations = %+v, want %+v", edges, want)
+			}
+			if n := countRequests(server, "GET /v1/databases/"+string(w.posts.ID)); n != 1 {
+				t.Errorf("the posts schema was fetched %d times, want once", n)
+			}
+		})
+	}
+}
+
+// newTestID returns a fixed page or database ID, for objects that refer to
+// each other before they are added to a workspace.
+//
+// Arguments:
+// - n: A number distinguishing the ID.
+//
+// Returns:
+// - The dashed ID.
+func newTestID(n int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+}
+
+// relationTo returns a relation property pointing to a page.
+//
+// Arguments:
+// - page: The related page.
+//
+// Returns:
+// - The property.
+func relationTo(page *types.Page) types.Property {
+	return types.Property{
+		Type:     types.PropertyTypeRelation,
+		Relation: []types.RelationProperty{{ID: string(page.ID)}},
+	}
+}
+
+// findItem returns the item with an ID.
+//
+// Arguments:
+// - items: The items.
+// - id: The ID.
+//
+// Returns:
+// - The first item with the ID, or an empty item.
+func findItem(items []engine.DataItemContainer[any], id string) engine.DataItemContainer[any] {
+	for _, item := range items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return engine.DataItemContainer[any]{}
+}
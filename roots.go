@@ -0,0 +1,217 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// rootCrawl holds the state of a read scoped to NotionSourceConfig.RootPageIDs
+// and RootDatabaseIDs instead of the whole workspace.
+type rootCrawl struct {
+	pages     []types.PageID
+	databases []types.DatabaseID
+
+	mu      sync.Mutex
+	visited map[string]struct{}
+}
+
+// newRootCrawl parses the configured roots.
+//
+// Arguments:
+// - config: The source configuration.
+//
+// Returns:
+// - The root crawl, or nil if no roots are configured.
+// - An error if a root ID is not a valid Notion ID.
+func newRootCrawl(config NotionSourceConfig) (*rootCrawl, error) {
+	if len(config.RootPageIDs) == 0 && len(config.RootDatabaseIDs) == 0 {
+		return nil, nil
+	}
+
+	roots := &rootCrawl{
+		visited: make(map[string]struct{}),
+	}
+	for _, raw := range config.RootPageIDs {
+		id, err := types.ParsePageID(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root page ID %q: %w", raw, err)
+		}
+		roots.pages = append(roots.pages, id)
+	}
+	for _, raw := range config.RootDatabaseIDs {
+		id, err := types.ParseDatabaseID(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root database ID %q: %w", raw, err)
+		}
+		roots.databases = append(roots.databases, id)
+	}
+
+	return roots, nil
+}
+
+// visit marks a page or database as visited.
+//
+// Arguments:
+// - id: The ID of the page or database.
+//
+// Returns:
+// - True if it had not been visited before.
+func (r *rootCrawl) visit(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.visited[id]; ok {
+		return false
+	}
+	r.visited[id] = struct{}{}
+	return true
+}
+
+// contains reports whether a page or database was visited.
+//
+// Arguments:
+// - id: The ID of the page or database.
+//
+// Returns:
+// - True if it was visited.
+func (r *rootCrawl) contains(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.visited[id]
+	return ok
+}
+
+// readRoots reads the configured root pages and databases and everything
+// below them.
+//
+// The crawl is depth-first: a child page or database is crawled as soon as its
+// block is found, so a block listing only completes once everything below it
+// has been read and resumed crawls never skip a subtree.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+func (ns *Plugin) readRoots(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any]) {
+	ctx, span := ns.otel.startStage(ctx, "read_roots")
+	defer endSpan(ctx, span)
+
+	for _, id := range scope.roots.pages {
+		if !ns.crawlPage(ctx, id, scope, results) {
+			return
+		}
+	}
+
+	for _, id := range scope.roots.databases {
+		if !ns.crawlDatabase(ctx, id, scope, results) {
+			return
+		}
+	}
+}
+
+// crawlChild crawls the page or database a child_page or child_database block
+// links to.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - block: The block found in the current subtree.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) crawlChild(ctx context.Context, block *types.Block, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	switch block.Type {
+	case types.BlockTypeChildPage:
+		return ns.crawlPage(ctx, types.PageID(block.ID), scope, results)
+	case types.BlockTypeChildDatabase:
+		return ns.crawlDatabase(ctx, types.DatabaseID(block.ID), scope, results)
+	}
+	return true
+}
+
+// crawlPage reads a page of the subtree and walks its blocks to find child
+// pages and databases.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - pageID: The ID of the page.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) crawlPage(ctx context.Context, pageID types.PageID, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	if !scope.roots.visit(string(pageID)) {
+		return true
+	}
+
+	page, err := ns.getPage(ctx, pageID)
+	if err != nil {
+		scope.errors.report(ctx, err, OperationGetPage)
+		return ctx.Err() == nil
+	}
+
+	emit := scope.marks.changed(checkpointKeyPages, page.LastEditedTime) && scope.pages
+	return ns.processPage(ctx, page, emit, scope, results)
+}
+
+// crawlDatabase reads a database of the subtree and, when rows are in scope,
+// its rows and everything below them.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - databaseID: The ID of the database.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) crawlDatabase(ctx context.Context, databaseID types.DatabaseID, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	if !scope.databases && !scope.rows {
+		return true
+	}
+	if !scope.roots.visit(string(databaseID)) {
+		return true
+	}
+
+	database, err := ns.getDatabase(ctx, databaseID)
+	if err != nil {
+		scope.errors.report(ctx, err, OperationGetDatabase)
+		return ctx.Err() == nil
+	}
+
+	changed := scope.marks.changed(checkpointKeyDatabases, database.LastEditedTime)
+	return ns.processDatabase(ctx, database, changed, scope, results)
+}
+
+// getDatabase fetches a single database.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - databaseID: The ID of the database.
+//
+// Returns:
+// - The database.
+// - An error if the request failed.
+func (ns *Plugin) getDatabase(ctx context.Context, databaseID types.DatabaseID) (*types.Database, error) {
+	call := apiCall{
+		operation:  OperationGetDatabase,
+		objectID:   string(databaseID),
+		objectType: common.ObjectTypeCollection,
+		databaseID: string(databaseID),
+	}
+	return doRequest(ctx, ns, call, func() (*types.Database, error) {
+		result := ns.client.Registry.Databases().Get(ctx, databaseID)
+		if result.IsError() {
+			return nil, result.Error
+		}
+		return &result.Data, nil
+	})
+}
@@ -0,0 +1,45 @@
+package notion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestRootScopedReadDoesNotSearch(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	root := workspace.AddPage(newWorkspacePage("Root"))
+	child := newWorkspacePage("Child")
+	child.Parent = &types.Parent{Type: types.ParentTypePage, PageID: &root.ID}
+	workspace.AddPage(child)
+	workspace.AddBlocks(string(root.ID), &types.Block{
+		ID:        types.BlockID(child.ID),
+		Type:      types.BlockTypeChildPage,
+		ChildPage: &types.ChildPageBlock{Title: "Child"},
+	})
+	database := workspace.AddDatabase(newWorkspaceDatabase("Posts"))
+	row := workspace.AddPage(newRow(database, "Row", true))
+
+	// Neither is below a root.
+	workspace.AddPage(newWorkspacePage("Elsewhere"))
+	workspace.AddDatabase(newWorkspaceDatabase("Elsewhere"))
+
+	config := testConfig()
+	config.RootPageIDs = []string{string(root.ID)}
+	config.RootDatabaseIDs = []string{string(database.ID)}
+	ns, server := newTestPlugin(t, workspace, config)
+
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeCollection, common.ObjectTypeBlock)
+
+	assertEmittedOnce(t, "pages", itemIDs(items, common.ObjectTypePage), []string{string(root.ID), string(child.ID), string(row.ID)})
+	assertEmittedOnce(t, "databases", itemIDs(items, common.ObjectTypeCollection), []string{string(database.ID)})
+	if n := countRequests(server, "POST /v1/search"); n != 0 {
+		t.Errorf("searched %d times, want no searches for a root-scoped read", n)
+	}
+	if errs := ns.LastErrorSummary().Errors; len(errs) != 0 {
+		t.Errorf("errors = %v, want none", errs)
+	}
+}
@@ -0,0 +1,168 @@
+package notion
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// maxRunSummaries is how many run summaries a plugin keeps.
+const maxRunSummaries = 100
+
+// RunSummary describes a finished read. Summaries are copied when retrieved,
+// so they never change once the read has ended.
+type RunSummary struct {
+	// RunID is the run ID of the read, set with WithRunID or generated.
+	RunID string `json:"run_id"`
+	// Completed is false if the read was cancelled before it finished.
+	Completed bool `json:"completed"`
+	// Metrics holds the counters of the read alone.
+	Metrics NotionSourceMetrics `json:"metrics"`
+	// Errors lists the errors of the read.
+	Errors *ErrorSummary `json:"errors"`
+	// Cursors holds the incremental high-water marks reached by the read, by
+	// checkpoint key. Empty unless NotionSourceConfig.Incremental is set.
+	Cursors map[string]time.Time `json:"cursors,omitempty"`
+	// ResumeCursors holds the start cursors of the listings an interrupted
+	// read stopped in, by listing key. Empty unless the read is resumable, see
+	// NotionSourceConfig.CrawlState.
+	ResumeCursors map[string]string `json:"resume_cursors,omitempty"`
+}
+
+// clone returns a copy of the summary that shares nothing mutable with it.
+//
+// Returns:
+// - The copy.
+func (s *RunSummary) clone() *RunSummary {
+	clone := *s
+	if s.Metrics.EndTime != nil {
+		end := *s.Metrics.EndTime
+		clone.Metrics.EndTime = &end
+	}
+	if s.Errors != nil {
+		errors := *s.Errors
+		errors.Errors = append([]*ReadError(nil), s.Errors.Errors...)
+		clone.Errors = &errors
+	}
+	if s.Cursors != nil {
+		clone.Cursors = make(map[string]time.Time, len(s.Cursors))
+		for key, mark := range s.Cursors {
+			clone.Cursors[key] = mark
+		}
+	}
+	if s.ResumeCursors != nil {
+		clone.ResumeCursors = make(map[string]string, len(s.ResumeCursors))
+		for key, cursor := range s.ResumeCursors {
+			clone.ResumeCursors[key] = cursor
+		}
+	}
+	return &clone
+}
+
+// newRunID generates a run ID for a read started without one.
+//
+// Returns:
+// - A random run ID.
+func newRunID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// finishRun records the summary of a read that ended and folds its duration
+// into the lifetime metrics.
+//
+// Arguments:
+// - ctx: The context of the read.
+// - scope: The stages run for the read.
+// - run: The metrics of the read.
+func (ns *Plugin) finishRun(ctx context.Context, scope readScope, run *metricsRecorder) {
+	now := time.Now()
+	run.update(func(m *NotionSourceMetrics) {
+		m.Runs = 1
+		m.EndTime = &now
+		m.TotalDuration = now.Sub(m.StartTime)
+	})
+	metrics := run.snapshot()
+
+	ns.metrics.update(func(m *NotionSourceMetrics) {
+		m.Runs++
+		m.TotalDuration += metrics.TotalDuration
+		m.EndTime = &now
+	})
+
+	summary := &RunSummary{
+		RunID:     RunIDFromContext(ctx),
+		Completed: ctx.Err() == nil,
+		Metrics:   metrics,
+		Errors:    ns.finishErrors(ctx, scope.errors),
+	}
+	if scope.marks != nil {
+		summary.Cursors = scope.marks.checkpoint().Marks
+	}
+	if !summary.Completed {
+		summary.ResumeCursors = scope.progress.cursors()
+	}
+
+	ns.mu.Lock()
+	if _, ok := ns.runs[summary.RunID]; !ok {
+		ns.runOrder = append(ns.runOrder, summary.RunID)
+	}
+	ns.runs[summary.RunID] = summary
+	for len(ns.runOrder) > maxRunSummaries {
+		delete(ns.runs, ns.runOrder[0])
+		ns.runOrder = ns.runOrder[1:]
+	}
+	ns.lastRun = summary
+	ns.mu.Unlock()
+
+	multilog.Info("notion.Read", "read finished", map[string]interface{}{
+		"run_id":    summary.RunID,
+		"completed": summary.Completed,
+		"objects":   metrics.ObjectsRead,
+		"requests":  metrics.RequestsMade,
+		"errors":    metrics.ErrorsEncountered,
+		"duration":  metrics.TotalDuration.String(),
+	})
+}
+
+// LastRunSummary returns the summary of the most recently finished read.
+//
+// The summary is recorded before the read's channel is closed, so it is
+// available as soon as the channel has been drained.
+//
+// Returns:
+// - The run summary, or nil if no read has finished yet.
+func (ns *Plugin) LastRunSummary() *RunSummary {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	if ns.lastRun == nil {
+		return nil
+	}
+	return ns.lastRun.clone()
+}
+
+// RunSummary returns the summary of a finished read. Only the summaries of
+// the most recent reads are kept.
+//
+// Arguments:
+// - runID: The run ID of the read.
+//
+// Returns:
+// - The run summary, or nil if the read is unknown or still running.
+func (ns *Plugin) RunSummary(runID string) *RunSummary {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	summary, ok := ns.runs[runID]
+	if !ok {
+		return nil
+	}
+	return summary.clone()
+}
@@ -0,0 +1,98 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+)
+
+func TestRunSummariesCountEachRead(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	first := workspace.AddPage(newWorkspacePage("First"))
+	workspace.AddBlocks(string(first.ID), newParagraph("one"), newParagraph("two"))
+
+	ns, _ := newTestPlugin(t, workspace, testConfig())
+
+	readAll(t, WithRunID(context.Background(), "first"), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+	workspace.AddPage(newWorkspacePage("Second"))
+	readAll(t, WithRunID(context.Background(), "second"), ns, common.ObjectTypePage)
+
+	firstRun := ns.RunSummary("first")
+	if firstRun == nil || !firstRun.Completed {
+		t.Fatalf("first run = %+v, want a completed run", firstRun)
+	}
+	if m := firstRun.Metrics; m.Runs != 1 || m.PagesRead != 1 || m.BlocksRead != 2 || m.ObjectsRead != 3 || m.RequestsMade != 2 {
+		t.Errorf("first run metrics = %+v, want 1 page, 2 blocks and 2 requests", m)
+	}
+
+	secondRun := ns.RunSummary("second")
+	if m := secondRun.Metrics; m.PagesRead != 2 || m.BlocksRead != 0 || m.RequestsMade != 1 {
+		t.Errorf("second run metrics = %+v, want 2 pages and 1 request", m)
+	}
+	if m := secondRun.Metrics; m.EndTime == nil || m.TotalDuration != m.EndTime.Sub(m.StartTime) {
+		t.Errorf("second run times = %+v, want the duration of the run", m)
+	}
+	if last := ns.LastRunSummary(); last.RunID != "second" {
+		t.Errorf("last run = %s, want second", last.RunID)
+	}
+
+	lifetime := ns.GetMetrics()
+	if lifetime.Runs != 2 || lifetime.PagesRead != 3 || lifetime.BlocksRead != 2 || lifetime.RequestsMade != 3 {
+		t.Errorf("lifetime metrics = %+v, want the sum of both runs", lifetime)
+	}
+	if lifetime.TotalDuration != firstRun.Metrics.TotalDuration+secondRun.Metrics.TotalDuration {
+		t.Errorf("lifetime duration = %s, want the sum of both runs", lifetime.TotalDuration)
+	}
+}
+
+func TestRunSummariesAreCopies(t *testing.T) {
+	ns, server := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+	server.Fail(1, http.StatusBadRequest, notiontest.CodeValidationError, "injected")
+
+	readAll(t, WithRunID(context.Background(), "run"), ns, common.ObjectTypePage)
+
+	summary := ns.RunSummary("run")
+	if len(summary.Errors.Errors) != 1 {
+		t.Fatalf("errors = %v, want 1", summary.Errors.Errors)
+	}
+	summary.Errors.Errors[0] = nil
+	summary.Errors.Errors = nil
+	summary.Metrics.PagesRead = 100
+
+	again := ns.RunSummary("run")
+	if len(again.Errors.Errors) != 1 || again.Errors.Errors[0] == nil || again.Metrics.PagesRead != 0 {
+		t.Errorf("summary = %+v, changed through a copy", again)
+	}
+}
+
+func TestRunSummariesAreBounded(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+
+	for i := 0; i <= maxRunSummaries; i++ {
+		ctx := WithRunID(context.Background(), fmt.Sprintf("run-%d", i))
+		ns.finishRun(ctx, readScope{errors: newErrorLog(ctx)}, newMetricsRecorder())
+	}
+
+	if ns.RunSummary("run-0") != nil {
+		t.Error("the oldest summary was kept")
+	}
+	if ns.RunSummary("run-1") == nil || ns.RunSummary(fmt.Sprintf("run-%d", maxRunSummaries)) == nil {
+		t.Error("a recent summary was dropped")
+	}
+}
+
+func TestCancelledRunIsNotCompleted(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+
+	ctx, cancel := context.WithCancel(WithRunID(context.Background(), "run"))
+	cancel()
+	ns.finishRun(ctx, readScope{errors: newErrorLog(ctx)}, newMetricsRecorder())
+
+	if summary := ns.RunSummary("run"); summary == nil || summary.Completed {
+		t.Errorf("summary = %+v, want an interrupted run", summary)
+	}
+}
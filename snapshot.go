@@ -0,0 +1,611 @@
+package notion
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// SnapshotManifestFile is the name of the manifest in a snapshot directory.
+// It is written last, so a directory without one holds an unfinished
+// snapshot.
+const SnapshotManifestFile = "manifest.json"
+
+// SnapshotDeletions is the key of the tombstones in SnapshotManifest.Files
+// and SnapshotManifest.Counts, see NotionSourceConfig.DetectDeletions.
+const SnapshotDeletions = "deletions"
+
+// snapshotVersion is the version of the snapshot format.
+const snapshotVersion = 1
+
+// snapshotBufferSize is the buffer size of the channel of a snapshot replay.
+const snapshotBufferSize = 100
+
+// SnapshotManifest describes a snapshot written by Plugin.Snapshot.
+type SnapshotManifest struct {
+	// Version is the version of the snapshot format.
+	Version int `json:"version"`
+	// RunID is the run ID of the read the snapshot was taken from.
+	RunID string `json:"run_id"`
+	// StartedAt is when the read started.
+	StartedAt time.Time `json:"started_at"`
+	// FinishedAt is when the read ended.
+	FinishedAt time.Time `json:"finished_at"`
+	// Completed is false if the read was cancelled before it finished.
+	Completed bool `json:"completed"`
+	// Types are the object types requested from the read.
+	Types []common.ObjectType `json:"types"`
+	// Files maps object types, and SnapshotDeletions, to the name of the JSONL
+	// file holding their items.
+	Files map[string]string `json:"files"`
+	// Counts holds the number of items in each file, by the same keys.
+	Counts map[string]int `json:"counts"`
+	// Cursors holds the incremental high-water marks reached by the read, see
+	// RunSummary.Cursors.
+	Cursors map[string]time.Time `json:"cursors,omitempty"`
+	// ResumeCursors holds the listings an interrupted read stopped in, see
+	// RunSummary.ResumeCursors.
+	ResumeCursors map[string]string `json:"resume_cursors,omitempty"`
+	// Errors is the number of errors the read encountered.
+	Errors int `json:"errors"`
+}
+
+// snapshotRecord is a line of a snapshot file.
+type snapshotRecord struct {
+	// Seq orders items across files in the order the read emitted them.
+	Seq      int64               `json:"seq"`
+	ID       string              `json:"id"`
+	Type     common.ObjectType   `json:"type"`
+	Data     json.RawMessage     `json:"data"`
+	Metadata engine.ItemMetadata `json:"metadata"`
+}
+
+// Snapshot reads the workspace and writes every item to a directory, one JSONL
+// file per object type plus a manifest, for SnapshotSource to replay.
+//
+// Snapshots are taken with a regular read, so the read is configured, scoped
+// and summarized like any other. Existing snapshot files in the directory are
+// replaced. Snapshots cannot be taken with NotionSourceConfig.Watch, since the
+// read would never end.
+//
+// Arguments:
+// - ctx: The context for the read.
+// - req: The read request.
+// - dir: The directory to write the snapshot to, created if missing.
+//
+// Returns:
+// - The manifest of the snapshot.
+// - An error if the snapshot could not be written or the read was cancelled,
+// in which case the manifest is still written with Completed unset.
+func (ns *Plugin) Snapshot(ctx context.Context, req *engine.ReadRequest, dir string) (*SnapshotManifest, error) {
+	if ns.config.Watch {
+		return nil, fmt.Errorf("snapshots cannot be taken by watching reads")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory %s: %w", dir, err)
+	}
+	// A manifest left behind would describe the files being replaced.
+	if err := os.Remove(filepath.Join(dir, SnapshotManifestFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove previous snapshot manifest: %w", err)
+	}
+
+	if RunIDFromContext(ctx) == "" {
+		ctx = WithRunID(ctx, newRunID())
+	}
+	runID := RunIDFromContext(ctx)
+
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	started := time.Now()
+	items, err := ns.Read(readCtx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := newSnapshotWriter(dir)
+	var writeErr error
+	for item := range items {
+		if writeErr != nil {
+			// The read is cancelled, its remaining items are drained.
+			continue
+		}
+		if writeErr = writer.write(&item); writeErr != nil {
+			cancel()
+		}
+	}
+	if err := writer.close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
+	manifest := &SnapshotManifest{
+		Version:    snapshotVersion,
+		RunID:      runID,
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+		Types:      req.Types,
+		Files:      writer.files(),
+		Counts:     writer.counts,
+	}
+	if summary := ns.RunSummary(runID); summary != nil {
+		manifest.Completed = summary.Completed
+		manifest.Cursors = summary.Cursors
+		manifest.ResumeCursors = summary.ResumeCursors
+		if summary.Errors != nil {
+			manifest.Errors = len(summary.Errors.Errors)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot manifest: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, SnapshotManifestFile), data); err != nil {
+		return nil, err
+	}
+
+	multilog.Info("notion.Snapshot", "snapshot written", map[string]interface{}{
+		"run_id":    runID,
+		"dir":       dir,
+		"completed": manifest.Completed,
+		"counts":    manifest.Counts,
+	})
+
+	if err := ctx.Err(); err != nil {
+		return manifest, fmt.Errorf("snapshot of run %s is incomplete: %w", runID, err)
+	}
+	return manifest, nil
+}
+
+// snapshotWriter writes the items of a read to snapshot files.
+type snapshotWriter struct {
+	dir    string
+	seq    int64
+	open   map[string]*snapshotFile
+	counts map[string]int
+}
+
+// snapshotFile is an open snapshot file.
+type snapshotFile struct {
+	name    string
+	file    *os.File
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+// newSnapshotWriter creates a writer for a snapshot directory.
+//
+// Arguments:
+// - dir: The snapshot directory.
+//
+// Returns:
+// - A new writer.
+func newSnapshotWriter(dir string) *snapshotWriter {
+	return &snapshotWriter{
+		dir:    dir,
+		open:   make(map[string]*snapshotFile),
+		counts: make(map[string]int),
+	}
+}
+
+// write appends an item to the file of its type, creating the file on first
+// use.
+//
+// Arguments:
+// - item: The item.
+//
+// Returns:
+// - An error if the item could not be encoded or written.
+func (w *snapshotWriter) write(item *engine.DataItemContainer[any]) error {
+	key := snapshotKey(item)
+	f, ok := w.open[key]
+	if !ok {
+		name := key + ".jsonl"
+		file, err := os.Create(filepath.Join(w.dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to create snapshot file %s: %w", name, err)
+		}
+		buf := bufio.NewWriter(file)
+		f = &snapshotFile{name: name, file: file, buf: buf, encoder: json.NewEncoder(buf)}
+		w.open[key] = f
+	}
+
+	data, err := json.Marshal(item.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s %s: %w", item.Type, item.ID, err)
+	}
+
+	w.seq++
+	record := snapshotRecord{
+		Seq:      w.seq,
+		ID:       item.ID,
+		Type:     item.Type,
+		Data:     data,
+		Metadata: item.Metadata,
+	}
+	if err := f.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write %s %s to %s: %w", item.Type, item.ID, f.name, err)
+	}
+
+	w.counts[key]++
+	return nil
+}
+
+// close flushes and closes the snapshot files.
+//
+// Returns:
+// - An error if a file could not be flushed or closed.
+func (w *snapshotWriter) close() error {
+	var errs []error
+	for _, f := range w.open {
+		if err := f.buf.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s: %w", f.name, err))
+		}
+		if err := f.file.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", f.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// files returns the names of the files written, by key.
+//
+// Returns:
+// - The file names.
+func (w *snapshotWriter) files() map[string]string {
+	files := make(map[string]string, len(w.open))
+	for key, f := range w.open {
+		files[key] = f.name
+	}
+	return files
+}
+
+// snapshotKey returns the file key of an item.
+//
+// Arguments:
+// - item: The item.
+//
+// Returns:
+// - The object type of the item, or SnapshotDeletions for tombstones.
+func snapshotKey(item *engine.DataItemContainer[any]) string {
+	if _, ok := item.Data.(*Tombstone); ok {
+		return SnapshotDeletions
+	}
+	return string(item.Type)
+}
+
+// SnapshotSource replays a snapshot written by Plugin.Snapshot as a data
+// source, emitting the items in the order the original read emitted them.
+//
+// Items are decoded into the same types Plugin.Read emits. Metadata
+// properties go through JSON, so structured values such as the "relations"
+// edges come back as generic maps and slices.
+type SnapshotSource struct {
+	dir      string
+	manifest *SnapshotManifest
+	active   activeRuns
+}
+
+// OpenSnapshot opens a snapshot directory for replay.
+//
+// Arguments:
+// - dir: The snapshot directory.
+//
+// Returns:
+// - The snapshot source.
+// - An error if the directory has no readable manifest.
+func OpenSnapshot(dir string) (*SnapshotSource, error) {
+	path := filepath.Join(dir, SnapshotManifestFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot manifest %s: %w", path, err)
+	}
+
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot manifest %s: %w", path, err)
+	}
+	if manifest.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d in %s", manifest.Version, path)
+	}
+
+	return &SnapshotSource{dir: dir, manifest: &manifest}, nil
+}
+
+// Manifest returns the manifest of the snapshot.
+//
+// Returns:
+// - The manifest.
+func (s *SnapshotSource) Manifest() SnapshotManifest {
+	return *s.manifest
+}
+
+// Read implements DataSource.Read, replaying the items of the requested types.
+// Tombstones are replayed with the items of their object's type.
+func (s *SnapshotSource) Read(ctx context.Context, req *engine.ReadRequest) (<-chan engine.DataItemContainer[any], error) {
+	if err := s.Validate(req); err != nil {
+		return nil, fmt.Errorf("invalid read request: %w", err)
+	}
+
+	wanted := make(map[common.ObjectType]bool, len(req.Types))
+	for _, objType := range req.Types {
+		wanted[objType] = true
+	}
+
+	var readers []*snapshotReader
+	closeAll := func() {
+		for _, r := range readers {
+			r.file.Close()
+		}
+	}
+	for _, key := range s.keys() {
+		if key != SnapshotDeletions && !wanted[common.ObjectType(key)] {
+			continue
+		}
+		r, err := openSnapshotReader(s.dir, key, s.manifest.Files[key])
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		readers = append(readers, r)
+	}
+
+	ctx, done, err := s.active.start(ctx)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	results := make(chan engine.DataItemContainer[any], snapshotBufferSize)
+	go func() {
+		defer done()
+		defer close(results)
+		defer closeAll()
+
+		for {
+			next := nextSnapshotReader(readers)
+			if next == nil {
+				return
+			}
+
+			record := next.head
+			if err := next.advance(); err != nil {
+				// The rest of the file is skipped, the other files still
+				// replay.
+				multilog.Error("notion.SnapshotSource", "failed to read snapshot file", map[string]interface{}{
+					"file":  next.name,
+					"error": err.Error(),
+				})
+			}
+
+			item, err := record.item(next.key)
+			if err != nil {
+				multilog.Error("notion.SnapshotSource", "failed to replay snapshot item", map[string]interface{}{
+					"file":  next.name,
+					"error": err.Error(),
+				})
+				continue
+			}
+			if next.key == SnapshotDeletions && !wanted[item.Type] {
+				continue
+			}
+
+			select {
+			case results <- *item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// Validate implements DataSource.Validate.
+func (s *SnapshotSource) Validate(req *engine.ReadRequest) error {
+	for _, objType := range req.Types {
+		if !s.SupportsType(objType) {
+			return fmt.Errorf("object type %s is not in the snapshot", objType)
+		}
+	}
+	return nil
+}
+
+// SupportsType implements DataSource.SupportsType. Only the types requested
+// when the snapshot was taken are supported.
+func (s *SnapshotSource) SupportsType(objType common.ObjectType) bool {
+	for _, t := range s.manifest.Types {
+		if t == objType {
+			return true
+		}
+	}
+	return false
+}
+
+// Config implements DataSource.Config.
+func (s *SnapshotSource) Config() engine.SourceConfig {
+	return engine.SourceConfig{
+		Type: "notion-snapshot",
+		Name: "Notion Snapshot Source",
+		Properties: map[string]interface{}{
+			"dir":         s.dir,
+			"run_id":      s.manifest.RunID,
+			"started_at":  s.manifest.StartedAt,
+			"finished_at": s.manifest.FinishedAt,
+			"completed":   s.manifest.Completed,
+			"counts":      s.manifest.Counts,
+		},
+	}
+}
+
+// Close implements DataSource.Close, cancelling running replays and waiting
+// for them to exit.
+func (s *SnapshotSource) Close() error {
+	s.active.shutdown()
+	s.active.wg.Wait()
+	return nil
+}
+
+// keys returns the file keys of the snapshot in a stable order.
+//
+// Returns:
+// - The keys.
+func (s *SnapshotSource) keys() []string {
+	keys := make([]string, 0, len(s.manifest.Files))
+	for key := range s.manifest.Files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// snapshotReader reads the records of a snapshot file one at a time.
+type snapshotReader struct {
+	key     string
+	name    string
+	file    *os.File
+	decoder *json.Decoder
+	// head is the next record, or nil once the file is exhausted.
+	head *snapshotRecord
+}
+
+// openSnapshotReader opens a snapshot file and reads its first record.
+//
+// Arguments:
+// - dir: The snapshot directory.
+// - key: The file key.
+// - name: The file name.
+//
+// Returns:
+// - The reader.
+// - An error if the file could not be opened or read.
+func openSnapshotReader(dir, key, name string) (*snapshotReader, error) {
+	file, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot file %s: %w", name, err)
+	}
+
+	r := &snapshotReader{
+		key:     key,
+		name:    name,
+		file:    file,
+		decoder: json.NewDecoder(bufio.NewReader(file)),
+	}
+	if err := r.advance(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// advance reads the next record into head.
+//
+// Returns:
+// - An error if the record could not be decoded.
+func (r *snapshotReader) advance() error {
+	var record snapshotRecord
+	err := r.decoder.Decode(&record)
+	if errors.Is(err, io.EOF) {
+		r.head = nil
+		return nil
+	}
+	if err != nil {
+		r.head = nil
+		return fmt.Errorf("failed to read snapshot file %s: %w", r.name, err)
+	}
+	r.head = &record
+	return nil
+}
+
+// item decodes a record into the item it was written from.
+//
+// Arguments:
+// - key: The file key of the record.
+//
+// Returns:
+// - The item.
+// - An error if the data could not be decoded.
+func (r *snapshotRecord) item(key string) (*engine.DataItemContainer[any], error) {
+	data, err := decodeSnapshotData(key, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s %s: %w", r.Type, r.ID, err)
+	}
+
+	return &engine.DataItemContainer[any]{
+		ID:       r.ID,
+		Type:     r.Type,
+		Data:     data,
+		Metadata: r.Metadata,
+	}, nil
+}
+
+// nextSnapshotReader returns the reader whose head record was emitted first.
+//
+// Arguments:
+// - readers: The readers.
+//
+// Returns:
+// - The reader, or nil once every file is exhausted.
+func nextSnapshotReader(readers []*snapshotReader) *snapshotReader {
+	var next *snapshotReader
+	for _, r := range readers {
+		if r.head != nil && (next == nil || r.head.Seq < next.head.Seq) {
+			next = r
+		}
+	}
+	return next
+}
+
+// decodeSnapshotData decodes the data of a record into the type Plugin.Read
+// emits for it.
+//
+// Arguments:
+// - key: The file key of the record.
+// - record: The record.
+//
+// Returns:
+// - The decoded data.
+// - An error if the data could not be decoded.
+func decodeSnapshotData(key string, record *snapshotRecord) (any, error) {
+	var data any
+	switch {
+	case key == SnapshotDeletions:
+		data = &Tombstone{}
+	case record.Type == common.ObjectTypePage:
+		data = &types.Page{}
+	case record.Type == common.ObjectTypeCollection:
+		data = &types.Database{}
+	case record.Type == common.ObjectTypeBlock:
+		data = &types.Block{}
+	case record.Type == common.ObjectTypeComment:
+		data = &types.Comment{}
+	case record.Type == common.ObjectTypeUser:
+		data = &types.User{}
+	default:
+		var raw map[string]any
+		if err := json.Unmarshal(record.Data, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+
+	if err := json.Unmarshal(record.Data, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
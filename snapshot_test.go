@@ -0,0 +1,156 @@
+package notion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	var pages, blocks []string
+	parents := make(map[string]string)
+	for _, title := range []string{"First", "Second"} {
+		page := workspace.AddPage(newWorkspacePage(title))
+		pages = append(pages, string(page.ID))
+		for _, block := range workspace.AddBlocks(string(page.ID), newParagraph(title+" one"), newParagraph(title+" two")) {
+			blocks = append(blocks, string(block.ID))
+			parents[string(block.ID)] = string(page.ID)
+		}
+	}
+	ns, _ := newTestPlugin(t, workspace, testConfig())
+
+	dir := t.TempDir()
+	req := &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage, common.ObjectTypeBlock}}
+	manifest, err := ns.Snapshot(WithRunID(context.Background(), "run"), req, dir)
+	if err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+	if !manifest.Completed || manifest.RunID != "run" || manifest.Errors != 0 {
+		t.Errorf("manifest = %+v, want a completed run", manifest)
+	}
+	if manifest.Counts[string(common.ObjectTypePage)] != 2 || manifest.Counts[string(common.ObjectTypeBlock)] != 4 {
+		t.Errorf("counts = %v, want 2 pages and 4 blocks", manifest.Counts)
+	}
+
+	source, err := OpenSnapshot(dir)
+	if err != nil {
+		t.Fatalf("failed to open snapshot: %v", err)
+	}
+	defer source.Close()
+	items := readSnapshot(t, source, req)
+
+	assertEmittedOnce(t, "page", itemIDs(items, common.ObjectTypePage), pages)
+	assertEmittedOnce(t, "block", itemIDs(items, common.ObjectTypeBlock), blocks)
+	seen := make(map[string]bool)
+	for _, item := range items {
+		switch data := item.Data.(type) {
+		case *types.Page:
+			if string(data.ID) != item.ID {
+				t.Errorf("page data = %s, want %s", data.ID, item.ID)
+			}
+		case *types.Block:
+			// Items replay in the order they were read, across files.
+			if !seen[parents[item.ID]] {
+				t.Errorf("block %s replayed before its page", item.ID)
+			}
+		default:
+			t.Errorf("%s %s replayed as %T", item.Type, item.ID, item.Data)
+		}
+		seen[item.ID] = true
+	}
+
+	pagesOnly := readSnapshot(t, source, &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}})
+	if len(pagesOnly) != 2 || len(itemIDs(pagesOnly, common.ObjectTypePage)) != 2 {
+		t.Errorf("replayed %d items, want only the pages", len(pagesOnly))
+	}
+	if _, err := source.Read(context.Background(), &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypeComment}}); err == nil {
+		t.Error("replaying a type missing from the snapshot succeeded")
+	}
+}
+
+func TestSnapshotReplaysTombstones(t *testing.T) {
+	config := testConfig()
+	config.DetectDeletions = true
+	config.Checkpoints = NewMemoryCheckpointStore()
+
+	before := notiontest.NewWorkspace()
+	kept := before.AddPage(newWorkspacePage("Kept"))
+	deleted := before.AddPage(newWorkspacePage("Deleted"))
+	ns, _ := newTestPlugin(t, before, config)
+	readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	after := notiontest.NewWorkspace()
+	after.AddPage(kept)
+	ns, _ = newTestPlugin(t, after, config)
+	dir := t.TempDir()
+	req := &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}}
+	manifest, err := ns.Snapshot(context.Background(), req, dir)
+	if err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+	if manifest.Counts[SnapshotDeletions] != 1 || manifest.Counts[string(common.ObjectTypePage)] != 1 {
+		t.Errorf("counts = %v, want a page and a tombstone", manifest.Counts)
+	}
+
+	source, err := OpenSnapshot(dir)
+	if err != nil {
+		t.Fatalf("failed to open snapshot: %v", err)
+	}
+	defer source.Close()
+
+	got := tombstones(readSnapshot(t, source, req))
+	if len(got) != 1 || got[0].ID != string(deleted.ID) || got[0].Type != common.ObjectTypePage {
+		t.Errorf("tombstones = %v, want the deleted page", got)
+	}
+}
+
+func TestOpenSnapshotRequiresManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "page.jsonl"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenSnapshot(dir); err == nil {
+		t.Error("opened an unfinished snapshot")
+	}
+}
+
+func TestSnapshotRejectsWatchingReads(t *testing.T) {
+	config := testConfig()
+	config.Watch = true
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), config)
+
+	req := &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}}
+	if _, err := ns.Snapshot(context.Background(), req, t.TempDir()); err == nil {
+		t.Error("took a snapshot of a read that never ends")
+	}
+}
+
+// readSnapshot replays a snapshot and collects its items.
+//
+// Arguments:
+// - t: The test.
+// - source: The snapshot source.
+// - req: The read request.
+//
+// Returns:
+// - The items in replay order.
+func readSnapshot(t *testing.T, source *SnapshotSource, req *engine.ReadRequest) []engine.DataItemContainer[any] {
+	t.Helper()
+
+	results, err := source.Read(context.Background(), req)
+	if err != nil {
+		t.Fatalf("failed to replay snapshot: %v", err)
+	}
+	var items []engine.DataItemContainer[any]
+	for item := range results {
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,226 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cmskitdev/engine"
+	"github.com/mateothegreat/go-multilog/multilog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the plugin's meter and tracer.
+const instrumentationName = "github.com/cmskitdev/notion"
+
+// Attribute keys set on the plugin's metrics and spans.
+const (
+	attrObjectType = attribute.Key("notion.object_type")
+	attrObjectID   = attribute.Key("notion.object_id")
+	attrDatabaseID = attribute.Key("notion.database_id")
+	attrOperation  = attribute.Key("notion.operation")
+	attrStatusCode = attribute.Key("http.response.status_code")
+	attrRunID      = attribute.Key("notion.run_id")
+)
+
+// telemetry exports the plugin's metrics and traces through OpenTelemetry.
+//
+// The meter and tracer providers set in NotionSourceConfig are used, or the
+// global ones when unset, so nothing is recorded until the application
+// installs an SDK.
+type telemetry struct {
+	tracer trace.Tracer
+
+	objects   metric.Int64Counter
+	requests  metric.Int64Counter
+	errors    metric.Int64Counter
+	throttles metric.Int64Counter
+	latency   metric.Float64Histogram
+}
+
+// newTelemetry creates the instruments of a plugin.
+//
+// Arguments:
+// - config: The source configuration.
+//
+// Returns:
+// - The telemetry of the plugin.
+func newTelemetry(config NotionSourceConfig) *telemetry {
+	meterProvider := config.MeterProvider
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+	tracerProvider := config.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
+	meter := meterProvider.Meter(instrumentationName)
+	t := &telemetry{
+		tracer: tracerProvider.Tracer(instrumentationName),
+	}
+
+	// Instruments that fail to register are still usable no-ops, so an error
+	// only loses the affected metric.
+	var errs []error
+	var err error
+	t.objects, err = meter.Int64Counter("notion.objects",
+		metric.WithDescription("Objects emitted by reads."),
+		metric.WithUnit("{object}"))
+	errs = append(errs, err)
+	t.requests, err = meter.Int64Counter("notion.requests",
+		metric.WithDescription("Requests made by the plugin to the Notion API, not counting the client's own retries."),
+		metric.WithUnit("{request}"))
+	errs = append(errs, err)
+	t.errors, err = meter.Int64Counter("notion.errors",
+		metric.WithDescription("Requests to the Notion API that failed after retries."),
+		metric.WithUnit("{error}"))
+	errs = append(errs, err)
+	t.throttles, err = meter.Int64Counter("notion.throttles",
+		metric.WithDescription("Requests still rate limited by the Notion API after the client's own retries."),
+		metric.WithUnit("{request}"))
+	errs = append(errs, err)
+	t.latency, err = meter.Float64Histogram("notion.request.duration",
+		metric.WithDescription("Duration of requests to the Notion API."),
+		metric.WithUnit("s"))
+	errs = append(errs, err)
+
+	if err := errors.Join(errs...); err != nil {
+		multilog.Warn("notion.telemetry", "failed to register instruments", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	return t
+}
+
+// callAttributes returns the attributes describing an API call.
+//
+// Arguments:
+// - call: The API call.
+//
+// Returns:
+// - The attributes.
+func callAttributes(call apiCall) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attrOperation.String(call.operation)}
+	if objectType := fmt.Sprint(call.objectType); objectType != "" {
+		attrs = append(attrs, attrObjectType.String(objectType))
+	}
+	if call.databaseID != "" {
+		attrs = append(attrs, attrDatabaseID.String(call.databaseID))
+	}
+	return attrs
+}
+
+// startCall starts the span of an API call, covering its retries.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - call: The API call.
+//
+// Returns:
+// - The context carrying the span.
+// - The span.
+func (t *telemetry) startCall(ctx context.Context, call apiCall) (context.Context, trace.Span) {
+	attrs := callAttributes(call)
+	if call.objectID != "" {
+		attrs = append(attrs, attrObjectID.String(call.objectID))
+	}
+	return t.tracer.Start(ctx, "notion."+call.operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// startStage starts the span of a crawl stage.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - stage: The name of the stage.
+// - attrs: Additional attributes of the stage.
+//
+// Returns:
+// - The context carrying the span.
+// - The span.
+func (t *telemetry) startStage(ctx context.Context, stage string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attrRunID.String(RunIDFromContext(ctx)))
+	return t.tracer.Start(ctx, "notion."+stage, trace.WithAttributes(attrs...))
+}
+
+// recordAttempt records a single attempt of an API call.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - call: The API call.
+// - elapsed: How long the attempt took.
+// - err: The error of the attempt, if any.
+func (t *telemetry) recordAttempt(ctx context.Context, call apiCall, elapsed time.Duration, err error) {
+	attrs := callAttributes(call)
+	if status, _ := errorStatus(err); status != 0 {
+		attrs = append(attrs, attrStatusCode.Int(status))
+	}
+
+	set := metric.WithAttributes(attrs...)
+	t.requests.Add(ctx, 1, set)
+	t.latency.Record(ctx, elapsed.Seconds(), set)
+}
+
+// recordThrottle records a rate limited attempt of an API call.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - call: The API call.
+// - delay: How long the call is paused for.
+func (t *telemetry) recordThrottle(ctx context.Context, call apiCall, delay time.Duration) {
+	t.throttles.Add(ctx, 1, metric.WithAttributes(callAttributes(call)...))
+	trace.SpanFromContext(ctx).AddEvent("throttled", trace.WithAttributes(
+		attribute.Float64("notion.retry_after", delay.Seconds()),
+	))
+}
+
+// recordError records an API call that failed.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - call: The API call.
+// - err: The error.
+func (t *telemetry) recordError(ctx context.Context, call apiCall, err *ReadError) {
+	attrs := callAttributes(call)
+	if err.StatusCode != 0 {
+		attrs = append(attrs, attrStatusCode.Int(err.StatusCode))
+	}
+	t.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Message)
+}
+
+// recordObject records an emitted item.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - item: The emitted item.
+func (t *telemetry) recordObject(ctx context.Context, item *engine.DataItemContainer[any]) {
+	attrs := []attribute.KeyValue{attrObjectType.String(fmt.Sprint(item.Type))}
+	if databaseID, _ := item.Metadata.Properties["database_id"].(string); databaseID != "" {
+		attrs = append(attrs, attrDatabaseID.String(databaseID))
+	}
+	t.objects.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+// endSpan ends a span, marking it failed if the context was cancelled.
+//
+// Arguments:
+// - ctx: The context of the span.
+// - span: The span to end.
+func endSpan(ctx context.Context, span trace.Span) {
+	if err := ctx.Err(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
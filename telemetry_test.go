@@ -0,0 +1,129 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestReadRecordsTelemetry(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	workspace.AddPage(newWorkspacePage("Page"))
+
+	reader := sdkmetric.NewManualReader()
+	spans := tracetest.NewSpanRecorder()
+	config := testConfig()
+	config.MeterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	config.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	ns, server := newTestPlugin(t, workspace, config)
+
+	// The second 429 outlasts the client's retry and reaches the plugin.
+	server.Throttle(2, 0)
+	readAll(t, context.Background(), ns, common.ObjectTypePage)
+	// Both client attempts of the next search fail.
+	server.Fail(2, http.StatusInternalServerError, notiontest.CodeInternalServerError, "injected")
+	readAll(t, context.Background(), ns, common.ObjectTypePage)
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	metrics := ns.GetMetrics()
+	counters := map[string]int64{
+		"notion.requests":  metrics.RequestsMade,
+		"notion.throttles": 1,
+		"notion.errors":    1,
+		"notion.objects":   1,
+	}
+	for name, want := range counters {
+		if got := counterSum(data, name); got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+	// The plugin made the throttled search twice and the failed one once,
+	// the client sent each of them twice.
+	if sent := countRequests(server, "POST /v1/search"); metrics.RequestsMade != 3 || sent != 5 {
+		t.Errorf("made %d requests and sent %d, want 3 and 5", metrics.RequestsMade, sent)
+	}
+	if got := histogramCount(data, "notion.request.duration"); got != uint64(metrics.RequestsMade) {
+		t.Errorf("notion.request.duration count = %d, want %d", got, metrics.RequestsMade)
+	}
+
+	var throttled, failed bool
+	stages := make(map[string]bool)
+	for _, span := range spans.Ended() {
+		stages[span.Name()] = true
+		if span.Name() != "notion."+OperationSearch {
+			continue
+		}
+		for _, event := range span.Events() {
+			throttled = throttled || event.Name == "throttled"
+		}
+		failed = failed || span.Status().Code == codes.Error
+	}
+	for _, stage := range []string{"notion.read", "notion.search_pages"} {
+		if !stages[stage] {
+			t.Errorf("no %s span in %v", stage, stages)
+		}
+	}
+	if !throttled {
+		t.Error("the search span has no throttled event")
+	}
+	if !failed {
+		t.Error("the failed search span has no error status")
+	}
+}
+
+// counterSum returns the sum of the data points of an int64 counter.
+//
+// Arguments:
+// - data: The collected metrics.
+// - name: The name of the counter.
+//
+// Returns:
+// - The sum over all attributes.
+func counterSum(data metricdata.ResourceMetrics, name string) int64 {
+	var sum int64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if counter, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == name {
+				for _, point := range counter.DataPoints {
+					sum += point.Value
+				}
+			}
+		}
+	}
+	return sum
+}
+
+// histogramCount returns the number of values recorded by a float64
+// histogram.
+//
+// Arguments:
+// - data: The collected metrics.
+// - name: The name of the histogram.
+//
+// Returns:
+// - The count over all attributes.
+func histogramCount(data metricdata.ResourceMetrics, name string) uint64 {
+	var count uint64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if histogram, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == name {
+				for _, point := range histogram.DataPoints {
+					count += point.Count
+				}
+			}
+		}
+	}
+	return count
+}
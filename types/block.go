@@ -4,6 +4,8 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 
 	"github.com/cmskitdev/notion/id"
 )
@@ -91,6 +93,7 @@ type Block struct {
 	Type         BlockType `json:"type"`
 	HasChildren  bool      `json:"has_children"`
 	Archived     bool      `json:"archived,omitempty"`
+	InTrash      bool      `json:"in_trash,omitempty"`
 	Parent       *Parent   `json:"parent,omitempty"`
 	CreatedBy    *User     `json:"created_by,omitempty"`
 	LastEditedBy *User     `json:"last_edited_by,omitempty"`
@@ -583,6 +586,86 @@ func (b *Block) GetRichText() []RichText {
 	return nil
 }
 
+// GetChildren returns the child blocks embedded in the block's content, if any.
+//
+// Child blocks are only embedded when a block is built locally or returned with
+// its children already resolved; blocks returned by the API otherwise only set
+// HasChildren and their children must be listed separately.
+//
+// Returns:
+// - []Block: The embedded child blocks, or nil if none are embedded.
+//
+// Example:
+//
+//	for _, child := range block.GetChildren() {
+//	    fmt.Println("Child:", child.Type)
+//	}
+func (b *Block) GetChildren() []Block {
+	switch b.Type {
+	case BlockTypeParagraph:
+		if b.Paragraph != nil {
+			return b.Paragraph.Children
+		}
+	case BlockTypeHeading1:
+		if b.Heading1 != nil {
+			return b.Heading1.Children
+		}
+	case BlockTypeHeading2:
+		if b.Heading2 != nil {
+			return b.Heading2.Children
+		}
+	case BlockTypeHeading3:
+		if b.Heading3 != nil {
+			return b.Heading3.Children
+		}
+	case BlockTypeBulletedListItem:
+		if b.BulletedListItem != nil {
+			return b.BulletedListItem.Children
+		}
+	case BlockTypeNumberedListItem:
+		if b.NumberedListItem != nil {
+			return b.NumberedListItem.Children
+		}
+	case BlockTypeToDo:
+		if b.ToDo != nil {
+			return b.ToDo.Children
+		}
+	case BlockTypeToggle:
+		if b.Toggle != nil {
+			return b.Toggle.Children
+		}
+	case BlockTypeQuote:
+		if b.Quote != nil {
+			return b.Quote.Children
+		}
+	case BlockTypeCallout:
+		if b.Callout != nil {
+			return b.Callout.Children
+		}
+	case BlockTypeColumn:
+		if b.Column != nil {
+			return b.Column.Children
+		}
+	case BlockTypeColumnList:
+		if b.ColumnList != nil {
+			return b.ColumnList.Children
+		}
+	case BlockTypeSyncedBlock:
+		if b.SyncedBlock != nil {
+			return b.SyncedBlock.Children
+		}
+	case BlockTypeTemplate:
+		if b.Template != nil {
+			return b.Template.Children
+		}
+	case BlockTypeTable:
+		if b.Table != nil {
+			return b.Table.Children
+		}
+	}
+	return nil
+}
+
 // Validate ensures the Block has valid required fields based on its type.
 //
 // Returns:
@@ -722,3 +805,103 @@ func validateEquationBlock(eb *EquationBlock) error {
 	}
 	return nil
 }
+
+// BlockChildrenRequest represents a request to list the children of a block or page.
+// See https://developers.notion.com/reference/get-block-children.
+type BlockChildrenRequest struct {
+	// The ID of the block (or page) whose children are listed.
+	BlockID BlockID `json:"-"`
+	// The start cursor to use for pagination.
+	StartCursor *string `json:"start_cursor,omitempty"`
+	// The page size to use for pagination.
+	PageSize *int `json:"page_size,omitempty"`
+}
+
+func (bcr *BlockChildrenRequest) GetPath() string {
+	return "/blocks/" + string(bcr.BlockID) + "/children"
+}
+
+func (bcr *BlockChildrenRequest) GetMethod() string {
+	return "GET"
+}
+
+func (bcr *BlockChildrenRequest) GetQuery() url.Values {
+	query := url.Values{}
+	if bcr.StartCursor != nil {
+		query.Set("start_cursor", *bcr.StartCursor)
+	}
+	if bcr.PageSize != nil {
+		query.Set("page_size", strconv.Itoa(*bcr.PageSize))
+	}
+	return query
+}
+
+// BlockListResponse represents a response containing a list of blocks.
+type BlockListResponse struct {
+	Object     ObjectType `json:"object"`
+	Results    []Block    `json:"results"`
+	NextCursor *string    `json:"next_cursor"`
+	HasMore    bool       `json:"has_more"`
+	Type       string     `json:"type"`
+	Block      struct{}   `json:"block"`
+}
+
+// BlockAppendRequest represents a request to append blocks to a block or page.
+// See https://developers.notion.com/reference/patch-block-children.
+type BlockAppendRequest struct {
+	// The ID of the block (or page) the children are appended to.
+	BlockID BlockID `json:"-"`
+	// The blocks to append, at most 100.
+	Children []Block `json:"children"`
+	// The ID of the existing block to append after, or nil to append at the end.
+	After *BlockID `json:"after,omitempty"`
+}
+
+func (bar *BlockAppendRequest) GetPath() string {
+	return "/blocks/" + string(bar.BlockID) + "/children"
+}
+
+func (bar *BlockAppendRequest) GetMethod() string {
+	return "PATCH"
+}
+
+// blockReadOnlyFields are the block fields set by Notion that are rejected
+// when creating blocks.
+var blockReadOnlyFields = []string{
+	"id", "created_time", "last_edited_time", "created_by", "last_edited_by",
+	"has_children", "archived", "in_trash", "parent",
+}
+
+// MarshalJSON implements custom JSON marshaling for BlockAppendRequest.
+// Read-only fields are left out of the children, so that blocks read from one
+// page can be appended to another.
+//
+// Returns:
+// - []byte: The JSON representation of the request.
+// - error: Marshaling error if a child cannot be encoded, nil if successful.
+func (bar BlockAppendRequest) MarshalJSON() ([]byte, error) {
+	children := make([]map[string]json.RawMessage, 0, len(bar.Children))
+	for i := range bar.Children {
+		data, err := json.Marshal(&bar.Children[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode block %d: %w", i, err)
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("failed to encode block %d: %w", i, err)
+		}
+		for _, field := range blockReadOnlyFields {
+			delete(fields, field)
+		}
+		children = append(children, fields)
+	}
+
+	return json.Marshal(struct {
+		Children []map[string]json.RawMessage `json:"children"`
+		After    *BlockID                     `json:"after,omitempty"`
+	}{
+		Children: children,
+		After:    bar.After,
+	})
+}
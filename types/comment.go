@@ -3,6 +3,8 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 
 	"github.com/cmskitdev/notion/id"
 )
@@ -161,10 +163,11 @@ func (d DiscussionID) Validate() error {
 // Comments are part of discussion threads and contain rich text content.
 type Comment struct {
 	BaseObject
-	ID           CommentID      `json:"id"`
-	Parent       *CommentParent `json:"parent,omitempty"`
-	DiscussionID DiscussionID   `json:"discussion_id" validate:"uuid"`
-	RichText     []RichText     `json:"rich_text" validate:"required"`
+	ID           CommentID           `json:"id"`
+	Parent       *CommentParent      `json:"parent,omitempty"`
+	DiscussionID DiscussionID        `json:"discussion_id" validate:"uuid"`
+	RichText     []RichText          `json:"rich_text" validate:"required"`
+	Attachments  []CommentAttachment `json:"attachments,omitempty"`
 }
 
 // CommentParent represents the parent object of a comment.
@@ -417,6 +420,37 @@ func NewCommentCreateRequest(parent CommentParent, richText []RichText) *Comment
 	}
 }
 
+// CommentListRequest represents a request to list the unresolved comments on a page or block.
+// See https://developers.notion.com/reference/retrieve-a-comment.
+type CommentListRequest struct {
+	// The ID of the page or block whose comments are listed.
+	BlockID BlockID `json:"-"`
+	// The start cursor to use for pagination.
+	StartCursor *string `json:"start_cursor,omitempty"`
+	// The page size to use for pagination.
+	PageSize *int `json:"page_size,omitempty"`
+}
+
+func (clr *CommentListRequest) GetPath() string {
+	return "/comments"
+}
+
+func (clr *CommentListRequest) GetMethod() string {
+	return "GET"
+}
+
+func (clr *CommentListRequest) GetQuery() url.Values {
+	query := url.Values{}
+	query.Set("block_id", string(clr.BlockID))
+	if clr.StartCursor != nil {
+		query.Set("start_cursor", *clr.StartCursor)
+	}
+	if clr.PageSize != nil {
+		query.Set("page_size", strconv.Itoa(*clr.PageSize))
+	}
+	return query
+}
+
 // CommentListResponse represents a response containing a list of comments.
 type CommentListResponse struct {
 	Object     ObjectType `json:"object"`
@@ -372,6 +372,8 @@ type PageCreateRequest struct {
 
 // PageUpdateRequest represents a request to update an existing page.
 type PageUpdateRequest struct {
+	// The ID of the page to update.
+	PageID     PageID              `json:"-"`
 	Properties map[string]Property `json:"properties,omitempty"`
 	Archived   *bool               `json:"archived,omitempty"`
 	Icon       *Icon               `json:"icon,omitempty"`
@@ -379,6 +381,100 @@ type PageUpdateRequest struct {
 	InTrash    *bool               `json:"in_trash,omitempty"`
 }
 
+func (pcr *PageCreateRequest) GetPath() string {
+	return "/pages"
+}
+
+func (pcr *PageCreateRequest) GetMethod() string {
+	return "POST"
+}
+
+func (pur *PageUpdateRequest) GetPath() string {
+	return "/pages/" + string(pur.PageID)
+}
+
+func (pur *PageUpdateRequest) GetMethod() string {
+	return "PATCH"
+}
+
+// MarshalJSON implements custom JSON marshaling for PageCreateRequest.
+// Properties without an ID are sent without one, see requestProperties.
+//
+// Returns:
+// - []byte: The JSON representation of the request.
+// - error: Marshaling error if a property cannot be encoded, nil if successful.
+func (pcr PageCreateRequest) MarshalJSON() ([]byte, error) {
+	properties, err := requestProperties(pcr.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	type request PageCreateRequest
+	return json.Marshal(struct {
+		request
+		Properties map[string]map[string]json.RawMessage `json:"properties"`
+	}{
+		request:    request(pcr),
+		Properties: properties,
+	})
+}
+
+// MarshalJSON implements custom JSON marshaling for PageUpdateRequest.
+// Properties without an ID are sent without one, see requestProperties.
+//
+// Returns:
+// - []byte: The JSON representation of the request.
+// - error: Marshaling error if a property cannot be encoded, nil if successful.
+func (pur PageUpdateRequest) MarshalJSON() ([]byte, error) {
+	properties, err := requestProperties(pur.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	type request PageUpdateRequest
+	return json.Marshal(struct {
+		request
+		Properties map[string]map[string]json.RawMessage `json:"properties,omitempty"`
+	}{
+		request:    request(pur),
+		Properties: properties,
+	})
+}
+
+// requestProperties encodes the properties of a page request. Properties are
+// written by name, and Notion rejects the empty ID of a property built from
+// scratch, so empty IDs are left out.
+//
+// Arguments:
+// - properties: The properties by name.
+//
+// Returns:
+// - The encoded properties, nil if there are none.
+// - error: Marshaling error if a property cannot be encoded, nil if successful.
+func requestProperties(properties map[string]Property) (map[string]map[string]json.RawMessage, error) {
+	if properties == nil {
+		return nil, nil
+	}
+
+	encoded := make(map[string]map[string]json.RawMessage, len(properties))
+	for name, property := range properties {
+		data, err := json.Marshal(property)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode property %q: %w", name, err)
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("failed to encode property %q: %w", name, err)
+		}
+		if string(fields["id"]) == `""` {
+			delete(fields, "id")
+		}
+		encoded[name] = fields
+	}
+	return encoded, nil
+}
+
 // NewPageCreateRequest creates a new page creation request.
 //
 // Arguments:
@@ -39,3 +39,33 @@ type Query struct {
 	StartCursor *string      `json:"start_cursor,omitempty"`
 	PageSize    *int         `json:"page_size,omitempty"`
 }
+
+// DatabaseQueryRequest represents a query against a specific Notion database.
+// See https://developers.notion.com/reference/post-database-query.
+type DatabaseQueryRequest struct {
+	Query
+	// The ID of the database to query.
+	DatabaseID DatabaseID `json:"-"`
+}
+
+func (dqr *DatabaseQueryRequest) GetPath() string {
+	return "/databases/" + string(dqr.DatabaseID) + "/query"
+}
+
+func (dqr *DatabaseQueryRequest) GetMethod() string {
+	return "POST"
+}
+
+func (dqr *DatabaseQueryRequest) GetBody() Query {
+	return dqr.Query
+}
+
+// QueryResponse represents a page of rows returned by a database query.
+type QueryResponse struct {
+	Object     ObjectType `json:"object"`
+	Results    []Page     `json:"results"`
+	NextCursor *string    `json:"next_cursor"`
+	HasMore    bool       `json:"has_more"`
+	Type       string     `json:"type"`
+	Page       struct{}   `json:"page_or_database"`
+}
@@ -18,7 +18,7 @@ type SearchFilter struct {
 // See https://developers.notion.com/reference/post-search.
 type SearchSort struct {
 	// The name of the timestamp to sort against. Possible values include last_edited_time.
-	Timestamp string `json:"property,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
 	// The direction to sort in. Possible values include ascending and descending.
 	Direction string `json:"direction,omitempty"`
 }
@@ -54,6 +54,15 @@ func (sr *SearchRequest) GetQuery() url.Values {
 	return nil
 }
 
+// SearchResponse represents a single page of search results.
+type SearchResponse struct {
+	Object     ObjectType     `json:"object"`
+	Results    []SearchResult `json:"results"`
+	NextCursor *string        `json:"next_cursor"`
+	HasMore    bool           `json:"has_more"`
+	Type       string         `json:"type"`
+}
+
 // SearchResult represents a search result that can be either a page or database.
 type SearchResult struct {
 	BaseObject
@@ -3,6 +3,8 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 
 	"github.com/cmskitdev/notion/id"
 )
@@ -253,3 +255,41 @@ func (u *User) GetDisplayName() string {
 	}
 	return u.ID.String()
 }
+
+// UserListRequest represents a request to list all users in the workspace.
+// See https://developers.notion.com/reference/get-users.
+type UserListRequest struct {
+	// The start cursor to use for pagination.
+	StartCursor *string `json:"start_cursor,omitempty"`
+	// The page size to use for pagination.
+	PageSize *int `json:"page_size,omitempty"`
+}
+
+func (ulr *UserListRequest) GetPath() string {
+	return "/users"
+}
+
+func (ulr *UserListRequest) GetMethod() string {
+	return "GET"
+}
+
+func (ulr *UserListRequest) GetQuery() url.Values {
+	query := url.Values{}
+	if ulr.StartCursor != nil {
+		query.Set("start_cursor", *ulr.StartCursor)
+	}
+	if ulr.PageSize != nil {
+		query.Set("page_size", strconv.Itoa(*ulr.PageSize))
+	}
+	return query
+}
+
+// UserListResponse represents a response containing a list of users.
+type UserListResponse struct {
+	Object     ObjectType `json:"object"`
+	Results    []User     `json:"results"`
+	NextCursor *string    `json:"next_cursor"`
+	HasMore    bool       `json:"has_more"`
+	Type       string     `json:"type"`
+	User       struct{}   `json:"user"`
+}
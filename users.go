@@ -0,0 +1,172 @@
+package notion
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+)
+
+// userSet tracks the users already emitted during a read so that every user
+// is emitted exactly once, no matter how many objects reference it.
+type userSet struct {
+	mu   sync.Mutex
+	seen map[types.UserID]struct{}
+}
+
+// newUserSet creates an empty user set.
+//
+// Returns:
+// - A new user set.
+func newUserSet() *userSet {
+	return &userSet{
+		seen: make(map[types.UserID]struct{}),
+	}
+}
+
+// add marks a user as emitted.
+//
+// Arguments:
+// - id: The ID of the user.
+//
+// Returns:
+// - True if the user had not been seen before.
+func (s *userSet) add(id types.UserID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[id]; ok {
+		return false
+	}
+	s.seen[id] = struct{}{}
+	return true
+}
+
+// readUsers emits every user in the workspace, people and bots alike.
+//
+// This runs before the other stages so that the canonical user records from
+// /users win over the partial user references found on other objects.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled while reading, true otherwise.
+func (ns *Plugin) readUsers(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	ctx, span := ns.otel.startStage(ctx, "read_users")
+	defer endSpan(ctx, span)
+
+	fetch := func(cursor *string) (*listPage[types.User], error) {
+		page, err := ns.listUsers(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &listPage[types.User]{results: page.Results, nextCursor: page.NextCursor, hasMore: page.HasMore}, nil
+	}
+
+	return paginate(ctx, scope, "users", fetch, func(user *types.User) bool {
+		return ns.emitUser(ctx, user, scope, false, results)
+	})
+}
+
+// discoverUsers emits users referenced by other objects that have not been
+// emitted yet, such as guests or former members missing from /users.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+// - refs: The referenced users, nil entries are ignored.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) discoverUsers(ctx context.Context, scope readScope, results chan<- engine.DataItemContainer[any], refs ...*types.User) bool {
+	if scope.users == nil {
+		return true
+	}
+
+	for _, user := range refs {
+		if user == nil || user.ID == "" {
+			continue
+		}
+		if !ns.emitUser(ctx, user, scope, true, results) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// emitUser emits a user unless it has already been emitted in this read.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - user: The user to emit.
+// - scope: The stages to run for the current read.
+// - discovered: Whether the user was found as a reference on another object.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) emitUser(ctx context.Context, user *types.User, scope readScope, discovered bool, results chan<- engine.DataItemContainer[any]) bool {
+	if !scope.users.add(user.ID) {
+		return true
+	}
+	return ns.emit(ctx, scope, ns.convertUserToDataItem(user, discovered), results)
+}
+
+// pageUserRefs returns the users referenced by a page, including the people,
+// created_by and last_edited_by property values.
+//
+// Arguments:
+// - page: The page to collect references from.
+//
+// Returns:
+// - The referenced users.
+func pageUserRefs(page *types.Page) []*types.User {
+	refs := []*types.User{page.CreatedBy, page.LastEditedBy}
+	if page.PropertyContainer == nil {
+		return refs
+	}
+
+	for _, prop := range page.Properties {
+		switch prop.Type {
+		case types.PropertyTypePeople:
+			for i := range prop.People {
+				refs = append(refs, &prop.People[i])
+			}
+		case types.PropertyTypeCreatedBy:
+			refs = append(refs, prop.CreatedBy)
+		case types.PropertyTypeLastEditedBy:
+			refs = append(refs, prop.LastEditedBy)
+		}
+	}
+
+	return refs
+}
+
+// listUsers fetches a single page of workspace users.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - cursor: The cursor to resume from, or nil for the first page.
+//
+// Returns:
+// - The page of users.
+// - An error if the request failed.
+func (ns *Plugin) listUsers(ctx context.Context, cursor *string) (*types.UserListResponse, error) {
+	call := apiCall{
+		operation:  OperationListUsers,
+		objectType: common.ObjectTypeUser,
+	}
+	return doRequest(ctx, ns, call, func() (*types.UserListResponse, error) {
+		return callAPI[types.UserListResponse](ctx, ns.client.Registry, &types.UserListRequest{
+			StartCursor: cursor,
+			PageSize:    &ns.config.PageSize,
+		})
+	})
+}
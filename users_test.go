@@ -0,0 +1,42 @@
+package notion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestReadEmitsUsersReferencedAcrossPagesOnce(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	name := "Member"
+	member := workspace.AddUsers(&types.User{Type: types.UserTypePerson, Name: &name})[0]
+	// Guests are referenced by pages but missing from /users.
+	guest := types.UserID("22222222-2222-4222-8222-222222222222")
+
+	for _, refs := range [][2]types.UserID{{member.ID, guest}, {guest, member.ID}, {member.ID, member.ID}} {
+		page := newWorkspacePage("Page")
+		page.CreatedBy = &types.User{ID: refs[0]}
+		page.LastEditedBy = &types.User{ID: refs[1]}
+		workspace.AddPage(page)
+	}
+
+	config := testConfig()
+	config.IncludeUsers = true
+	ns, _ := newTestPlugin(t, workspace, config)
+	items := readAll(t, context.Background(), ns, common.ObjectTypePage, common.ObjectTypeUser)
+
+	assertEmittedOnce(t, "users", itemIDs(items, common.ObjectTypeUser), []string{string(member.ID), string(guest)})
+	for _, item := range items {
+		if item.Type != common.ObjectTypeUser {
+			continue
+		}
+		// The member is listed before the pages are read, so its canonical
+		// record wins over the references.
+		if discovered, want := item.Metadata.Properties["discovered"], item.ID == string(guest); discovered != want {
+			t.Errorf("discovered of user %s = %v, want %v", item.ID, discovered, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package notion
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// ValidationMode controls whether emitted items are validated, and what
+// happens to the items that fail.
+//
+// Pages, databases, blocks and comments are checked with the Validate method
+// of their types package type. The result is recorded in the item's
+// ValidationState, with the message of a failed check in its Errors.
+type ValidationMode string
+
+const (
+	// ValidationOff emits items without validating them. This is the
+	// default.
+	ValidationOff ValidationMode = ""
+	// ValidationFlag emits invalid items with ValidationState.IsValid unset.
+	ValidationFlag ValidationMode = "flag"
+	// ValidationDrop skips invalid items.
+	ValidationDrop ValidationMode = "drop"
+	// ValidationFail ends the read at the first invalid item, reporting a
+	// ReadError with OperationValidate.
+	ValidationFail ValidationMode = "fail"
+)
+
+// validatable is implemented by the types package types items hold.
+type validatable interface {
+	Validate() error
+}
+
+// validation validates the items of a read, see NotionSourceConfig.Validation.
+//
+// A nil *validation means items are not validated.
+type validation struct {
+	mode   ValidationMode
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	failed *ReadError
+}
+
+// newValidation creates the validation of a read.
+//
+// Arguments:
+// - config: The source configuration.
+// - cancel: Cancels the read, called when an item fails in ValidationFail
+// mode.
+//
+// Returns:
+// - The validation, or nil if items are not validated.
+func newValidation(config NotionSourceConfig, cancel context.CancelFunc) *validation {
+	if config.Validation == ValidationOff {
+		return nil
+	}
+	return &validation{mode: config.Validation, cancel: cancel}
+}
+
+// check validates an item and records the result in its metadata.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - scope: The stages to run for the current read.
+// - item: The item.
+//
+// Returns:
+// - Whether the item is emitted.
+// - False if the read must stop, true otherwise.
+func (v *validation) check(ctx context.Context, scope readScope, item *engine.DataItemContainer[any]) (bool, bool) {
+	if v == nil {
+		return true, true
+	}
+
+	data, ok := item.Data.(validatable)
+	if !ok {
+		return true, true
+	}
+	switch item.Type {
+	case common.ObjectTypePage, common.ObjectTypeCollection, common.ObjectTypeBlock, common.ObjectTypeComment:
+	default:
+		return true, true
+	}
+
+	err := data.Validate()
+	item.Metadata.ValidationState = engine.ValidationState{
+		IsValid:     err == nil,
+		ValidatedAt: time.Now(),
+	}
+	if err == nil {
+		return true, true
+	}
+
+	item.Metadata.ValidationState.Errors = []engine.ValidationError{{
+		Code:     "invalid_" + string(item.Type),
+		Message:  err.Error(),
+		Severity: engine.ValidationSeverityError,
+	}}
+
+	switch v.mode {
+	case ValidationDrop:
+		multilog.Warn("notion.Read", "dropping invalid item", map[string]interface{}{
+			"id":    item.ID,
+			"type":  item.Type,
+			"error": err.Error(),
+		})
+		return false, true
+	case ValidationFail:
+		readErr := newReadError(apiCall{operation: OperationValidate, objectID: item.ID, objectType: item.Type}, err)
+		readErr.Retryable = false
+		scope.errors.report(ctx, readErr, OperationValidate)
+
+		v.mu.Lock()
+		if v.failed == nil {
+			v.failed = readErr
+		}
+		v.mu.Unlock()
+
+		if v.cancel != nil {
+			v.cancel()
+		}
+		return false, false
+	}
+	return true, true
+}
+
+// err returns the error that ended a read in ValidationFail mode.
+//
+// Returns:
+// - The first validation error, or nil if no item failed.
+func (v *validation) err() error {
+	if v == nil {
+		return nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.failed == nil {
+		return nil
+	}
+	return v.failed
+}
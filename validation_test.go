@@ -0,0 +1,81 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+)
+
+func TestReadValidationModes(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	page := workspace.AddPage(newWorkspacePage("Page"))
+	invalid := newParagraph("invalid")
+	invalid.Paragraph = nil
+	workspace.AddBlocks(string(page.ID), invalid)
+	valid := workspace.AddBlocks(string(page.ID), newParagraph("valid"))[0]
+
+	tests := []struct {
+		mode      ValidationMode
+		blocks    []string
+		validated bool
+		failed    bool
+	}{
+		{mode: ValidationOff, blocks: []string{string(invalid.ID), string(valid.ID)}},
+		{mode: ValidationFlag, blocks: []string{string(invalid.ID), string(valid.ID)}, validated: true},
+		{mode: ValidationDrop, blocks: []string{string(valid.ID)}, validated: true},
+		{mode: ValidationFail, blocks: []string{}, validated: true, failed: true},
+	}
+
+	for _, tt := range tests {
+		name := string(tt.mode)
+		if name == "" {
+			name = "off"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			config := testConfig()
+			config.Validation = tt.mode
+			ns, _ := newTestPlugin(t, workspace, config)
+
+			items := readAll(t, WithRunID(context.Background(), "run"), ns, common.ObjectTypePage, common.ObjectTypeBlock)
+
+			assertEmittedOnce(t, "block", itemIDs(items, common.ObjectTypeBlock), tt.blocks)
+			for _, item := range items {
+				// Items are only marked valid once checked.
+				flagged := tt.validated && item.ID == string(invalid.ID)
+				if want := tt.validated && !flagged; item.Metadata.ValidationState.IsValid != want {
+					t.Errorf("%s %s valid = %v, want %v", item.Type, item.ID, item.Metadata.ValidationState.IsValid, want)
+				}
+				errs := item.Metadata.ValidationState.Errors
+				if (len(errs) == 1) != flagged || len(errs) > 1 {
+					t.Errorf("%s %s validation errors = %+v", item.Type, item.ID, errs)
+				}
+				if flagged && (errs[0].Code != "invalid_block" || errs[0].Message == "" || errs[0].Severity != engine.ValidationSeverityError) {
+					t.Errorf("validation error = %+v, want an invalid block error", errs[0])
+				}
+			}
+
+			summary := ns.RunSummary("run")
+			if !tt.failed {
+				if !summary.Completed || len(summary.Errors.Errors) != 0 {
+					t.Errorf("summary = %+v, want a completed run", summary)
+				}
+				return
+			}
+			if summary.Completed {
+				t.Error("the failed read is marked completed")
+			}
+			var readErr *ReadError
+			if len(summary.Errors.Errors) != 1 || !errors.As(summary.Errors.Errors[0], &readErr) {
+				t.Fatalf("errors = %v, want the validation error", summary.Errors.Errors)
+			}
+			if readErr.Operation != OperationValidate || readErr.ObjectID != string(invalid.ID) || readErr.Retryable {
+				t.Errorf("error = %+v, want a permanent validation error for the invalid block", readErr)
+			}
+		})
+	}
+}
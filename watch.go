@@ -0,0 +1,231 @@
+package notion
+
+import (
+	"context"
+	"time"
+
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// defaultWatchInterval is how often watching reads poll for changes when
+// NotionSourceConfig.WatchInterval is unset.
+const defaultWatchInterval = time.Minute
+
+// watchFeed tracks the changes already emitted by a watching read.
+type watchFeed struct {
+	// since is the edit time of the newest change seen so far.
+	since time.Time
+	// emitted holds the edit time emitted per object, for the objects edited
+	// at since. Notion truncates edit times to the minute, so changes made in
+	// the same minute can only be told apart by comparing them.
+	emitted map[string]time.Time
+}
+
+// newWatchFeed creates the feed of a watching read.
+//
+// Arguments:
+// - since: When the initial crawl started.
+//
+// Returns:
+// - A new feed.
+func newWatchFeed(since time.Time) *watchFeed {
+	return &watchFeed{
+		since:   since.Truncate(time.Minute),
+		emitted: make(map[string]time.Time),
+	}
+}
+
+// changed reports whether an object was edited since it was last emitted.
+//
+// Arguments:
+// - id: The ID of the object.
+// - editedAt: The object's LastEditedTime.
+//
+// Returns:
+// - Whether the object changed.
+// - False once the search reached objects older than the last poll.
+func (f *watchFeed) changed(id string, editedAt time.Time) (bool, bool) {
+	if editedAt.Before(f.since) {
+		return false, false
+	}
+	if emitted, ok := f.emitted[id]; ok && !editedAt.After(emitted) {
+		return false, true
+	}
+	f.emitted[id] = editedAt
+	return true, true
+}
+
+// advance moves the feed past the changes of a poll.
+func (f *watchFeed) advance() {
+	for _, editedAt := range f.emitted {
+		if editedAt.After(f.since) {
+			f.since = editedAt
+		}
+	}
+	for id, editedAt := range f.emitted {
+		if editedAt.Before(f.since) {
+			delete(f.emitted, id)
+		}
+	}
+}
+
+// watch polls /search for changes after the initial crawl until the context
+// is cancelled, see NotionSourceConfig.Watch.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - since: When the initial crawl started.
+// - checkpoint: The checkpoint loaded when the read started, or nil.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+func (ns *Plugin) watch(ctx context.Context, since time.Time, checkpoint *Checkpoint, scope readScope, results chan<- engine.DataItemContainer[any]) {
+	interval := ns.config.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	feed := newWatchFeed(since)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		errs := scope.errors.count()
+		if !ns.poll(ctx, feed, scope, results) {
+			return
+		}
+
+		if checkpoint != nil {
+			// The objects seen by a poll are not every object, so deletions
+			// are not diffed against them and the hashes of the objects it
+			// did not see are kept.
+			if err := ns.saveCheckpoint(ctx, checkpoint, scope, false, scope.errors.count() == errs); err != nil {
+				ns.incrementErrorCount(ctx)
+				scope.errors.report(ctx, err, OperationSaveCheckpoint)
+				multilog.Error("notion.Read", "failed to save checkpoint", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+	}
+}
+
+// poll emits the pages and databases edited since the previous poll, with the
+// blocks and comments of the changed pages.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - feed: The changes already emitted.
+// - scope: The stages to run for the current read.
+// - results: The channel to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) poll(ctx context.Context, feed *watchFeed, scope readScope, results chan<- engine.DataItemContainer[any]) bool {
+	ctx, span := ns.otel.startStage(ctx, "poll")
+	defer endSpan(ctx, span)
+
+	// Pages reached through relations by a previous poll may have changed
+	// since.
+	scope.relations = newRelationGraph(ns.config)
+
+	if scope.pages || scope.rows || scope.blocks || scope.comments {
+		searchReq := types.SearchRequest{
+			Query: ns.config.SearchQuery,
+			Filter: &types.SearchFilter{
+				Value:    "page",
+				Property: "object",
+			},
+			Sort:     lastEditedDescending(),
+			PageSize: &ns.config.PageSize,
+		}
+
+		ns.search(ctx, scope, "", searchReq, func(result *types.SearchResult) bool {
+			page := result.Page
+			if page == nil {
+				return true
+			}
+
+			changed, more := feed.changed(string(page.ID), page.LastEditedTime)
+			if !changed || !scope.watches(string(page.ID), page.Parent) {
+				return more
+			}
+
+			key := checkpointKeyPages
+			if databaseID := pageDatabaseID(page); databaseID != "" {
+				key = databaseCheckpointKey(databaseID)
+			}
+			scope.marks.changed(key, page.LastEditedTime)
+			if scope.roots != nil {
+				scope.roots.visit(string(page.ID))
+			}
+
+			emit := scope.pages || (scope.rows && page.IsInDatabase())
+			return ns.processPage(ctx, page, emit, scope, results)
+		})
+	}
+
+	if scope.databases {
+		searchReq := types.SearchRequest{
+			Filter: &types.SearchFilter{
+				Value:    "database",
+				Property: "object",
+			},
+			Sort:     lastEditedDescending(),
+			PageSize: &ns.config.PageSize,
+		}
+
+		// Changed rows are found by the page search, so databases are emitted
+		// without querying them.
+		databaseScope := scope
+		databaseScope.rows = false
+
+		ns.search(ctx, scope, "", searchReq, func(result *types.SearchResult) bool {
+			database := result.Database
+			if database == nil {
+				return true
+			}
+
+			changed, more := feed.changed(string(database.ID), database.LastEditedTime)
+			if !changed || !scope.watches(string(database.ID), database.Parent) {
+				return more
+			}
+
+			scope.marks.changed(checkpointKeyDatabases, database.LastEditedTime)
+			if scope.roots != nil {
+				scope.roots.visit(string(database.ID))
+			}
+			return ns.processDatabase(ctx, database, true, databaseScope, results)
+		})
+	}
+
+	feed.advance()
+	return ctx.Err() == nil
+}
+
+// watches reports whether a changed object is part of the read, which for
+// root-scoped reads means it was visited or is below a page or database the
+// read visited.
+//
+// Arguments:
+// - id: The ID of the changed object.
+// - parent: The parent of the changed object.
+//
+// Returns:
+// - True if the object is watched.
+func (s readScope) watches(id string, parent *types.Parent) bool {
+	if s.roots == nil {
+		return true
+	}
+	if s.roots.contains(id) {
+		return true
+	}
+	return parent != nil && s.roots.contains(parent.GetParentID())
+}
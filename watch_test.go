@@ -0,0 +1,102 @@
+package notion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+)
+
+func TestWatchFeedTellsChangesWithinAMinute(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 30, 0, time.UTC)
+	minute := start.Truncate(time.Minute)
+	feed := newWatchFeed(start)
+
+	// Edit times are truncated, so an edit in the minute the crawl started
+	// may have been missed by it.
+	if changed, more := feed.changed("a", minute); !changed || !more {
+		t.Errorf("edit in the start minute = %v, %v, want a change", changed, more)
+	}
+	if changed, more := feed.changed("a", minute); changed || !more {
+		t.Errorf("emitted edit = %v, %v, want no change", changed, more)
+	}
+	if changed, more := feed.changed("b", minute.Add(-time.Minute)); changed || more {
+		t.Errorf("edit before the start = %v, %v, want the search stopped", changed, more)
+	}
+
+	feed.advance()
+	if changed, _ := feed.changed("a", minute); changed {
+		t.Error("the emitted edit was forgotten by advance")
+	}
+	if changed, _ := feed.changed("b", minute); !changed {
+		t.Error("another edit in the same minute was not a change")
+	}
+	if changed, _ := feed.changed("a", minute.Add(time.Minute)); !changed {
+		t.Error("a later edit was not a change")
+	}
+
+	feed.advance()
+	if !feed.since.Equal(minute.Add(time.Minute)) || len(feed.emitted) != 1 {
+		t.Errorf("feed = %v %v, want only the newest edit kept", feed.since, feed.emitted)
+	}
+}
+
+func TestWatchedReadEmitsChanges(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	old := newWorkspacePage("Old")
+	old.CreatedTime = time.Now().Add(-time.Hour)
+	workspace.AddPage(old)
+
+	store := NewMemoryCheckpointStore()
+	config := testConfig()
+	config.Watch = true
+	config.WatchInterval = 10 * time.Millisecond
+	config.Incremental = true
+	config.Checkpoints = store
+	ns, _ := newTestPlugin(t, workspace, config)
+
+	ctx, cancel := context.WithCancel(WithRunID(context.Background(), "run"))
+	defer cancel()
+	results, err := ns.Read(ctx, &engine.ReadRequest{Types: []common.ObjectType{common.ObjectTypePage}})
+	if err != nil {
+		t.Fatalf("failed to start read: %v", err)
+	}
+	if item := receiveItem(t, results); item.ID != string(old.ID) {
+		t.Fatalf("crawled %s, want the existing page", item.ID)
+	}
+
+	changed := newWorkspacePage("Changed")
+	changed.CreatedTime = time.Now().Add(2 * time.Minute)
+	workspace.AddPage(changed)
+	if item := receiveItem(t, results); item.ID != string(changed.ID) {
+		t.Fatalf("polled %s, want the changed page", item.ID)
+	}
+
+	// Every poll saves the checkpoint, so a later read starts from the
+	// changes already emitted.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		checkpoint, err := store.Load(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if checkpoint.Marks[checkpointKeyPages].Equal(changed.LastEditedTime) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mark = %v, want %v", checkpoint.Marks[checkpointKeyPages], changed.LastEditedTime)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	for item := range results {
+		t.Errorf("emitted %s %s after the changes were read", item.Type, item.ID)
+	}
+	if summary := ns.RunSummary("run"); summary == nil || summary.Completed {
+		t.Errorf("summary = %+v, want a watching run that never completes", summary)
+	}
+}
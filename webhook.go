@@ -0,0 +1,523 @@
+package notion
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// WebhookEventType is the type of a Notion webhook event.
+//
+// See: https://developers.notion.com/reference/webhooks-events-delivery
+type WebhookEventType string
+
+const (
+	WebhookEventPageCreated           WebhookEventType = "page.created"
+	WebhookEventPagePropertiesUpdated WebhookEventType = "page.properties_updated"
+	WebhookEventPageContentUpdated    WebhookEventType = "page.content_updated"
+	WebhookEventPageMoved             WebhookEventType = "page.moved"
+	WebhookEventPageDeleted           WebhookEventType = "page.deleted"
+	WebhookEventPageUndeleted         WebhookEventType = "page.undeleted"
+	WebhookEventPageLocked            WebhookEventType = "page.locked"
+	WebhookEventPageUnlocked          WebhookEventType = "page.unlocked"
+	WebhookEventDatabaseCreated       WebhookEventType = "database.created"
+	WebhookEventDatabaseContentUpdate WebhookEventType = "database.content_updated"
+	WebhookEventDatabaseMoved         WebhookEventType = "database.moved"
+	WebhookEventDatabaseDeleted       WebhookEventType = "database.deleted"
+	WebhookEventDatabaseUndeleted     WebhookEventType = "database.undeleted"
+	WebhookEventDatabaseSchemaUpdated WebhookEventType = "database.schema_updated"
+	WebhookEventCommentCreated        WebhookEventType = "comment.created"
+	WebhookEventCommentUpdated        WebhookEventType = "comment.updated"
+	WebhookEventCommentDeleted        WebhookEventType = "comment.deleted"
+)
+
+// WebhookSignatureHeader is the header carrying the signature of a webhook
+// request.
+const WebhookSignatureHeader = "X-Notion-Signature"
+
+// maxWebhookBody is the largest webhook request body accepted.
+const maxWebhookBody = 1 << 20
+
+// WebhookEntity identifies an object referenced by a webhook event.
+type WebhookEntity struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// WebhookEventData holds the details of a webhook event, which depend on its
+// type.
+type WebhookEventData struct {
+	// Parent is the parent of the entity.
+	Parent *WebhookEntity `json:"parent,omitempty"`
+	// PageID is the page a comment belongs to, for comment events.
+	PageID string `json:"page_id,omitempty"`
+	// UpdatedBlocks lists the blocks of a page, or the rows of a database,
+	// changed by a content update.
+	UpdatedBlocks []WebhookEntity `json:"updated_blocks,omitempty"`
+	// UpdatedProperties lists the IDs of the properties changed by a
+	// properties or schema update.
+	UpdatedProperties []string `json:"updated_properties,omitempty"`
+}
+
+// WebhookEvent is a Notion webhook event.
+type WebhookEvent struct {
+	ID             string           `json:"id"`
+	Timestamp      time.Time        `json:"timestamp"`
+	WorkspaceID    string           `json:"workspace_id"`
+	WorkspaceName  string           `json:"workspace_name,omitempty"`
+	SubscriptionID string           `json:"subscription_id"`
+	IntegrationID  string           `json:"integration_id"`
+	Type           WebhookEventType `json:"type"`
+	Authors        []WebhookEntity  `json:"authors,omitempty"`
+	AttemptNumber  int              `json:"attempt_number"`
+	Entity         WebhookEntity    `json:"entity"`
+	Data           WebhookEventData `json:"data"`
+}
+
+// ParseWebhookEvent decodes the body of a webhook request.
+//
+// Arguments:
+// - body: The request body.
+//
+// Returns:
+// - The event.
+// - An error if the body is not a webhook event.
+func ParseWebhookEvent(body []byte) (*WebhookEvent, error) {
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook event: %w", err)
+	}
+	if event.Type == "" || event.Entity.ID == "" {
+		return nil, fmt.Errorf("webhook event is missing its type or entity")
+	}
+	return &event, nil
+}
+
+// SignWebhookPayload computes the signature Notion sends with a webhook body,
+// which is useful to replay recorded payloads in tests.
+//
+// Arguments:
+// - token: The verification token of the subscription.
+// - body: The request body.
+//
+// Returns:
+// - The value of the X-Notion-Signature header.
+func SignWebhookPayload(token string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookConfig configures a WebhookHandler.
+type WebhookConfig struct {
+	// VerificationToken is the token Notion sent when the subscription was
+	// created, used to verify the signature of every event. Once it is set,
+	// unsigned verification requests are rejected.
+	VerificationToken string
+	// OnVerification is called with the token of the verification request
+	// Notion sends when a subscription is created, as long as no
+	// VerificationToken is set. The request is unsigned, so anyone can send
+	// one: compare the token with the one shown in the integration settings
+	// before trusting it as VerificationToken.
+	OnVerification func(token string)
+	// QueueSize is the number of events buffered before requests are
+	// rejected with 503, which makes Notion retry them. Defaults to 100.
+	QueueSize int
+}
+
+// WebhookHandler is an http.Handler receiving Notion webhook events.
+//
+// Verified events are queued and processed by Run, which re-fetches the
+// affected objects and emits them into a long-lived stream.
+type WebhookHandler struct {
+	ns     *Plugin
+	config WebhookConfig
+	events chan *WebhookEvent
+}
+
+// NewWebhookHandler creates a webhook handler re-fetching objects through a
+// plugin.
+//
+// Arguments:
+// - ns: The plugin.
+// - config: The webhook configuration.
+//
+// Returns:
+// - A new webhook handler.
+func NewWebhookHandler(ns *Plugin, config WebhookConfig) *WebhookHandler {
+	size := config.QueueSize
+	if size <= 0 {
+		size = 100
+	}
+	return &WebhookHandler{
+		ns:     ns,
+		config: config,
+		events: make(chan *WebhookEvent, size),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebhookBody {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The verification request is sent before the token is known, so it is
+	// the only unsigned request accepted, and only until the token is set.
+	var verification struct {
+		VerificationToken string `json:"verification_token"`
+	}
+	if err := json.Unmarshal(body, &verification); err == nil && verification.VerificationToken != "" {
+		if h.config.VerificationToken != "" {
+			http.Error(w, "subscription already verified", http.StatusUnauthorized)
+			return
+		}
+		if h.config.OnVerification != nil {
+			h.config.OnVerification(verification.VerificationToken)
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !h.verify(r.Header.Get(WebhookSignatureHeader), body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := ParseWebhookEvent(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.events <- event:
+		w.WriteHeader(http.StatusOK)
+	default:
+		multilog.Warn("notion.Webhook", "event queue full", map[string]interface{}{
+			"event_id": event.ID,
+			"type":     event.Type,
+		})
+		http.Error(w, "event queue full", http.StatusServiceUnavailable)
+	}
+}
+
+// verify checks the signature of a request body.
+//
+// Arguments:
+// - signature: The X-Notion-Signature header.
+// - body: The request body.
+//
+// Returns:
+// - True if the body was signed with the verification token.
+func (h *WebhookHandler) verify(signature string, body []byte) bool {
+	if h.config.VerificationToken == "" || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	expected := SignWebhookPayload(h.config.VerificationToken, body)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// Run processes queued events until the context is cancelled or the plugin is
+// closed, re-fetching the affected objects and emitting them into stream.
+//
+// Arguments:
+// - ctx: The context for the requests.
+// - req: The object types to emit.
+// - stream: The stream to send the results to.
+//
+// Returns:
+// - The context error once processing stopped, the ReadError of the invalid
+// item in ValidationFail mode, or ErrClosed.
+func (h *WebhookHandler) Run(ctx context.Context, req *engine.ReadRequest, stream chan<- engine.DataItemContainer[any]) error {
+	ctx, done, err := h.ns.active.start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scope := h.ns.newReadScope(req)
+	scope.errors = newErrorLog(ctx)
+	scope.ancestry = newAncestry(h.ns.config)
+	scope.validation = newValidation(h.ns.config, cancel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := scope.validation.err(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case event := <-h.events:
+			// Downloads are shared per event, the stream never ends.
+			scope.assets = h.ns.assets.newFetches()
+			h.ns.handleWebhookEvent(ctx, event, scope, stream)
+		}
+	}
+}
+
+// handleWebhookEvent re-fetches the objects affected by an event and emits
+// them.
+//
+// Arguments:
+// - ctx: The context for the requests.
+// - event: The event.
+// - scope: The stages to run for the stream.
+// - stream: The stream to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) handleWebhookEvent(ctx context.Context, event *WebhookEvent, scope readScope, stream chan<- engine.DataItemContainer[any]) bool {
+	ctx, span := ns.otel.startStage(ctx, "webhook:"+string(event.Type))
+	defer endSpan(ctx, span)
+
+	multilog.Debug("notion.Webhook", "processing event", map[string]interface{}{
+		"event_id": event.ID,
+		"type":     event.Type,
+		"entity":   event.Entity.ID,
+	})
+
+	switch event.Type {
+	case WebhookEventPageDeleted:
+		return ns.emitWebhookTombstone(ctx, event, common.ObjectTypePage, scope, stream)
+	case WebhookEventDatabaseDeleted:
+		return ns.emitWebhookTombstone(ctx, event, common.ObjectTypeCollection, scope, stream)
+	case WebhookEventCommentDeleted:
+		return ns.emitWebhookTombstone(ctx, event, common.ObjectTypeComment, scope, stream)
+
+	case WebhookEventPagePropertiesUpdated, WebhookEventPageMoved,
+		WebhookEventPageLocked, WebhookEventPageUnlocked:
+		// Only the page itself changed.
+		scope.blocks, scope.comments = false, false
+		return ns.refetchPage(ctx, event.Entity.ID, true, scope, stream)
+	case WebhookEventPageContentUpdated:
+		// Only the blocks changed, so the page is not emitted again.
+		scope.comments = false
+		return ns.refetchPage(ctx, event.Entity.ID, false, scope, stream)
+	case WebhookEventPageCreated, WebhookEventPageUndeleted:
+		return ns.refetchPage(ctx, event.Entity.ID, true, scope, stream)
+
+	case WebhookEventDatabaseContentUpdate:
+		if rows := webhookPages(event.Data.UpdatedBlocks); len(rows) > 0 && scope.rows {
+			scope.blocks, scope.comments = false, false
+			for _, id := range rows {
+				if !ns.refetchPage(ctx, id, true, scope, stream) {
+					return false
+				}
+			}
+			return true
+		}
+		return ns.refetchDatabase(ctx, event.Entity.ID, scope, stream)
+	case WebhookEventDatabaseSchemaUpdated, WebhookEventDatabaseMoved:
+		scope.rows = false
+		return ns.refetchDatabase(ctx, event.Entity.ID, scope, stream)
+	case WebhookEventDatabaseCreated, WebhookEventDatabaseUndeleted:
+		return ns.refetchDatabase(ctx, event.Entity.ID, scope, stream)
+
+	case WebhookEventCommentCreated, WebhookEventCommentUpdated:
+		if !scope.comments || event.Data.PageID == "" {
+			return true
+		}
+		pageID, err := parseWebhookID(event.Data.PageID, "page")
+		if err != nil {
+			scope.errors.report(ctx, err, OperationWebhook)
+			return true
+		}
+		// Comments on a block are listed by the block's ID.
+		parentID := pageID
+		if parent := event.Data.Parent; parent != nil && parent.Type == "block" {
+			if parentID, err = parseWebhookID(parent.ID, parent.Type); err != nil {
+				scope.errors.report(ctx, err, OperationWebhook)
+				return true
+			}
+		}
+		return ns.readComments(ctx, pageID, parentID, scope, stream)
+	}
+
+	multilog.Debug("notion.Webhook", "ignoring event", map[string]interface{}{
+		"event_id": event.ID,
+		"type":     event.Type,
+	})
+	return true
+}
+
+// webhookPages returns the IDs of the pages among updated entities.
+//
+// Arguments:
+// - entities: The updated entities.
+//
+// Returns:
+// - The page IDs.
+func webhookPages(entities []WebhookEntity) []string {
+	var ids []string
+	for _, entity := range entities {
+		if entity.Type == "page" {
+			ids = append(ids, entity.ID)
+		}
+	}
+	return ids
+}
+
+// refetchPage reads a page again and emits it with the blocks and comments in
+// scope.
+//
+// Arguments:
+// - ctx: The context for the requests.
+// - id: The ID of the page.
+// - emit: Whether the page itself is emitted, false to only emit what is
+// below it.
+// - scope: The stages to run.
+// - stream: The stream to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) refetchPage(ctx context.Context, id string, emit bool, scope readScope, stream chan<- engine.DataItemContainer[any]) bool {
+	pageID, err := types.ParsePageID(id)
+	if err != nil {
+		scope.errors.report(ctx, err, OperationGetPage)
+		return true
+	}
+
+	page, err := ns.getPage(ctx, pageID)
+	if err != nil {
+		scope.errors.report(ctx, err, OperationGetPage)
+		return ctx.Err() == nil
+	}
+
+	emit = emit && (scope.pages || (scope.rows && page.IsInDatabase()))
+	return ns.processPage(ctx, page, emit, scope, stream)
+}
+
+// refetchDatabase reads a database again and emits it, with its rows when
+// they are in scope.
+//
+// Arguments:
+// - ctx: The context for the requests.
+// - id: The ID of the database.
+// - scope: The stages to run.
+// - stream: The stream to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) refetchDatabase(ctx context.Context, id string, scope readScope, stream chan<- engine.DataItemContainer[any]) bool {
+	databaseID, err := types.ParseDatabaseID(id)
+	if err != nil {
+		scope.errors.report(ctx, err, OperationGetDatabase)
+		return true
+	}
+
+	database, err := ns.getDatabase(ctx, databaseID)
+	if err != nil {
+		scope.errors.report(ctx, err, OperationGetDatabase)
+		return ctx.Err() == nil
+	}
+	return ns.processDatabase(ctx, database, true, scope, stream)
+}
+
+// emitWebhookTombstone emits a tombstone for an object deleted according to
+// a webhook event.
+//
+// Arguments:
+// - ctx: The context for the requests.
+// - event: The event.
+// - objectType: The type of the deleted object.
+// - scope: The stages to run.
+// - stream: The stream to send the results to.
+//
+// Returns:
+// - False if the context was cancelled, true otherwise.
+func (ns *Plugin) emitWebhookTombstone(ctx context.Context, event *WebhookEvent, objectType common.ObjectType, scope readScope, stream chan<- engine.DataItemContainer[any]) bool {
+	id, err := parseWebhookID(event.Entity.ID, event.Entity.Type)
+	if err != nil {
+		scope.errors.report(ctx, err, OperationWebhook)
+		return true
+	}
+
+	tombstone := &Tombstone{
+		ID:         id,
+		Type:       objectType,
+		Reason:     tombstoneDeleted,
+		DetectedAt: event.Timestamp,
+	}
+	if parent := event.Data.Parent; parent != nil {
+		if tombstone.ParentID, err = parseWebhookID(parent.ID, parent.Type); err != nil {
+			scope.errors.report(ctx, err, OperationWebhook)
+			return true
+		}
+		if parent.Type == "database" {
+			tombstone.DatabaseID = tombstone.ParentID
+		}
+	}
+	if objectType == common.ObjectTypeComment && event.Data.PageID != "" {
+		if tombstone.ParentID, err = parseWebhookID(event.Data.PageID, "page"); err != nil {
+			scope.errors.report(ctx, err, OperationWebhook)
+			return true
+		}
+	}
+	return ns.emit(ctx, scope, ns.convertTombstoneToDataItem(tombstone), stream)
+}
+
+// parseWebhookID normalizes the ID of an object referenced by a webhook event
+// to the form the items read from the API carry.
+//
+// Arguments:
+// - id: The ID from the event.
+// - entityType: The type of the object, such as "page" or "database".
+//
+// Returns:
+// - The normalized ID, or the ID as is for types without IDs of their own.
+// - An error if the ID is invalid.
+func parseWebhookID(id string, entityType string) (string, error) {
+	var parsed string
+	var err error
+	switch entityType {
+	case "page":
+		var pageID types.PageID
+		pageID, err = types.ParsePageID(id)
+		parsed = string(pageID)
+	case "database":
+		var databaseID types.DatabaseID
+		databaseID, err = types.ParseDatabaseID(id)
+		parsed = string(databaseID)
+	case "block":
+		var blockID types.BlockID
+		blockID, err = types.ParseBlockID(id)
+		parsed = string(blockID)
+	case "comment":
+		var commentID types.CommentID
+		commentID, err = types.ParseCommentID(id)
+		parsed = string(commentID)
+	default:
+		return id, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid %s ID %q in webhook event: %w", entityType, id, err)
+	}
+	return parsed, nil
+}
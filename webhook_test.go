@@ -0,0 +1,272 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+const webhookToken = "secret_webhook"
+
+func TestWebhookVerification(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+	body := []byte(`{"verification_token":"secret_new"}`)
+
+	var received string
+	handler := NewWebhookHandler(ns, WebhookConfig{OnVerification: func(token string) { received = token }})
+	if code := postWebhook(handler, body, ""); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if received != "secret_new" {
+		t.Errorf("token = %q, want secret_new", received)
+	}
+
+	// Once verified, an unsigned verification request could replace the token.
+	received = ""
+	handler = NewWebhookHandler(ns, WebhookConfig{
+		VerificationToken: webhookToken,
+		OnVerification:    func(token string) { received = token },
+	})
+	if code := postWebhook(handler, body, ""); code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if received != "" {
+		t.Errorf("token = %q, want the verification ignored", received)
+	}
+}
+
+func TestWebhookVerifiesSignatures(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+	body := webhookBody(t, &WebhookEvent{
+		ID:     "event",
+		Type:   WebhookEventPageCreated,
+		Entity: WebhookEntity{ID: missingPageID, Type: "page"},
+	})
+
+	tests := []struct {
+		name      string
+		token     string
+		signature string
+		want      int
+	}{
+		{name: "signed", token: webhookToken, signature: SignWebhookPayload(webhookToken, body), want: http.StatusOK},
+		{name: "unsigned", token: webhookToken, want: http.StatusUnauthorized},
+		{name: "other token", token: webhookToken, signature: SignWebhookPayload("secret_other", body), want: http.StatusUnauthorized},
+		{name: "no prefix", token: webhookToken, signature: strings.TrimPrefix(SignWebhookPayload(webhookToken, body), "sha256="), want: http.StatusUnauthorized},
+		{name: "not verified", signature: SignWebhookPayload("", body), want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWebhookHandler(ns, WebhookConfig{VerificationToken: tt.token})
+			if code := postWebhook(handler, body, tt.signature); code != tt.want {
+				t.Errorf("status = %d, want %d", code, tt.want)
+			}
+			if queued := len(handler.events); (queued == 1) != (tt.want == http.StatusOK) {
+				t.Errorf("queued %d events for status %d", queued, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookRejectsFullQueue(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+	handler := NewWebhookHandler(ns, WebhookConfig{VerificationToken: webhookToken, QueueSize: 1})
+	body := webhookBody(t, &WebhookEvent{
+		ID:     "event",
+		Type:   WebhookEventPageCreated,
+		Entity: WebhookEntity{ID: missingPageID, Type: "page"},
+	})
+
+	if code := postWebhook(handler, body, SignWebhookPayload(webhookToken, body)); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	// Notion retries the event later.
+	if code := postWebhook(handler, body, SignWebhookPayload(webhookToken, body)); code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebhookTombstonesUseReadIDs(t *testing.T) {
+	ns, _ := newTestPlugin(t, notiontest.NewWorkspace(), testConfig())
+	handler := NewWebhookHandler(ns, WebhookConfig{VerificationToken: webhookToken})
+	stream := runWebhookHandler(t, handler, common.ObjectTypePage)
+
+	databaseID := "22222222-2222-4222-8222-222222222222"
+	body := webhookBody(t, &WebhookEvent{
+		ID:     "event",
+		Type:   WebhookEventPageDeleted,
+		Entity: WebhookEntity{ID: strings.ReplaceAll(missingPageID, "-", ""), Type: "page"},
+		Data:   WebhookEventData{Parent: &WebhookEntity{ID: strings.ReplaceAll(databaseID, "-", ""), Type: "database"}},
+	})
+	if code := postWebhook(handler, body, SignWebhookPayload(webhookToken, body)); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+
+	got := tombstones([]engine.DataItemContainer[any]{receiveItem(t, stream)})
+	if len(got) != 1 {
+		t.Fatal("the event did not emit a tombstone")
+	}
+	if tombstone := got[0]; tombstone.ID != missingPageID || tombstone.ParentID != databaseID || tombstone.DatabaseID != databaseID {
+		t.Errorf("tombstone = %+v, want the dashed IDs of the items read", tombstone)
+	}
+}
+
+func TestWebhookReadsCommentsOnBlocks(t *testing.T) {
+	workspace := notiontest.NewWorkspace()
+	page := workspace.AddPage(newWorkspacePage("Page"))
+	block := workspace.AddBlocks(string(page.ID), newParagraph("commented"))[0]
+	workspace.AddComments(types.NewComment(
+		&types.CommentParent{Type: types.CommentParentTypePage, PageID: &page.ID},
+		[]types.RichText{*types.NewTextRichText("on the page", nil)},
+	))
+	comment := workspace.AddComments(types.NewComment(
+		&types.CommentParent{Type: types.CommentParentTypeBlock, BlockID: &block.ID},
+		[]types.RichText{*types.NewTextRichText("on the block", nil)},
+	))[0]
+
+	ns, _ := newTestPlugin(t, workspace, testConfig())
+	handler := NewWebhookHandler(ns, WebhookConfig{VerificationToken: webhookToken})
+	stream := runWebhookHandler(t, handler, common.ObjectTypeComment)
+
+	body := webhookBody(t, &WebhookEvent{
+		ID:     "event",
+		Type:   WebhookEventCommentCreated,
+		Entity: WebhookEntity{ID: string(comment.ID), Type: "comment"},
+		Data: WebhookEventData{
+			PageID: strings.ReplaceAll(string(page.ID), "-", ""),
+			Parent: &WebhookEntity{ID: strings.ReplaceAll(string(block.ID), "-", ""), Type: "block"},
+		},
+	})
+	if code := postWebhook(handler, body, SignWebhookPayload(webhookToken, body)); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+
+	item := receiveItem(t, stream)
+	if item.ID != string(comment.ID) {
+		t.Fatalf("emitted %s %s, want the comment on the block", item.Type, item.ID)
+	}
+	if pageID := item.Metadata.Properties["page_id"]; pageID != string(page.ID) {
+		t.Errorf("page_id = %v, want %s", pageID, page.ID)
+	}
+	select {
+	case extra := <-stream:
+		t.Errorf("emitted %s %s, want only the comments on the block", extra.Type, extra.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestParseWebhookID(t *testing.T) {
+	tests := []struct {
+		id         string
+		entityType string
+		want       string
+		err        bool
+	}{
+		{id: strings.ReplaceAll(missingPageID, "-", ""), entityType: "page", want: missingPageID},
+		{id: missingPageID, entityType: "database", want: missingPageID},
+		{id: strings.ToUpper(strings.ReplaceAll(missingPageID, "-", "")), entityType: "block", want: missingPageID},
+		{id: "workspace", entityType: "workspace", want: "workspace"},
+		{id: "not-an-id", entityType: "comment", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWebhookID(tt.id, tt.entityType)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseWebhookID(%q, %q) = %q, %v, want %q", tt.id, tt.entityType, got, err, tt.want)
+		}
+	}
+}
+
+// postWebhook sends a webhook request to a handler.
+//
+// Arguments:
+// - handler: The handler.
+// - body: The request body.
+// - signature: The X-Notion-Signature header, or "" to leave it out.
+//
+// Returns:
+// - The status code of the response.
+func postWebhook(handler *WebhookHandler, body []byte, signature string) int {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	if signature != "" {
+		req.Header.Set(WebhookSignatureHeader, signature)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// webhookBody encodes a webhook event.
+//
+// Arguments:
+// - t: The test.
+// - event: The event.
+//
+// Returns:
+// - The request body.
+func webhookBody(t *testing.T, event *WebhookEvent) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// runWebhookHandler processes the events of a handler until the test ends.
+//
+// Arguments:
+// - t: The test.
+// - handler: The handler.
+// - objTypes: The object types to emit.
+//
+// Returns:
+// - The stream the items are emitted into.
+func runWebhookHandler(t *testing.T, handler *WebhookHandler, objTypes ...common.ObjectType) <-chan engine.DataItemContainer[any] {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := make(chan engine.DataItemContainer[any])
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.Run(ctx, &engine.ReadRequest{Types: objTypes}, stream)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return stream
+}
+
+// receiveItem receives an item from a stream.
+//
+// Arguments:
+// - t: The test.
+// - stream: The stream.
+//
+// Returns:
+// - The item.
+func receiveItem(t *testing.T, stream <-chan engine.DataItemContainer[any]) engine.DataItemContainer[any] {
+	t.Helper()
+
+	select {
+	case item := <-stream:
+		return item
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for an item")
+		return engine.DataItemContainer[any]{}
+	}
+}
@@ -0,0 +1,897 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cmskitdev/client"
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/types"
+	"github.com/mateothegreat/go-multilog/multilog"
+)
+
+// WriteConfig configures how a Destination publishes pages and blocks to Notion.
+type WriteConfig struct {
+	// DatabaseID is the database new pages are created in. Either DatabaseID
+	// or ParentPageID must be set.
+	DatabaseID string `json:"database_id,omitempty"`
+	// ParentPageID is the page new pages are created below when DatabaseID is
+	// not set. Only the title of pages is written below a page.
+	ParentPageID string `json:"parent_page_id,omitempty"`
+	// KeyProperty is the database property whose value identifies a page, so
+	// that a page written again updates the existing row instead of creating a
+	// new one. Title, rich text, number and select properties are supported.
+	KeyProperty string `json:"key_property,omitempty"`
+	// AppendToExisting appends the blocks of pages that matched an existing
+	// row. By default only pages created by the write get blocks, since
+	// appending to an existing page would duplicate its content.
+	AppendToExisting bool `json:"append_to_existing,omitempty"`
+	// Targets persists the IDs of the written pages, so that tombstones
+	// written by a later Destination still archive them. The IDs are only
+	// kept for the lifetime of the Destination when nil.
+	Targets WriteTargetStore `json:"-"`
+}
+
+// WriteTargetStore persists the IDs of the pages a Destination wrote, keyed by
+// the IDs of their source pages.
+//
+// Implementations must be safe for concurrent use.
+type WriteTargetStore interface {
+	// Load returns the saved targets, or an empty map if none were saved yet.
+	Load(ctx context.Context) (map[string]string, error)
+	// Save persists the targets, replacing the saved ones.
+	Save(ctx context.Context, targets map[string]string) error
+}
+
+// MemoryWriteTargetStore keeps the targets in memory, which is useful for
+// tests and for long-lived processes that create destinations repeatedly.
+type MemoryWriteTargetStore struct {
+	mu      sync.RWMutex
+	targets map[string]string
+}
+
+// NewMemoryWriteTargetStore creates an empty in-memory target store.
+//
+// Returns:
+// - A new in-memory target store.
+func NewMemoryWriteTargetStore() *MemoryWriteTargetStore {
+	return &MemoryWriteTargetStore{}
+}
+
+// Load implements WriteTargetStore.Load.
+func (s *MemoryWriteTargetStore) Load(ctx context.Context) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.targets == nil {
+		return map[string]string{}, nil
+	}
+	return maps.Clone(s.targets), nil
+}
+
+// Save implements WriteTargetStore.Save.
+func (s *MemoryWriteTargetStore) Save(ctx context.Context, targets map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.targets = maps.Clone(targets)
+	return nil
+}
+
+// FileWriteTargetStore persists the targets as a JSON file.
+type FileWriteTargetStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileWriteTargetStore creates a target store backed by the given file.
+//
+// Arguments:
+// - path: The path of the JSON file, created on the first save.
+//
+// Returns:
+// - A new file target store.
+func NewFileWriteTargetStore(path string) *FileWriteTargetStore {
+	return &FileWriteTargetStore{path: path}
+}
+
+// Load implements WriteTargetStore.Load.
+func (s *FileWriteTargetStore) Load(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read write targets %s: %w", s.path, err)
+	}
+
+	targets := make(map[string]string)
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to decode write targets %s: %w", s.path, err)
+	}
+	return targets, nil
+}
+
+// Save implements WriteTargetStore.Save.
+func (s *FileWriteTargetStore) Save(ctx context.Context, targets map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode write targets: %w", err)
+	}
+
+	return writeFileAtomic(s.path, data)
+}
+
+// maxAppendBlocks is the maximum number of blocks appended per request.
+const maxAppendBlocks = 100
+
+// writeParent returns the parent new pages are created in.
+//
+// Returns:
+// - The parent.
+// - An error if neither a database nor a parent page is configured, or an ID
+// is invalid.
+func (c WriteConfig) writeParent() (types.Parent, error) {
+	if c.DatabaseID != "" {
+		id, err := types.ParseDatabaseID(c.DatabaseID)
+		if err != nil {
+			return types.Parent{}, fmt.Errorf("invalid write database ID %q: %w", c.DatabaseID, err)
+		}
+		return types.Parent{Type: types.ParentTypeDatabase, DatabaseID: &id}, nil
+	}
+
+	if c.ParentPageID != "" {
+		if c.KeyProperty != "" {
+			return types.Parent{}, fmt.Errorf("matching pages by key property requires a write database ID")
+		}
+		id, err := types.ParsePageID(c.ParentPageID)
+		if err != nil {
+			return types.Parent{}, fmt.Errorf("invalid write parent page ID %q: %w", c.ParentPageID, err)
+		}
+		return types.Parent{Type: types.ParentTypePage, PageID: &id}, nil
+	}
+
+	return types.Parent{}, fmt.Errorf("writing requires a database ID or a parent page ID")
+}
+
+// writeSession holds what a Destination has written so far.
+type writeSession struct {
+	parent types.Parent
+
+	// targets maps the IDs of written source pages and blocks to the IDs of
+	// the pages and blocks created or updated in Notion.
+	targets map[string]string
+	// pages holds the entries of targets for pages, which are saved to
+	// WriteConfig.Targets. loaded is set once the saved ones were loaded, and
+	// changed when they need to be saved again.
+	pages   map[string]string
+	loaded  bool
+	changed bool
+	// appendable holds the target pages blocks may be appended to.
+	appendable map[string]bool
+	// skipped holds the IDs of the source pages and blocks that were not
+	// written, whose blocks are skipped as well.
+	skipped map[string]bool
+
+	// batch holds the blocks waiting to be appended to batchParent.
+	batchParent string
+	batchIDs    []string
+	batch       []types.Block
+}
+
+// Destination publishes pages and blocks to Notion, implementing the
+// engine's PluginDestination.
+//
+// Pages are created in the database or below the page set in
+// NotionSourceConfig.Write, or update the existing row with the same
+// KeyProperty value. Blocks are appended to the page or block they belonged
+// to in the source, so items must arrive parents first, as Read emits them.
+//
+// Archived pages are not created but archive the row with the same key, and
+// tombstones archive the page written for the source page that is gone, see
+// WriteConfig.Targets. Archived blocks and the blocks of pages that were not
+// written are skipped, and so are blocks whose files cannot be written, see
+// writableBlock.
+type Destination struct {
+	plugin *Plugin
+
+	// mu serializes writes, since items depend on the ones written before.
+	mu      sync.Mutex
+	session *writeSession
+}
+
+// NewNotionDestination creates a destination publishing to Notion.
+//
+// Arguments:
+// - client: The Notion client.
+// - config: The configuration, whose Write field sets where pages go.
+//
+// Returns:
+// - The destination.
+// - An error if neither a database nor a parent page is configured, or an ID
+// is invalid.
+func NewNotionDestination(client *client.Client, config NotionSourceConfig) (*Destination, error) {
+	parent, err := config.Write.writeParent()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Destination{
+		plugin: NewNotionSource(client, config),
+		session: &writeSession{
+			parent:     parent,
+			targets:    make(map[string]string),
+			pages:      make(map[string]string),
+			appendable: make(map[string]bool),
+			skipped:    make(map[string]bool),
+		},
+	}, nil
+}
+
+// Write implements PluginDestination.Write.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - item: The item to write.
+//
+// Returns:
+// - An error if the item could not be written.
+func (d *Destination) Write(ctx context.Context, item engine.DataItemContainer[any]) error {
+	return d.Batch(ctx, []engine.DataItemContainer[any]{item})
+}
+
+// Batch implements PluginDestination.Batch. Consecutive sibling blocks are
+// appended with a single request.
+//
+// A failed item does not stop the batch; failures are reported to the handler
+// set with WithErrorHandler and returned together.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - items: The items to write, in order.
+//
+// Returns:
+// - An error if the destination is closed, the context was cancelled or
+// items failed to be written.
+func (d *Destination) Batch(ctx context.Context, items []engine.DataItemContainer[any]) error {
+	ns := d.plugin
+
+	ctx, done, err := ns.active.start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	ctx, span := ns.otel.startStage(ctx, "write")
+	defer endSpan(ctx, span)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.loadTargets(ctx); err != nil {
+		return err
+	}
+
+	log := newErrorLog(ctx)
+	var errs []error
+	fail := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+			log.report(ctx, err, OperationWrite)
+		}
+	}
+
+	for i := range items {
+		if ctx.Err() != nil {
+			break
+		}
+
+		item := &items[i]
+		switch item.Type {
+		case common.ObjectTypePage:
+			fail(d.flushBlocks(ctx))
+			fail(d.writePage(ctx, item))
+		case common.ObjectTypeBlock:
+			fail(d.writeBlock(ctx, item))
+		}
+	}
+
+	fail(d.flushBlocks(ctx))
+	fail(d.saveTargets(ctx))
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		multilog.Error("notion.Write", "write finished with errors", map[string]interface{}{
+			"errors": len(errs),
+		})
+		return fmt.Errorf("failed to write %d items: %w", len(errs), errors.Join(errs...))
+	}
+	return nil
+}
+
+// SupportsType implements PluginDestination.SupportsType.
+func (d *Destination) SupportsType(objType common.ObjectType) bool {
+	return objType == common.ObjectTypePage || objType == common.ObjectTypeBlock
+}
+
+// Validate implements PluginDestination.Validate.
+func (d *Destination) Validate(item engine.DataItemContainer[any]) error {
+	if !d.SupportsType(item.Type) {
+		return fmt.Errorf("object type %s is not supported by Notion destination", item.Type)
+	}
+
+	switch item.Data.(type) {
+	case *types.Page, *types.Block, *Tombstone:
+		return nil
+	}
+	return fmt.Errorf("%s %s carries unsupported data %T", item.Type, item.ID, item.Data)
+}
+
+// Config implements PluginDestination.Config.
+func (d *Destination) Config() engine.DestinationConfig {
+	config := d.plugin.config
+	return engine.DestinationConfig{
+		Type: "notion",
+		Name: "Notion API Destination",
+		Properties: map[string]interface{}{
+			"database_id":         config.Write.DatabaseID,
+			"parent_page_id":      config.Write.ParentPageID,
+			"key_property":        config.Write.KeyProperty,
+			"append_to_existing":  config.Write.AppendToExisting,
+			"requests_per_second": config.RequestsPerSecond,
+		},
+	}
+}
+
+// GetMetrics returns the lifetime metrics of the destination.
+//
+// Returns:
+// - A snapshot of the metrics.
+func (d *Destination) GetMetrics() NotionSourceMetrics {
+	return d.plugin.GetMetrics()
+}
+
+// Close implements PluginDestination.Close, cancelling running writes and
+// waiting for them to exit, see Plugin.Close.
+//
+// Returns:
+// - An error if writes did not exit in time.
+func (d *Destination) Close() error {
+	return d.plugin.Close()
+}
+
+// writePage creates, updates or archives the page carried by an item.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - item: The item carrying a *types.Page or a *Tombstone.
+//
+// Returns:
+// - An error if the page could not be written.
+func (d *Destination) writePage(ctx context.Context, item *engine.DataItemContainer[any]) error {
+	ns, session := d.plugin, d.session
+
+	if tombstone, ok := item.Data.(*Tombstone); ok {
+		target, ok := session.targets[tombstone.ID]
+		if !ok {
+			return fmt.Errorf("failed to archive page %s: no page was written for it", tombstone.ID)
+		}
+		session.forget(tombstone.ID)
+		return d.archivePage(ctx, types.PageID(target))
+	}
+
+	page, ok := item.Data.(*types.Page)
+	if !ok || page == nil {
+		return nil
+	}
+
+	properties := writableProperties(page, session.parent.Type == types.ParentTypeDatabase)
+
+	existing, err := ns.findPage(ctx, properties)
+	if err != nil {
+		return err
+	}
+
+	if page.Archived || page.InTrash {
+		session.skipped[item.ID] = true
+		if existing == "" {
+			existing = types.PageID(session.targets[item.ID])
+		}
+		session.forget(item.ID)
+		return d.archivePage(ctx, existing)
+	}
+
+	if existing != "" {
+		if _, err := ns.updatePage(ctx, types.PageUpdateRequest{
+			PageID:     existing,
+			Properties: properties,
+			Icon:       page.Icon,
+			Cover:      page.Cover,
+		}); err != nil {
+			return err
+		}
+		ns.incrementPagesUpdated(ctx)
+
+		session.wrote(item.ID, string(existing))
+		session.appendable[string(existing)] = ns.config.Write.AppendToExisting
+		return nil
+	}
+
+	created, err := ns.createPage(ctx, types.PageCreateRequest{
+		Parent:     session.parentOf(page),
+		Properties: properties,
+		Icon:       page.Icon,
+		Cover:      page.Cover,
+	})
+	if err != nil {
+		return err
+	}
+	ns.incrementPagesCreated(ctx)
+
+	session.wrote(item.ID, string(created.ID))
+	session.appendable[string(created.ID)] = true
+	return nil
+}
+
+// wrote records the page written for a source page.
+//
+// Arguments:
+// - sourceID: The ID of the source page.
+// - targetID: The ID of the page in Notion.
+func (s *writeSession) wrote(sourceID string, targetID string) {
+	s.targets[sourceID] = targetID
+	if s.pages[sourceID] != targetID {
+		s.pages[sourceID] = targetID
+		s.changed = true
+	}
+}
+
+// forget drops the page written for a source page once it is archived.
+//
+// Arguments:
+// - sourceID: The ID of the source page.
+func (s *writeSession) forget(sourceID string) {
+	delete(s.targets, sourceID)
+	if _, ok := s.pages[sourceID]; ok {
+		delete(s.pages, sourceID)
+		s.changed = true
+	}
+}
+
+// loadTargets loads the pages written by previous destinations from
+// WriteConfig.Targets on the first write.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - An error if the targets could not be loaded.
+func (d *Destination) loadTargets(ctx context.Context) error {
+	session, store := d.session, d.plugin.config.Write.Targets
+	if session.loaded || store == nil {
+		return nil
+	}
+
+	pages, err := store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load write targets: %w", err)
+	}
+	for source, target := range pages {
+		if _, ok := session.targets[source]; !ok {
+			session.targets[source] = target
+			session.pages[source] = target
+		}
+	}
+	session.loaded = true
+	return nil
+}
+
+// saveTargets saves the written pages to WriteConfig.Targets if they changed.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - An error if the targets could not be saved.
+func (d *Destination) saveTargets(ctx context.Context) error {
+	session, store := d.session, d.plugin.config.Write.Targets
+	if !session.changed || store == nil {
+		return nil
+	}
+
+	if err := store.Save(ctx, session.pages); err != nil {
+		return fmt.Errorf("failed to save write targets: %w", err)
+	}
+	session.changed = false
+	return nil
+}
+
+// archivePage archives a page in Notion, counted as an update.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - id: The ID of the page, or empty if there is none to archive.
+//
+// Returns:
+// - An error if the page could not be archived.
+func (d *Destination) archivePage(ctx context.Context, id types.PageID) error {
+	if id == "" {
+		return nil
+	}
+
+	archived := true
+	if _, err := d.plugin.updatePage(ctx, types.PageUpdateRequest{
+		PageID:   id,
+		Archived: &archived,
+	}); err != nil {
+		return err
+	}
+	d.plugin.incrementPagesUpdated(ctx)
+	return nil
+}
+
+// parentOf returns the parent to create a page in: the written copy of its
+// source parent page if there is one, otherwise the configured parent.
+//
+// Arguments:
+// - page: The source page.
+//
+// Returns:
+// - The parent.
+func (s *writeSession) parentOf(page *types.Page) types.Parent {
+	if target, ok := s.targets[page.GetParentID()]; ok && page.Parent != nil && page.Parent.Type == types.ParentTypePage {
+		id := types.PageID(target)
+		return types.Parent{Type: types.ParentTypePage, PageID: &id}
+	}
+	return s.parent
+}
+
+// writeBlock queues the block carried by an item for appending.
+//
+// Consecutive siblings are appended with a single request; the batch is
+// flushed whenever a block needs a parent that is still waiting in it.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - item: The item carrying a *types.Block.
+//
+// Returns:
+// - An error if a batch could not be appended or the parent is unknown.
+func (d *Destination) writeBlock(ctx context.Context, item *engine.DataItemContainer[any]) error {
+	session := d.session
+
+	block, ok := item.Data.(*types.Block)
+	if !ok || block == nil || blockLeaf(block) {
+		return nil
+	}
+
+	sourceParent, _ := item.Metadata.Properties["parent_id"].(string)
+	sourcePage, _ := item.Metadata.Properties["page_id"].(string)
+
+	if block.Archived || block.InTrash || session.skipped[sourcePage] || session.skipped[sourceParent] {
+		session.skipped[item.ID] = true
+		return nil
+	}
+
+	page, ok := session.targets[sourcePage]
+	if !ok {
+		return fmt.Errorf("failed to write block %s: page %s was not written", item.ID, sourcePage)
+	}
+	if !session.appendable[page] {
+		return nil
+	}
+
+	block, ok = writableBlock(block)
+	if !ok {
+		multilog.Warn("notion.Write", "skipping block with a Notion-hosted file", map[string]interface{}{
+			"block_id": item.ID,
+			"type":     block.Type,
+		})
+		session.skipped[item.ID] = true
+		return nil
+	}
+
+	if session.waiting(sourceParent) {
+		if err := d.flushBlocks(ctx); err != nil {
+			return err
+		}
+	}
+
+	parent, ok := session.targets[sourceParent]
+	if !ok {
+		return fmt.Errorf("failed to write block %s: parent %s was not written", item.ID, sourceParent)
+	}
+
+	if session.batchParent != parent || len(session.batch) == maxAppendBlocks {
+		if err := d.flushBlocks(ctx); err != nil {
+			return err
+		}
+		session.batchParent = parent
+	}
+
+	session.batchIDs = append(session.batchIDs, item.ID)
+	session.batch = append(session.batch, *block)
+	return nil
+}
+
+// writableBlock returns a copy of a block that can be appended.
+//
+// Notion only accepts files by external URL or by file upload, so the files of
+// file, image, video, audio and pdf blocks are written as external files. A
+// Notion-hosted file qualifies once it was downloaded to an absolute URL, see
+// AssetConfig; its original URL expires about an hour after it was read.
+//
+// Arguments:
+// - block: The source block.
+//
+// Returns:
+// - The block to append, or the source block if it cannot be written.
+// - False if a file of the block cannot be written.
+func writableBlock(block *types.Block) (*types.Block, bool) {
+	copied := *block
+	for _, file := range []**types.FileBlock{&copied.File, &copied.Image, &copied.Video, &copied.Audio, &copied.PDF} {
+		if *file == nil || (*file).File == nil {
+			continue
+		}
+
+		var url string
+		switch source := (*file).File; {
+		case source.File != nil && source.File.ExpiryTime == "":
+			url = source.File.URL
+		case source.External != nil:
+			url = source.External.URL
+		}
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return block, false
+		}
+
+		external := **file
+		external.Type = types.FileBlockTypeExternal
+		external.File = nil
+		external.External = &types.File{Type: "external", External: &types.ExternalFileType{URL: url}}
+		*file = &external
+	}
+	return &copied, true
+}
+
+// waiting reports whether a source block is queued but not appended yet.
+//
+// Arguments:
+// - sourceID: The ID of the source block.
+//
+// Returns:
+// - True if the block is in the current batch.
+func (s *writeSession) waiting(sourceID string) bool {
+	for _, id := range s.batchIDs {
+		if id == sourceID {
+			return true
+		}
+	}
+	return false
+}
+
+// flushBlocks appends the queued blocks.
+//
+// Arguments:
+// - ctx: The context for the request.
+//
+// Returns:
+// - An error if the blocks could not be appended.
+func (d *Destination) flushBlocks(ctx context.Context) error {
+	session := d.session
+	if len(session.batch) == 0 {
+		return nil
+	}
+
+	ids, blocks, parent := session.batchIDs, session.batch, session.batchParent
+	session.batchIDs, session.batch = nil, nil
+
+	appended, err := d.plugin.appendBlocks(ctx, types.BlockAppendRequest{
+		BlockID:  types.BlockID(parent),
+		Children: blocks,
+	})
+	if err != nil {
+		return err
+	}
+	d.plugin.incrementBlocksAppended(ctx, len(blocks))
+
+	// Notion returns the appended blocks in order, which maps them back to
+	// their sources so that their own children can be appended later.
+	for i := range ids {
+		if i < len(appended.Results) {
+			session.targets[ids[i]] = string(appended.Results[i].ID)
+		}
+	}
+	return nil
+}
+
+// findPage looks up the row of the write database with the same key property
+// value.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - properties: The properties of the page being written.
+//
+// Returns:
+// - The ID of the existing row, or an empty ID if there is none.
+// - An error if the key property is missing or unsupported, or the query
+// failed.
+func (ns *Plugin) findPage(ctx context.Context, properties map[string]types.Property) (types.PageID, error) {
+	key := ns.config.Write.KeyProperty
+	if key == "" {
+		return "", nil
+	}
+
+	property, ok := properties[key]
+	if !ok {
+		return "", fmt.Errorf("page has no key property %q", key)
+	}
+
+	filter, err := keyFilter(key, property)
+	if err != nil {
+		return "", err
+	}
+
+	databaseID, err := types.ParseDatabaseID(ns.config.Write.DatabaseID)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := ns.queryDatabaseRows(ctx, databaseID, DatabaseQuery{Filter: filter}, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(rows.Results) == 0 {
+		return "", nil
+	}
+	return rows.Results[0].ID, nil
+}
+
+// keyFilter builds the query filter matching a key property value.
+//
+// Arguments:
+// - name: The name of the key property.
+// - property: The key property value.
+//
+// Returns:
+// - The filter.
+// - An error if the property type cannot be used as a key.
+func keyFilter(name string, property types.Property) (*types.QueryFilter, error) {
+	filter := &types.QueryFilter{Property: &name}
+
+	switch property.Type {
+	case types.PropertyTypeTitle:
+		value := types.ToPlainText(property.Title)
+		filter.RichText = &types.RichTextFilter{Equals: &value}
+	case types.PropertyTypeRichText:
+		value := types.ToPlainText(property.RichText)
+		filter.RichText = &types.RichTextFilter{Equals: &value}
+	case types.PropertyTypeNumber:
+		if property.Number == nil || property.Number.Number == nil {
+			return nil, fmt.Errorf("key property %q is empty", name)
+		}
+		filter.Number = &types.NumberFilter{Equals: property.Number.Number}
+	case types.PropertyTypeSelect:
+		if property.Select == nil || property.Select.Name == nil {
+			return nil, fmt.Errorf("key property %q is empty", name)
+		}
+		filter.Select = &types.SelectFilter{Equals: property.Select.Name}
+	default:
+		return nil, fmt.Errorf("key property %q has unsupported type %s", name, property.Type)
+	}
+
+	if filter.RichText != nil && strings.TrimSpace(*filter.RichText.Equals) == "" {
+		return nil, fmt.Errorf("key property %q is empty", name)
+	}
+	return filter, nil
+}
+
+// writableProperties returns the page properties that can be written.
+//
+// Computed properties are left out, and so is everything but the title for
+// pages created below another page, since those have no schema.
+//
+// Arguments:
+// - page: The source page.
+// - database: Whether the page is written to a database.
+//
+// Returns:
+// - The properties by name.
+func writableProperties(page *types.Page, database bool) map[string]types.Property {
+	properties := make(map[string]types.Property)
+	if page.PropertyContainer == nil {
+		return properties
+	}
+
+	for name, property := range page.Properties {
+		switch property.Type {
+		case types.PropertyTypeFormula, types.PropertyTypeRollup,
+			types.PropertyTypeCreatedTime, types.PropertyTypeCreatedBy,
+			types.PropertyTypeLastEditedTime, types.PropertyTypeLastEditedBy,
+			types.PropertyTypeUniqueID, types.PropertyTypeVerification:
+			continue
+		}
+		if !database && property.Type != types.PropertyTypeTitle {
+			continue
+		}
+
+		// Property IDs belong to the source schema.
+		property.ID = ""
+		properties[name] = property
+	}
+	return properties
+}
+
+// createPage creates a page.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - req: The page to create.
+//
+// Returns:
+// - The created page.
+// - An error if the request failed.
+func (ns *Plugin) createPage(ctx context.Context, req types.PageCreateRequest) (*types.Page, error) {
+	call := apiCall{
+		operation:  OperationCreatePage,
+		objectType: common.ObjectTypePage,
+	}
+	return doRequest(ctx, ns, call, func() (*types.Page, error) {
+		return callAPI[types.Page](ctx, ns.client.Registry, &req)
+	})
+}
+
+// updatePage updates a page.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - req: The update to apply.
+//
+// Returns:
+// - The updated page.
+// - An error if the request failed.
+func (ns *Plugin) updatePage(ctx context.Context, req types.PageUpdateRequest) (*types.Page, error) {
+	call := apiCall{
+		operation:  OperationUpdatePage,
+		objectID:   string(req.PageID),
+		objectType: common.ObjectTypePage,
+	}
+	return doRequest(ctx, ns, call, func() (*types.Page, error) {
+		return callAPI[types.Page](ctx, ns.client.Registry, &req)
+	})
+}
+
+// appendBlocks appends blocks to a page or block.
+//
+// Arguments:
+// - ctx: The context for the request.
+// - req: The blocks to append.
+//
+// Returns:
+// - The appended blocks.
+// - An error if the request failed.
+func (ns *Plugin) appendBlocks(ctx context.Context, req types.BlockAppendRequest) (*types.BlockListResponse, error) {
+	call := apiCall{
+		operation:  OperationAppendBlocks,
+		objectID:   string(req.BlockID),
+		objectType: common.ObjectTypeBlock,
+	}
+	return doRequest(ctx, ns, call, func() (*types.BlockListResponse, error) {
+		return callAPI[types.BlockListResponse](ctx, ns.client.Registry, &req)
+	})
+}
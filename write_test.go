@@ -0,0 +1,223 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cmskitdev/common"
+	"github.com/cmskitdev/engine"
+	"github.com/cmskitdev/notion/notiontest"
+	"github.com/cmskitdev/notion/types"
+)
+
+func TestDestinationWritesRowsAndBlocks(t *testing.T) {
+	source := notiontest.NewWorkspace()
+	row := source.AddPage(newRow(source.AddDatabase(newWorkspaceDatabase("Posts")), "Alpha", true))
+	top := source.AddBlocks(string(row.ID), newParagraph("top"))[0]
+	source.AddBlocks(string(top.ID), newParagraph("nested"))
+
+	reader, _ := newTestPlugin(t, source, testConfig())
+	items := readAll(t, context.Background(), reader, common.ObjectTypeCollection, common.ObjectTypeBlock)
+
+	target := notiontest.NewWorkspace()
+	database := target.AddDatabase(newWorkspaceDatabase("Posts"))
+	config := testConfig()
+	config.Write = WriteConfig{DatabaseID: string(database.ID), KeyProperty: "Name"}
+
+	d := newTestDestination(t, target, config)
+	if err := d.Batch(context.Background(), items); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	written := d.session.targets[string(row.ID)]
+	page := target.Page(written)
+	if page == nil || page.Parent.GetParentID() != string(database.ID) {
+		t.Fatalf("row %s was written to %v, want a row of %s", row.ID, page, database.ID)
+	}
+	if name := types.ToPlainText(page.Properties["Name"].Title); name != "Alpha" {
+		t.Errorf("name = %q, want Alpha", name)
+	}
+	blocks := target.Children(written)
+	if len(blocks) != 1 || len(target.Children(string(blocks[0].ID))) != 1 {
+		t.Errorf("blocks = %v, want the top block with its nested block", blocks)
+	}
+	if metrics := d.GetMetrics(); metrics.PagesCreated != 1 || metrics.BlocksAppended != 2 {
+		t.Errorf("created %d pages and appended %d blocks, want 1 and 2", metrics.PagesCreated, metrics.BlocksAppended)
+	}
+
+	// Writing again matches the row by key and leaves its blocks alone.
+	again := newTestDestination(t, target, config)
+	if err := again.Batch(context.Background(), items); err != nil {
+		t.Fatalf("failed to write again: %v", err)
+	}
+	if got := again.session.targets[string(row.ID)]; got != written {
+		t.Errorf("row was written again to %s, want %s", got, written)
+	}
+	if metrics := again.GetMetrics(); metrics.PagesCreated != 0 || metrics.PagesUpdated != 1 || metrics.BlocksAppended != 0 {
+		t.Errorf("metrics = %+v, want a single update", metrics)
+	}
+}
+
+func TestDestinationArchivesPagesOfTombstones(t *testing.T) {
+	target := notiontest.NewWorkspace()
+	parent := target.AddPage(newWorkspacePage("Parent"))
+	config := testConfig()
+	config.Write = WriteConfig{ParentPageID: string(parent.ID), Targets: NewFileWriteTargetStore(filepath.Join(t.TempDir(), "targets.json"))}
+
+	d := newTestDestination(t, target, config)
+	page := newWorkspacePage("Page")
+	page.ID = types.PageID(missingPageID)
+	if err := d.Write(context.Background(), *d.plugin.convertPageDataToDataItem(page)); err != nil {
+		t.Fatalf("failed to write the page: %v", err)
+	}
+	written := d.session.targets[missingPageID]
+
+	tombstone := *d.plugin.convertTombstoneToDataItem(&Tombstone{ID: missingPageID, Type: common.ObjectTypePage, Reason: tombstoneDeleted})
+
+	// The page is found through the store by a later destination.
+	later := newTestDestination(t, target, config)
+	if err := later.Write(context.Background(), tombstone); err != nil {
+		t.Fatalf("failed to write the tombstone: %v", err)
+	}
+	if !target.Page(written).Archived {
+		t.Errorf("page %s was not archived", written)
+	}
+
+	// Without a written page there is nothing to archive.
+	if err := later.Write(context.Background(), tombstone); err == nil || !strings.Contains(err.Error(), "no page was written") {
+		t.Errorf("error = %v, want a missing target", err)
+	}
+}
+
+func TestDestinationWritesFilesAsExternal(t *testing.T) {
+	target := notiontest.NewWorkspace()
+	parent := target.AddPage(newWorkspacePage("Parent"))
+	config := testConfig()
+	config.Write = WriteConfig{ParentPageID: string(parent.ID)}
+
+	d := newTestDestination(t, target, config)
+	page := newWorkspacePage("Page")
+	page.ID = types.PageID(missingPageID)
+
+	image := func(id string, url string, expiry string) engine.DataItemContainer[any] {
+		block := &types.Block{
+			ID:   types.BlockID(id),
+			Type: types.BlockTypeImage,
+			Image: &types.FileBlock{
+				Type: types.FileBlockTypeFile,
+				File: &types.File{Type: "file", File: &types.NotionHostedFileType{URL: url, ExpiryTime: expiry}},
+			},
+		}
+		return *d.plugin.convertBlockToDataItem(block, missingPageID, missingPageID, 1)
+	}
+	stored := "https://assets.example.com/ab/ab12"
+	items := []engine.DataItemContainer[any]{
+		*d.plugin.convertPageDataToDataItem(page),
+		// Downloaded, see AssetConfig.
+		image("aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa", stored, ""),
+		// Still at its expiring Notion URL.
+		image("bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb", "https://files.notion.so/secure/a.png?X-Amz-Signature=abc", "2026-10-16T15:00:00.000Z"),
+		// Downloaded without a base URL.
+		image("cccccccc-cccc-4ccc-8ccc-cccccccccccc", "ab/ab12", ""),
+	}
+	if err := d.Batch(context.Background(), items); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	blocks := target.Children(d.session.targets[missingPageID])
+	if len(blocks) != 1 {
+		t.Fatalf("blocks = %d, want only the downloaded image", len(blocks))
+	}
+	written := blocks[0].Image
+	if written == nil || written.Type != types.FileBlockTypeExternal || written.File != nil ||
+		written.External == nil || written.External.External == nil || written.External.External.URL != stored {
+		t.Errorf("image = %+v, want an external file at %s", written, stored)
+	}
+	// The source item is left untouched.
+	if source := items[1].Data.(*types.Block).Image; source.Type != types.FileBlockTypeFile || source.File == nil {
+		t.Errorf("source image = %+v, want the Notion-hosted file", source)
+	}
+}
+
+func TestWriteRequestsEncoding(t *testing.T) {
+	pageID := types.PageID(missingPageID)
+	blockID := types.BlockID("aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa")
+
+	// Properties built from scratch have no ID.
+	title := *types.NewTitleProperty("Page")
+	title.ID = ""
+	create := &types.PageCreateRequest{
+		Properties: map[string]types.Property{"title": title},
+	}
+	update := &types.PageUpdateRequest{PageID: pageID}
+	paragraph := *newParagraph("text")
+	paragraph.ID = blockID
+	paragraph.HasChildren = true
+	appendReq := &types.BlockAppendRequest{BlockID: types.BlockID(pageID), Children: []types.Block{paragraph}, After: &blockID}
+
+	tests := []struct {
+		name    string
+		req     endpoint
+		path    string
+		method  string
+		present []string
+		absent  []string
+	}{
+		{name: "create page", req: create, path: "/pages", method: "POST", present: []string{`"properties":{"title":{`, `"title":[`}, absent: []string{`"id"`}},
+		{name: "update page", req: update, path: "/pages/" + missingPageID, method: "PATCH", absent: []string{`"properties"`, missingPageID}},
+		{name: "append blocks", req: appendReq, path: "/blocks/" + missingPageID + "/children", method: "PATCH",
+			present: []string{`"after":"` + string(blockID) + `"`, `"paragraph"`}, absent: []string{`"id"`, `"has_children"`, `"created_time"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if path, method := tt.req.GetPath(), tt.req.GetMethod(); path != tt.path || method != tt.method {
+				t.Errorf("endpoint = %s %s, want %s %s", method, path, tt.method, tt.path)
+			}
+
+			data, err := json.Marshal(tt.req)
+			if err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			body := string(data)
+			for _, want := range tt.present {
+				if !strings.Contains(body, want) {
+					t.Errorf("body %s lacks %s", body, want)
+				}
+			}
+			for _, unwanted := range tt.absent {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("body %s contains %s", body, unwanted)
+				}
+			}
+		})
+	}
+}
+
+// newTestDestination starts a notiontest server for a workspace and creates a
+// destination writing to it. Both are closed when the test ends.
+//
+// Arguments:
+// - t: The test.
+// - workspace: The workspace to write to.
+// - config: The configuration, whose Write field sets where pages go.
+//
+// Returns:
+// - The destination.
+func newTestDestination(t *testing.T, workspace *notiontest.Workspace, config NotionSourceConfig) *Destination {
+	t.Helper()
+
+	server := notiontest.NewServer(workspace)
+	server.Token = testToken
+	t.Cleanup(server.Close)
+
+	d, err := NewNotionDestination(newTestClient(t, server), config)
+	if err != nil {
+		t.Fatalf("failed to create destination: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}